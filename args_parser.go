@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"fmt"
 	"net"
 	"net/url"
@@ -49,6 +50,33 @@ type kingpinParser struct {
 	dynKvShow         bool
 	ftsTestHelp       bool
 	dynFtsLimit       uint64
+	queryMixPath      string
+	wordListFiles     *namedPathsList
+	geoPointsPath     string
+	vectorsPath       string
+	groundTruthPath   string
+	groundTruthIDFmt  string
+	facetsPath        string
+	pages             int
+	pageMode          string
+	pageSort          string
+	requestOptions    requestOptionFlags
+	seed              *nullableInt64
+	dryRun            string
+	replayPath        string
+	replayTiming      string
+	template          bool
+	csvFiles          *namedPathsList
+	dataPath          string
+	dataMode          string
+	bucketStats       bool
+	credentialsPath   string
+	targets           []string
+	targetsPath       string
+	balance           string
+	ejectAfter        int
+	ejectFor          time.Duration
+	discover          string
 	dynDocSz          uint64
 	dynDocBatchSz     uint64
 	reqBatchSz        uint64
@@ -62,6 +90,12 @@ type kingpinParser struct {
 	keyPath           string
 	rate              *nullableUint64
 	minBackoff        int
+	maxBackoff        time.Duration
+	backoffMultiplier float64
+	backoffJitter     string
+	maxAttempts       int
+	retryOn           string
+	ignoreRetryAfter  bool
 	clientType        clientTyp
 
 	printSpec *nullableString
@@ -24285,6 +24319,21 @@ func getHotelLocationLatLons() [][]float32 {
     }
 }
 
+// setBuiltinFtsData loads the travel-sample word lists and hotel
+// locations used by the FTS query generators.
+func setBuiltinFtsData(c *config) {
+	c.commonReviewWords = getCommonReviewWords()
+	c.commonReviewWordsLen = len(c.commonReviewWords)
+	c.commonEnglishWords = getCommonEnglishWords()
+	c.commonEnglishWordsLen = len(c.commonEnglishWords)
+	c.commonVerbWords = getCommonVerbWords()
+	c.commonVerbWordsLen = len(c.commonVerbWords)
+	c.sampleReviewWords = getSampleReviewWords()
+	c.sampleReviewWordsLen = len(c.sampleReviewWords)
+	c.hotelLocationLatLons = getHotelLocationLatLons()
+	c.hotelLocationLatLonsLen = len(c.hotelLocationLatLons)
+}
+
 func newKingpinParser() argsParser {
 	kparser := &kingpinParser{
 		numReqs:          new(nullableUint64),
@@ -24311,6 +24360,10 @@ func newKingpinParser() argsParser {
 		url:              "",
 		rate:             new(nullableUint64),
 		minBackoff:       defaultMinBackoff,
+		maxBackoff:       defaultMaxBackoff,
+		backoffJitter:    jitterNone,
+		maxAttempts:      defaultMaxAttempts,
+		retryOn:          defaultRetryOn,
 		clientType:       fhttp,
 		printSpec:        new(nullableString),
 		noPrint:          false,
@@ -24323,6 +24376,22 @@ func newKingpinParser() argsParser {
 		dynKvShow:       false,
 		ftsTestHelp:      false,
 		dynFtsLimit:      0,
+		queryMixPath:     "",
+		wordListFiles:    new(namedPathsList),
+		geoPointsPath:    "",
+		vectorsPath:      "",
+		groundTruthPath:  "",
+		groundTruthIDFmt: "%d",
+		facetsPath:       "",
+		pageMode:         pageModeFrom,
+		pageSort:         defaultPageSort,
+		replayTiming:     replayTimingASAP,
+		csvFiles:         new(namedPathsList),
+		dataMode:         dataModeSequential,
+		balance:          balanceRoundRobin,
+		ejectAfter:       defaultEjectAfter,
+		ejectFor:         defaultEjectFor,
+		seed:             new(nullableInt64),
 		dynDocSz:         defaultDynDocSz,
 		dynDocBatchSz:    defaultDynDocBatchSz,
 		reqBatchSz:       defaultReqBatchSz,
@@ -24502,10 +24571,126 @@ func newKingpinParser() argsParser {
 		Default("").
 		Short('j').
 		StringVar(&kparser.altMeteringHost)
-	app.Flag("dynFtsLimit", "COUCHBASE: select the dynamically generated query test for FTS 1 31-43, 99").
+	app.Flag("dynFtsLimit", "COUCHBASE: select the dynamically generated query test for FTS, see --ftsTestHelp for the list").
 		Short('L').
 		Uint64Var(&kparser.dynFtsLimit)
-	app.Flag("ftsTestHelp", "COUCHBASE: show details on above tests 1-13, 99 used for the FTS benchmarking (sorry, you most give an arg after -J or --ftsTestHelp)").
+	app.Flag("queryMix", "COUCHBASE: JSON or YAML file listing FTS query generators by name with weights and params, used instead of the default mix").
+		PlaceHolder("mix.yaml").
+		Default("").
+		StringVar(&kparser.queryMixPath)
+	app.Flag("wordList", "COUCHBASE: load an FTS word list from a text (one term per line), CSV (first column) or JSON (array) file, "+
+		"replaces the built-in list of the same name or adds a new one for --queryMix (can be repeated)").
+		PlaceHolder("name=path").
+		SetValue(kparser.wordListFiles)
+	app.Flag("geoPoints", "COUCHBASE: load the lon/lat points used by the geo queries from a CSV or JSON file instead of the built-in hotel locations").
+		PlaceHolder("path").
+		Default("").
+		StringVar(&kparser.geoPointsPath)
+	app.Flag("vectors", "COUCHBASE: load the query vectors used by the knn tests from an .fvecs, JSON (array of arrays) or JSONL file instead of random vectors").
+		PlaceHolder("path").
+		Default("").
+		StringVar(&kparser.vectorsPath)
+	app.Flag("groundTruth", "COUCHBASE: neighbor ids of each --vectors query from an .ivecs, JSON or JSONL file, reports the recall@k of the knn queries").
+		PlaceHolder("path").
+		Default("").
+		StringVar(&kparser.groundTruthPath)
+	app.Flag("groundTruthIdFormat", "COUCHBASE: printf format turning numeric --groundTruth ids into FTS document ids, e.g. doc_%d").
+		Default("%d").
+		StringVar(&kparser.groundTruthIDFmt)
+	app.Flag("facets", "COUCHBASE: JSON or YAML file of term, numeric and date range facets added to every generated FTS query").
+		PlaceHolder("facets.yaml").
+		Default("").
+		StringVar(&kparser.facetsPath)
+	app.Flag("pages", "COUCHBASE: walk this many pages for each generated FTS query and report per-page latency and bytesRead").
+		Default("0").
+		IntVar(&kparser.pages)
+	app.Flag("pageMode", "COUCHBASE: how --pages are walked, from (from/size), search_after or search_before (from the last page back)").
+		Default(pageModeFrom).
+		EnumVar(&kparser.pageMode, pageModes...)
+	app.Flag("pageSort", "COUCHBASE: sort added for --pageMode search_after/search_before unless the query has one, a JSON array").
+		Default(defaultPageSort).
+		StringVar(&kparser.pageSort)
+	app.Flag("requestOptions", "COUCHBASE: JSON or YAML file of request members (score, explain, highlight, fields, sort, ctl, ...) merged into every generated FTS query").
+		PlaceHolder("options.yaml").
+		Default("").
+		StringVar(&kparser.requestOptions.path)
+	app.Flag("score", "COUCHBASE: request option \"score\", none disables scoring").
+		PlaceHolder("none").
+		Default("").
+		StringVar(&kparser.requestOptions.score)
+	app.Flag("explain", "COUCHBASE: request option \"explain\": true").
+		BoolVar(&kparser.requestOptions.explain)
+	app.Flag("highlight", "COUCHBASE: request option \"highlight\" with this style, html or ansi").
+		PlaceHolder("html").
+		Default("").
+		StringVar(&kparser.requestOptions.highlight)
+	app.Flag("highlightFields", "COUCHBASE: comma separated \"highlight\" fields, implies highlighting").
+		PlaceHolder("f1,f2").
+		Default("").
+		StringVar(&kparser.requestOptions.highlightFields)
+	app.Flag("fields", "COUCHBASE: request option \"fields\", comma separated stored fields to return, e.g. *").
+		PlaceHolder("f1,f2").
+		Default("").
+		StringVar(&kparser.requestOptions.fields)
+	app.Flag("includeLocations", "COUCHBASE: request option \"includeLocations\": true").
+		BoolVar(&kparser.requestOptions.includeLocations)
+	app.Flag("sort", "COUCHBASE: request option \"sort\", a JSON array, e.g. [\"-_score\", \"_id\"]").
+		PlaceHolder("json").
+		Default("").
+		StringVar(&kparser.requestOptions.sort)
+	app.Flag("ctlTimeout", "COUCHBASE: request option \"ctl\": {\"timeout\": ms}, the server side timeout of each query").
+		PlaceHolder("10s").
+		Default("0s").
+		DurationVar(&kparser.requestOptions.ctlTimeout)
+	app.Flag("seed", "COUCHBASE: seed of the generated FTS queries, each connection gets its own reproducible stream (default: time based, printed)").
+		PlaceHolder("int").
+		SetValue(kparser.seed)
+	app.Flag("dry-run", "COUCHBASE: write the -n generated requests (method, url, headers, body) as JSONL to this file (- for stdout) instead of sending them").
+		PlaceHolder("requests.jsonl").
+		Default("").
+		StringVar(&kparser.dryRun)
+	app.Flag("replay", "COUCHBASE: send the requests of a JSONL file (url, method, headers, body, timestamp) to the target instead of -b, -n defaults to the number of records").
+		PlaceHolder("requests.jsonl").
+		Default("").
+		StringVar(&kparser.replayPath)
+	app.Flag("replayTiming", "COUCHBASE: send the --replay requests asap (as fast as possible) or with their original timing (needs timestamps in seconds)").
+		Default(replayTimingASAP).
+		EnumVar(&kparser.replayTiming, replayTimings...)
+	app.Flag("template", "COUCHBASE: evaluate the body and the path and query of the URL as Go text/templates for every request, e.g. {{ RandInt 1 10 }}, see the README").
+		BoolVar(&kparser.template)
+	app.Flag("csv", "COUCHBASE: CSV file with a header line (or JSONL file) usable in --template as CSVRow \"name\" (can be repeated)").
+		PlaceHolder("name=path").
+		SetValue(kparser.csvFiles)
+	app.Flag("data", "COUCHBASE: CSV file with a header line or JSONL file whose rows fill the [[DATA:column]] placeholders of the URL, -H values and body").
+		PlaceHolder("rows.csv").
+		StringVar(&kparser.dataPath)
+	app.Flag("dataMode", "COUCHBASE: pick the --data rows in sequential order, at random or partitioned (connection i of c takes rows i, i+c, ...)").
+		Default(dataModeSequential).
+		EnumVar(&kparser.dataMode, dataModes...)
+	app.Flag("bucketStats", "COUCHBASE: also break the statistics (latencies, HTTP codes, 429 retries, hits, bytesRead and with --showMetering RU) down per bucket").
+		BoolVar(&kparser.bucketStats)
+	app.Flag("credentials", "COUCHBASE: YAML or JSON file mapping a bucket name or [[SEQ:#:##]] value (\"*\" for any other) to user:pass or {cert, key} of its tenant, used instead of -u for HTTP and KV").
+		PlaceHolder("tenants.yaml").
+		StringVar(&kparser.credentialsPath)
+	app.Flag("target", "COUCHBASE: search node host[:port] to spread the requests over instead of the URL host, comma separated or repeated (the port defaults to the one of the URL)").
+		PlaceHolder("host:port").
+		StringsVar(&kparser.targets)
+	app.Flag("targetsFile", "COUCHBASE: file with a --target host[:port] per line (# comments)").
+		PlaceHolder("nodes.txt").
+		StringVar(&kparser.targetsPath)
+	app.Flag("balance", "COUCHBASE: spread the requests over the --target nodes round-robin, at random or to the node with the least outstanding requests").
+		Default(balanceRoundRobin).
+		EnumVar(&kparser.balance, balanceModes...)
+	app.Flag("ejectAfter", "COUCHBASE: leave a --target node out after this many failures (transport errors or 5xx) in a row, 0 never").
+		PlaceHolder(strconv.Itoa(defaultEjectAfter)).
+		IntVar(&kparser.ejectAfter)
+	app.Flag("ejectFor", "COUCHBASE: how long an ejected --target node is left out").
+		PlaceHolder(defaultEjectFor.String()).
+		DurationVar(&kparser.ejectFor)
+	app.Flag("discover", "COUCHBASE: look up the search nodes (and their ports, TLS ones for an https URL) on this cluster manager's /pools/default and use them as the --target nodes and for --showMetering").
+		PlaceHolder("host:8091").
+		StringVar(&kparser.discover)
+	app.Flag("ftsTestHelp", "COUCHBASE: show details on the registered query tests used for the FTS benchmarking (sorry, you most give an arg after -J or --ftsTestHelp)").
 		Short('J').
 		BoolVar(&kparser.ftsTestHelp)
 	app.Flag( "kvHost", "COUCHBASE: Utilize KV 'host' to perform document lookups for FTS hits").
@@ -24521,9 +24706,25 @@ func newKingpinParser() argsParser {
 	app.Flag("dynKvShow", "COUCHBASE: if -K 'host' show the first 110 chars of any KV reads to resolve FTS docs").
 		Short('z').
 		BoolVar(&kparser.dynKvShow)
-	app.Flag("minBackoff", "COUCHBASE: min backoff in ms. HTTP 429 retry progression, default 0").
+	app.Flag("minBackoff", "COUCHBASE: min backoff in ms. before a retry (0 is 0.5 ms.), default 0").
 		Short('e').
 		IntVar(&kparser.minBackoff)
+	app.Flag("retryOn", "COUCHBASE: comma separated HTTP statuses and transport error classes (timeout, connection, error for any) to retry, empty for none").
+		PlaceHolder(defaultRetryOn).
+		StringVar(&kparser.retryOn)
+	app.Flag("maxBackoff", "COUCHBASE: max backoff before a retry").
+		PlaceHolder(defaultMaxBackoff.String()).
+		DurationVar(&kparser.maxBackoff)
+	app.Flag("backoffMultiplier", "COUCHBASE: backoff growth per retry (0 is x4 for retries 2 and 3, x2 up to retry 7 and x1.2 after), default 0").
+		Float64Var(&kparser.backoffMultiplier)
+	app.Flag("backoffJitter", "COUCHBASE: randomize the backoff, full (0 up to the backoff) or decorrelated (--minBackoff up to --backoffMultiplier times the previous one)").
+		Default(jitterNone).
+		EnumVar(&kparser.backoffJitter, jitterModes...)
+	app.Flag("maxAttempts", "COUCHBASE: max times a request is sent, retries included").
+		PlaceHolder(strconv.Itoa(defaultMaxAttempts)).
+		IntVar(&kparser.maxAttempts)
+	app.Flag("ignoreRetryAfter", "COUCHBASE: don't wait the Retry-After of a response when it is longer than the backoff").
+		BoolVar(&kparser.ignoreRetryAfter)
 
 	kparser.app = app
 	return argsParser(kparser)
@@ -24561,42 +24762,21 @@ func (k *kingpinParser) parse(args []string) (config, error) {
         // BEG cb_fts_bench only
 	// extract [[SEQ:#:##]]
 
-	rand.Seed(time.Now().UnixNano())
-
-        var myBegBucketSeq int
-        var myEndBucketSeq int
-        var myLenBucketSeq int
-
-	// fmt.Printf("URL %s\n",url)
-        m := regexp.MustCompile(`\[\[SEQ.\d+.\d+\]\]`)
-	myPatBucketSeq := m.FindString(url);
-
-	n, err := fmt.Sscanf(myPatBucketSeq,"[[SEQ:%d:%d]]", &myBegBucketSeq, &myEndBucketSeq)
-	if n > -1 && err == nil {
-	   myLenBucketSeq = len(strconv.Itoa(myEndBucketSeq))
-	   if myLenBucketSeq == 1 {
-		   myLenBucketSeq = 2 
-	   }
-	   // fmt.Printf("YES %d: %d, %d len %d\n\n", n, myBegBucketSeq, myEndBucketSeq, myLenBucketSeq)
+	seed := time.Now().UnixNano()
+	if k.seed.val != nil {
+		seed = *k.seed.val
 	}
-	// fmt.Printf("NO %d: %d, %d len %d\n\n", n, myBegBucketSeq, myEndBucketSeq, myLenBucketSeq)
-
+	rand.Seed(seed)
 
-        myCommonReviewWords := getCommonReviewWords()
-        myCommonReviewWordsLen := len(myCommonReviewWords)
-        myCommonEnglishWords := getCommonEnglishWords()
-        myCommonEnglishWordsLen := len(myCommonEnglishWords)
-        myCommonVerbWords := getCommonVerbWords()
-        myCommonVerbWordsLen := len(myCommonVerbWords)
-        mySampleReviewWords := getSampleReviewWords()
-        mySampleReviewWordsLen := len(mySampleReviewWords)
-	myHotelLocationLatLons := getHotelLocationLatLons()
-	myHotelLocationLatLonsLen := len(myHotelLocationLatLons)
+	urlSeqs, err := parseURLSeqs(url, k.kvBucket)
+	if err != nil {
+		return emptyConf, err
+	}
 
         // END cb_fts_bench only
 
 
-	return config{
+	conf := config{
 		numConns:          k.numConns,
 		numReqs:           k.numReqs.val,
 		duration:          k.duration.val,
@@ -24630,11 +24810,16 @@ func (k *kingpinParser) parse(args []string) (config, error) {
 		dynDoc:            k.dynDoc,
 		dynDocShow:        k.dynDocShow,
 		showMetering:      k.showMetering,
+		bucketStats:       k.bucketStats,
+		balance:           k.balance,
+		ejectAfter:        k.ejectAfter,
+		ejectFor:          k.ejectFor,
 		altMeteringHost:   k.altMeteringHost,
 		dynFtsShow:        k.dynFtsShow,
 		dynKvShow:         k.dynKvShow,
 		ftsTestHelp:       k.ftsTestHelp,
 		dynFtsLimit:       k.dynFtsLimit,
+		queryMixPath:      k.queryMixPath,
 		dynDocSz:          k.dynDocSz,
 		dynDocBatchSz:     k.dynDocBatchSz,
 		reqBatchSz:        k.reqBatchSz,
@@ -24643,25 +24828,95 @@ func (k *kingpinParser) parse(args []string) (config, error) {
 		nobatchnum:        k.nobatchnum,
 
 		// BEG cb_fts_bench only
-		begBucketSeq: myBegBucketSeq,
-		endBucketSeq: myEndBucketSeq,
-		lenBucketSeq: myLenBucketSeq,
-		patBucketSeq: myPatBucketSeq,
-
-		commonReviewWords: myCommonReviewWords,
-		commonReviewWordsLen: myCommonReviewWordsLen,
-		commonEnglishWords: myCommonEnglishWords, 
-		commonEnglishWordsLen: myCommonEnglishWordsLen,
-		commonVerbWords: myCommonVerbWords, 
-		commonVerbWordsLen: myCommonVerbWordsLen, 
-		sampleReviewWords: mySampleReviewWords, 
-		sampleReviewWordsLen: mySampleReviewWordsLen, 
-		hotelLocationLatLons: myHotelLocationLatLons,
-		hotelLocationLatLonsLen: myHotelLocationLatLonsLen,
+		urlSeqs:      urlSeqs,
 
 		// END cb_fts_bench only
 
-	}, nil
+	}
+	setBuiltinFtsData(&conf)
+	if err := loadFtsDataFiles(&conf, ftsDataFiles{
+		wordLists:           *k.wordListFiles,
+		geoPoints:           k.geoPointsPath,
+		vectors:             k.vectorsPath,
+		groundTruth:         k.groundTruthPath,
+		groundTruthIDFormat: k.groundTruthIDFmt,
+		csvTables:           *k.csvFiles,
+	}); err != nil {
+		return emptyConf, err
+	}
+	if k.pages < 0 {
+		return emptyConf, errInvalidPages
+	}
+	if !json.Valid([]byte(k.pageSort)) || !strings.HasPrefix(strings.TrimSpace(k.pageSort), "[") {
+		return emptyConf, errInvalidPageSort
+	}
+	conf.pages, conf.pageMode, conf.pageSort = k.pages, k.pageMode, k.pageSort
+	opts, err := newRequestOptions(k.requestOptions)
+	if err != nil {
+		return emptyConf, err
+	}
+	conf.requestOptions = opts
+	conf.seed = seed
+	if k.replayPath != "" {
+		replay, err := loadReplayFile(k.replayPath, k.replayTiming)
+		if err != nil {
+			return emptyConf, err
+		}
+		if conf.numReqs == nil && conf.duration == nil {
+			n := uint64(len(replay.records))
+			conf.numReqs = &n
+		}
+		conf.replay = replay
+	}
+	if k.dryRun != "" && conf.numReqs == nil {
+		return emptyConf, errDryRunNeedsNumReqs
+	}
+	conf.dryRun = k.dryRun
+	conf.template = k.template
+	if k.credentialsPath != "" {
+		creds, err := loadCredentialsFile(k.credentialsPath)
+		if err != nil {
+			return emptyConf, err
+		}
+		conf.credentials = creds
+	}
+	conf.targets = splitTargets(k.targets)
+	if k.targetsPath != "" {
+		targets, err := loadTargetsFile(k.targetsPath)
+		if err != nil {
+			return emptyConf, err
+		}
+		conf.targets = append(conf.targets, targets...)
+	}
+	conf.discover = k.discover
+	if k.dataPath != "" {
+		data, err := loadDataSource(k.dataPath, k.dataMode)
+		if err != nil {
+			return emptyConf, err
+		}
+		conf.data = data
+	}
+	if k.facetsPath != "" {
+		facets, err := loadFacetsFile(k.facetsPath)
+		if err != nil {
+			return emptyConf, err
+		}
+		conf.facets = facets
+	}
+	retry, err := newRetryPolicy(k.retryOn, retryPolicy{
+		minBackoff:       minBackoffOf(k.minBackoff),
+		maxBackoff:       k.maxBackoff,
+		multiplier:       k.backoffMultiplier,
+		jitter:           k.backoffJitter,
+		maxAttempts:      k.maxAttempts,
+		ignoreRetryAfter: k.ignoreRetryAfter,
+	})
+	if err != nil {
+		return emptyConf, err
+	}
+	conf.retry = retry
+
+	return conf, nil
 }
 
 func parsePrintSpec(spec string) (bool, bool, bool, error) {
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Compound queries nest the existing builders as leaves under depth
+// levels of boolean (must/should/must_not), conjuncts and disjuncts
+// nodes to see how the shape of the query tree drives latency and
+// bytesRead.
+
+var (
+	booleanNodeTypes = []string{"bool", "conjuncts", "disjuncts"}
+	booleanLeafTypes = []string{"term", "match", "query-string", "geo-range"}
+)
+
+// maxBooleanLeaves bounds the maxFanOut^depth leaves of a query tree.
+const maxBooleanLeaves = 1024
+
+// booleanQueryGen emits a query tree of depth compound levels, each
+// node has minFanOut to maxFanOut children.
+type booleanQueryGen struct {
+	depth                int
+	minFanOut, maxFanOut int
+	// nodes and leaves are picked at random for each node and leaf
+	nodes  []string
+	leaves []string
+	// shouldMin is the "min" of should and disjuncts clauses
+	shouldMin int
+	// mustNot lets bool nodes put a child into must_not
+	mustNot bool
+	words   string
+	miss    float64
+}
+
+// queryClause strips the "query": of a fragment from one of the
+// builders so it can be nested.
+func queryClause(fragment string) string {
+	return strings.TrimSpace(strings.TrimPrefix(fragment, "\"query\":"))
+}
+
+func (g booleanQueryGen) leaf(conf config) string {
+	words := conf.wordList(g.words)
+	switch g.leaves[conf.rnd().Intn(len(g.leaves))] {
+	case "match":
+		return queryClause(buildBasicRandomQueryMatch(conf.rnd(), 1, words, len(words)))
+	case "query-string":
+		return queryClause(buildBasicRandomQueryNumWords(conf.rnd(), 2, words, len(words)))
+	case "geo-range":
+		return queryClause(buildPseudoGeoRandomQuery(0.25, conf))
+	}
+	w := strings.ToLower(words[conf.rnd().Intn(len(words))])
+	return fmt.Sprintf("{ \"term\": %s, \"field\": \"reviews.content\" }", jsonString(w))
+}
+
+func (g booleanQueryGen) children(conf config, level, n int) []string {
+	res := make([]string, n)
+	for i := range res {
+		if level >= g.depth {
+			res[i] = g.leaf(conf)
+		} else {
+			res[i] = g.node(conf, level+1)
+		}
+	}
+	return res
+}
+
+// disjuncts clamps shouldMin to the number of clauses so the query
+// can match at all.
+func (g booleanQueryGen) disjuncts(clauses []string) string {
+	min := g.shouldMin
+	if min > len(clauses) {
+		min = len(clauses)
+	}
+	return fmt.Sprintf("{\"disjuncts\": [%s], \"min\": %d}", strings.Join(clauses, ", "), min)
+}
+
+func (g booleanQueryGen) node(conf config, level int) string {
+	clauses := g.children(conf, level, randIntFromRange(conf.rnd(), g.minFanOut, g.maxFanOut))
+	switch g.nodes[conf.rnd().Intn(len(g.nodes))] {
+	case "conjuncts":
+		return fmt.Sprintf("{\"conjuncts\": [%s]}", strings.Join(clauses, ", "))
+	case "disjuncts":
+		return g.disjuncts(clauses)
+	}
+
+	// bool: split the children into must, should and must_not, there is
+	// always at least one must clause
+	var must, should, mustNot []string
+	for i, c := range clauses {
+		switch {
+		case i == 0:
+			must = append(must, c)
+		case g.mustNot && conf.rnd().Intn(3) == 0:
+			mustNot = append(mustNot, c)
+		case conf.rnd().Intn(2) == 0:
+			should = append(should, c)
+		default:
+			must = append(must, c)
+		}
+	}
+	parts := []string{fmt.Sprintf("\"must\": {\"conjuncts\": [%s]}", strings.Join(must, ", "))}
+	if len(should) > 0 {
+		parts = append(parts, "\"should\": "+g.disjuncts(should))
+	}
+	if len(mustNot) > 0 {
+		parts = append(parts, fmt.Sprintf("\"must_not\": {\"disjuncts\": [%s]}", strings.Join(mustNot, ", ")))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func (g booleanQueryGen) generate(conf config) string {
+	return "\"query\": " + g.node(conf, 1)
+}
+
+func (g booleanQueryGen) describe() string {
+	s := fmt.Sprintf("build a query tree %d level(s) deep of %s nodes with %d to %d children each,\n"+
+		"the leaves are %s queries using words from %s, should/disjuncts need %d match(es)",
+		g.depth, strings.Join(g.nodes, "/"), g.minFanOut, g.maxFanOut, strings.Join(g.leaves, "/"), g.words, g.shouldMin)
+	if g.mustNot && containsString(g.nodes, "bool") {
+		s += "\nbool nodes put about a third of their children into must_not"
+	}
+	return s
+}
+
+func (g booleanQueryGen) missRate() float64 { return g.miss }
+
+func (g booleanQueryGen) withParams(conf config, p *queryParams) (queryGenerator, error) {
+	g.depth = p.int("depth", g.depth)
+	g.minFanOut = p.int("minFanOut", g.minFanOut)
+	g.maxFanOut = p.int("maxFanOut", g.maxFanOut)
+	g.nodes = p.strings("nodes", g.nodes, booleanNodeTypes...)
+	g.leaves = p.strings("leaves", g.leaves, booleanLeafTypes...)
+	g.shouldMin = p.int("shouldMin", g.shouldMin)
+	g.mustNot = p.bool("mustNot", g.mustNot)
+	g.words = p.words(conf, "words", g.words)
+	if g.depth < 1 || g.depth > 10 {
+		return nil, errors.New("depth must be 1 to 10")
+	}
+	if g.minFanOut < 1 || g.maxFanOut < g.minFanOut {
+		return nil, errors.New("need 0 < minFanOut <= maxFanOut")
+	}
+	if g.shouldMin < 0 {
+		return nil, errors.New("shouldMin must be >= 0")
+	}
+	leaves := 1
+	for i := 0; i < g.depth; i++ {
+		if leaves *= g.maxFanOut; leaves > maxBooleanLeaves {
+			return nil, fmt.Errorf("maxFanOut^depth must be at most %d leaves", maxBooleanLeaves)
+		}
+	}
+	return g, nil
+}
+
+func init() {
+	registerQueryGenerator(62, "boolean-2x3", booleanQueryGen{
+		depth: 2, minFanOut: 2, maxFanOut: 3,
+		nodes: booleanNodeTypes, leaves: []string{"term", "match", "geo-range"},
+		shouldMin: 1, mustNot: true, words: "commonReviewWords", miss: missRateUnknown,
+	})
+	registerQueryGenerator(63, "boolean-3x4", booleanQueryGen{
+		depth: 3, minFanOut: 3, maxFanOut: 4,
+		nodes: booleanNodeTypes, leaves: booleanLeafTypes,
+		shouldMin: 1, mustNot: true, words: "commonReviewWords", miss: missRateUnknown,
+	})
+	registerQueryGenerator(64, "disjuncts-1x8", booleanQueryGen{
+		depth: 1, minFanOut: 8, maxFanOut: 8,
+		nodes: []string{"disjuncts"}, leaves: []string{"term"},
+		shouldMin: 1, words: "commonReviewWords", miss: missRateUnknown,
+	})
+}
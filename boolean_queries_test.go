@@ -0,0 +1,87 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// queryTreeDepth returns the number of compound levels of an FTS query
+// and checks the fan-out of each of them.
+func queryTreeDepth(t *testing.T, q map[string]interface{}, minFanOut, maxFanOut int) int {
+	var children []interface{}
+	if c, ok := q["conjuncts"].([]interface{}); ok {
+		children = c
+	} else if d, ok := q["disjuncts"].([]interface{}); ok {
+		children = d
+	} else if must, ok := q["must"].(map[string]interface{}); ok {
+		children = must["conjuncts"].([]interface{})
+		for _, key := range []string{"should", "must_not"} {
+			if sub, ok := q[key].(map[string]interface{}); ok {
+				children = append(children, sub["disjuncts"].([]interface{})...)
+			}
+		}
+	} else {
+		return 0
+	}
+	if len(children) < minFanOut || len(children) > maxFanOut {
+		t.Errorf("fan-out %d not in [%d, %d]: %v", len(children), minFanOut, maxFanOut, q)
+	}
+	depth := 0
+	for _, c := range children {
+		if d := queryTreeDepth(t, c.(map[string]interface{}), minFanOut, maxFanOut); d > depth {
+			depth = d
+		}
+	}
+	return depth + 1
+}
+
+func TestBooleanQueryShape(t *testing.T) {
+	var conf config
+	setBuiltinFtsData(&conf)
+	gen := queryGeneratorsByName["boolean-2x3"].gen.(configurableQueryGenerator)
+	p := newQueryParams(map[string]interface{}{
+		"depth": 3, "minFanOut": 2, "maxFanOut": 4, "shouldMin": 2,
+		"nodes":  []interface{}{"bool", "disjuncts"},
+		"leaves": []interface{}{"term", "query-string"},
+	})
+	g, err := gen.withParams(conf, p)
+	if err == nil {
+		err = p.check()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 100; i++ {
+		frag := g.generate(conf)
+		var v struct {
+			Query map[string]interface{} `json:"query"`
+		}
+		if err := json.Unmarshal([]byte("{"+frag+"}"), &v); err != nil {
+			t.Fatalf("%v in %s", err, frag)
+		}
+		if _, ok := v.Query["conjuncts"]; ok {
+			t.Fatalf("conjuncts nodes were not selected: %s", frag)
+		}
+		if d := queryTreeDepth(t, v.Query, 2, 4); d != 3 {
+			t.Fatalf("expected depth 3, got %d: %s", d, frag)
+		}
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"depth": 0},
+		{"minFanOut": 3, "maxFanOut": 2},
+		{"nodes": []interface{}{"xor"}},
+		{"leaves": "term"},
+		{"depth": 10, "maxFanOut": 8},
+		{"depth": 5, "maxFanOut": 5},
+	} {
+		p := newQueryParams(bad)
+		_, err := gen.withParams(conf, p)
+		if err == nil {
+			err = p.check()
+		}
+		if err == nil {
+			t.Errorf("expected an error for %v", bad)
+		}
+	}
+}
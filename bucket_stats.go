@@ -0,0 +1,157 @@
+package main
+
+import (
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+
+	"cb_fts_bench/internal"
+)
+
+// With --bucketStats the statistics are also kept per bucket (tenant), the
+// bucket of a request is the one named by /api/bucket/<name>/ of its URL,
+// else the -C bucket (after [[SEQ:#:##]]) or else the index name of
+// /api/index/<name> up to the first "." of a scoped name.
+
+var (
+	apiBucketRegexp = regexp.MustCompile(`/api/bucket/([^/?]+)`)
+	apiIndexRegexp  = regexp.MustCompile(`/api/index/([^/?.]+)`)
+	meteringRegexp  = regexp.MustCompile(`meter_ru_total\{.*bucket="([^"]*)".*for="fts"`)
+)
+
+type bucketStats struct {
+	latencies *uhist.Histogram
+
+	req1xx, req2xx, req3xx, req4xx, req5xx, others uint64
+	retryReq429                                    uint64
+	hits, bytesRead                                uint64
+}
+
+type bucketStatsMap struct {
+	mu sync.Mutex
+	m  map[string]*bucketStats
+	// ru is the meter_ru_total delta of each bucket with --showMetering
+	ru map[string]*big.Int
+}
+
+func newBucketStatsMap() *bucketStatsMap {
+	return &bucketStatsMap{m: map[string]*bucketStats{}}
+}
+
+// requestBucket returns the bucket requestURI goes to, kvBucket is the -C
+// bucket of the request.
+func requestBucket(requestURI, kvBucket string) string {
+	if m := apiBucketRegexp.FindStringSubmatch(requestURI); m != nil {
+		return m[1]
+	}
+	if kvBucket != "" {
+		return kvBucket
+	}
+	if m := apiIndexRegexp.FindStringSubmatch(requestURI); m != nil {
+		return m[1]
+	}
+	return "-"
+}
+
+func (bm *bucketStatsMap) get(bucket string) *bucketStats {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	bs, ok := bm.m[bucket]
+	if !ok {
+		bs = &bucketStats{latencies: uhist.Default()}
+		bm.m[bucket] = bs
+	}
+	return bs
+}
+
+func (bs *bucketStats) record(code int, usTaken uint64, hits, bytesRead int) {
+	bs.latencies.Increment(usTaken)
+	var counter *uint64
+	switch code / 100 {
+	case 1:
+		counter = &bs.req1xx
+	case 2:
+		counter = &bs.req2xx
+	case 3:
+		counter = &bs.req3xx
+	case 4:
+		counter = &bs.req4xx
+	case 5:
+		counter = &bs.req5xx
+	default:
+		counter = &bs.others
+	}
+	atomic.AddUint64(counter, 1)
+	atomic.AddUint64(&bs.hits, uint64(hits))
+	atomic.AddUint64(&bs.bytesRead, uint64(bytesRead))
+}
+
+// setRU sets the RU of each bucket to its end - beg of meter_ru_total.
+func (bm *bucketStatsMap) setRU(beg, end map[string]*big.Int) {
+	bm.ru = map[string]*big.Int{}
+	for bucket, e := range end {
+		d := new(big.Int).Set(e)
+		if b, ok := beg[bucket]; ok {
+			d.Sub(d, b)
+		}
+		bm.ru[bucket] = d
+	}
+}
+
+// results returns the per bucket results sorted by name.
+func (bm *bucketStatsMap) results() []internal.BucketResult {
+	bm.mu.Lock()
+	defer bm.mu.Unlock()
+	res := make([]internal.BucketResult, 0, len(bm.m))
+	for name, bs := range bm.m {
+		br := internal.BucketResult{
+			Name:       name,
+			Req1XX:     bs.req1xx,
+			Req2XX:     bs.req2xx,
+			Req3XX:     bs.req3xx,
+			Req4XX:     bs.req4xx,
+			Req5XX:     bs.req5xx,
+			Others:     bs.others,
+			Retries429: bs.retryReq429,
+			Hits:       bs.hits,
+			BytesRead:  bs.bytesRead,
+			Latencies:  bs.latencies,
+			RU:         -1,
+		}
+		if ru, ok := bm.ru[name]; ok {
+			br.RU = ru.Int64()
+		}
+		res = append(res, br)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// meteringRUByBucket sums the meter_ru_total{bucket=...,for="fts"} lines
+// of the _metering endpoint(s) per bucket.
+func meteringRUByBucket(lines []string) map[string]*big.Int {
+	ru := map[string]*big.Int{}
+	for _, line := range lines {
+		m := meteringRegexp.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		fields := strings.Fields(line)
+		flt, _, err := big.ParseFloat(fields[len(fields)-1], 10, 0, big.ToNearestEven)
+		if err != nil {
+			continue
+		}
+		i, _ := flt.Int(nil)
+		if sum, ok := ru[m[1]]; ok {
+			sum.Add(sum, i)
+		} else {
+			ru[m[1]] = i
+		}
+	}
+	return ru
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRequestBucket(t *testing.T) {
+	expectations := []struct {
+		uri, kvBucket, bucket string
+	}{
+		{"/api/bucket/ts01/scope/s/index/i/query", "other", "ts01"},
+		{"/api/index/ts02_fts_01/query", "ts02", "ts02"},
+		{"/api/index/ts03_fts_01/query", "", "ts03_fts_01"},
+		{"/api/index/ts04.inventory.hotels/query", "", "ts04"},
+		{"/query?x=1", "", "-"},
+	}
+	for _, e := range expectations {
+		if bucket := requestBucket(e.uri, e.kvBucket); bucket != e.bucket {
+			t.Errorf("%s %s: expected %s, got %s", e.uri, e.kvBucket, e.bucket, bucket)
+		}
+	}
+}
+
+func TestMeteringRUByBucket(t *testing.T) {
+	lines := []string{
+		`# TYPE meter_ru_total counter`,
+		`meter_ru_total{bucket="ts01",for="fts"} 9.34389e+07`,
+		`meter_ru_total{bucket="ts02",for="fts"} 1200`,
+		`meter_ru_total{bucket="ts02",for="kv"} 5000`,
+		`meter_ru_total{bucket="ts02",for="fts"} 300`,
+	}
+	ru := meteringRUByBucket(lines)
+	if len(ru) != 2 || ru["ts01"].Int64() != 93438900 || ru["ts02"].Int64() != 1500 {
+		t.Errorf("unexpected RU %v", ru)
+	}
+
+	bm := newBucketStatsMap()
+	bm.get("ts01").record(200, 100, 3, 10)
+	bm.get("ts03").record(503, 200, 0, 0)
+	bm.setRU(map[string]*big.Int{"ts01": big.NewInt(1000)}, map[string]*big.Int{"ts01": big.NewInt(1600), "ts02": big.NewInt(7)})
+	res := bm.results()
+	if len(res) != 2 || res[0].Name != "ts01" || res[0].RU != 600 || res[1].RU != -1 {
+		t.Errorf("unexpected results %+v", res)
+	}
+	if res[0].HitsPerRequest() != 3 || res[1].Req5XX != 1 || res[1].Requests() != 1 {
+		t.Errorf("unexpected counts %+v", res)
+	}
+}
+
+func TestBombardierBucketStats(t *testing.T) {
+	testAllClients(t, testBombardierBucketStats)
+}
+
+func testBombardierBucketStats(clientType clientTyp, t *testing.T) {
+	var (
+		mu     sync.Mutex
+		failed = map[string]bool{}
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			bucket := strings.Split(r.URL.Path, "/")[3]
+			mu.Lock()
+			first := !failed[bucket]
+			failed[bucket] = true
+			mu.Unlock()
+			// the first request to ts02 is throttled
+			if bucket == "ts02" && first {
+				rw.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			if bucket == "ts03" {
+				rw.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			rw.Write([]byte(`{"status": {"total": 1, "successful": 1}, "total_hits": 5, "bytesRead": 40, "hits": []}`))
+		}),
+	)
+	defer s.Close()
+
+	numReqs := uint64(60)
+	conf := config{
+		numConns:    2,
+		numReqs:     &numReqs,
+		url:         s.URL + "/api/bucket/ts[[SEQ:1:3:rr]]/scope/s/index/i/query",
+		headers:     new(headersList),
+		timeout:     defaultTimeout,
+		method:      "GET",
+		clientType:  clientType,
+		format:      knownFormat("json"),
+		printResult: true,
+		bucketStats: true,
+	}
+	conf.urlSeqs, _ = parseURLSeqs(conf.url, "")
+	b, err := newBombardier(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.disableOutput()
+	b.bombard()
+	out := new(bytes.Buffer)
+	b.redirectOutputTo(out)
+	b.printStats()
+
+	var info struct {
+		Result struct {
+			Buckets []struct {
+				Name       string
+				Requests   uint64
+				Req2xx     uint64
+				Req5xx     uint64
+				Retries429 uint64
+				Hits       uint64
+				BytesRead  uint64
+				Latency    *struct{ Mean float64 }
+				RU         *int64
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("%v in %s", err, out)
+	}
+	buckets := info.Result.Buckets
+	if len(buckets) != 3 {
+		t.Fatalf("expected 3 buckets, got %s", out)
+	}
+	total := uint64(0)
+	for _, bs := range buckets {
+		total += bs.Requests
+		if bs.Latency == nil || bs.RU != nil {
+			t.Errorf("%s: expected latencies and no RU, got %s", bs.Name, out)
+		}
+		switch bs.Name {
+		case "ts01":
+			if bs.Req2xx != bs.Requests || bs.Hits != 5*bs.Requests || bs.BytesRead != 40*bs.Requests {
+				t.Errorf("unexpected ts01 stats in %s", out)
+			}
+		case "ts03":
+			if bs.Req5xx != bs.Requests || bs.Hits != 0 {
+				t.Errorf("unexpected ts03 stats in %s", out)
+			}
+		}
+	}
+	if total != numReqs {
+		t.Errorf("expected %d requests over the buckets, got %s", numReqs, out)
+	}
+	// the retry goes to the next bucket in turn, the 429 stays with ts02
+	if buckets[1].Retries429 != 1 || b.retryReq429 != 1 {
+		t.Errorf("expected the 429 retry on ts02, got %s", out)
+	}
+}
@@ -16,12 +16,12 @@ import (
 	"bufio"
 	"log"
 	"net/http"
+	"crypto/tls"
 	"math/big"
 
 	"github.com/cheggaaa/pb"
 	fhist "github.com/codesenberg/concurrent/float64/histogram"
 	uhist "github.com/codesenberg/concurrent/uint64/histogram"
-	uuid "github.com/satori/go.uuid"
 
 	b64 "encoding/base64"
 
@@ -63,9 +63,28 @@ type bombardier struct {
 	timeTaken time.Duration
 	latencies *uhist.Histogram
 	requests  *fhist.Histogram
+	// recall@k of the knn queries checked against --groundTruth
+	recall *fhist.Histogram
+	// latencies are end-to-end (retries and backoffs included),
+	// attemptLatencies those of every attempt
+	attemptLatencies *uhist.Histogram
 
 	client     client
 	ack_client client
+
+	// FTS query generator(s) used to replace __FTS_QUERY__
+	queryMix *queryMix
+	// reqTemplates are the --template body and URL, nil without
+	reqTemplates *requestTemplates
+	// per page stats with --pages
+	pages []*pageStats
+	// per bucket stats with --bucketStats
+	buckets *bucketStatsMap
+	// the --target nodes with their stats, nil without
+	nodes *nodeBalancer
+	// retry policy and the retries
+	retry   *retryPolicy
+	retries *retryStats
 	doneChan   chan struct{}
 
 	// RPS metrics
@@ -108,6 +127,9 @@ type bombardier struct {
         resp_tot_bytesRead uint64
 	resp_withhits_cnt uint64
 	hits_tot_bytes uint64
+	resp_withfacets_cnt uint64
+	facets_tot_buckets uint64
+	facets_tot_bytes uint64
 	tot_kv_reads uint64
 	tot_kv_bytes_read uint64
 	tot_kv_read_us int64
@@ -138,153 +160,6 @@ const TEST_HELP string =
 	# commonReviewWords          has    50 items from https://www.researchgate.net/figure/Fifty-Most-Frequently-used-Words-in-60-648-Hotel-Review-Comments_tbl1_233475559
 	# commonEnglishWords         has   100 items form https://www.espressoenglish.net/the-100-most-common-words-in-english/
 	# commonVerbWords            has    34 items from https://literacyforall.org/docs/100_Most_common_in_American_English.pdf
-
-    The following tests are supported via -L #
-
-
-    1: //  0.0% misses
-	repl = buildBasicRandomQueryMatch(1, conf.sampleReviewWords, conf.sampleReviewWordsLen)
-
-        select one random word from sampleReviewWords 
-
-
-    31: //  0.0% misses
-	repl = buildBasicRandomQueryNumWords(1, conf.sampleReviewWords, conf.sampleReviewWordsLen)
-
-        select one random word from sampleReviewWords 
-
-	example: 
-		"query": { "query": "+euro" } 
-
-    32: // 90.4% misses
-	repl = buildBasicRandomQueryNumWords(2, conf.sampleReviewWords, conf.sampleReviewWordsLen)
-
-        selects two random words from sampleReviewWords 
-
-	example: 
-		"query": { "query": "+wong +skews" } 
-
-    33: //  0.0% misses
-	repl = buildBasicRandomQueryNumWords(1, conf.commonReviewWords, conf.commonReviewWordsLen)
-
-        select one random word from commonReviewWords
-
-	example: 
-		"query": { "query": "+Store" } 
-
-    34: //  1.3%  misses
-	repl = buildBasicRandomQueryNumWords(2, conf.commonReviewWords, conf.commonReviewWordsLen)
-
-        select two random words from commonReviewWords
-
-	example: 
-		"query": { "query": "+Toilet +Noisy" } 
-
-    35: // 10.6% misses
-	repl = buildBasicRandomQueryNumWords(3, conf.commonReviewWords, conf.commonReviewWordsLen)
-
-        select three random words from commonReviewWords
-
-	example: 
-		"query": { "query": "+Downtown +Clerk +View" } 
-
-
-    36: // 30.4% misses
-	repl = buildBasicRandomQueryNumWords(4, conf.commonReviewWords, conf.commonReviewWordsLen)
-
-        select four random words from commonReviewWords
-
-	example: 
-		"query": { "query": "+Bath +Dinner +Clerk +Price" }
-
-
-    37: //  51.0% misses
-	repl = buildBasicRandomQueryTwoNumWords(1, conf.sampleReviewWords, conf.commonReviewWordsLen, 1, conf.commonReviewWords, conf.commonReviewWordsLen)
-
-        select one random word from sampleReviewWords AND select one random word from commonReviewWords
-
-	example: 
-		"query": { "query": "+absolut +Broken" }
-
-
-    38: // 77.0% misses
-	repl = buildBasicRandomQueryTwoNumWords(1, conf.sampleReviewWords, conf.commonReviewWordsLen, 2, conf.commonReviewWords, conf.commonReviewWordsLen)
-
-        select one random word from sampleReviewWords AND select two random words from commonReviewWords
-
-	example: 
-		"query": { "query": "+abbas +Staff +Noisy" } 
-
-    39: // 88.4% misses
-	repl = buildBasicRandomQueryTwoNumWords(1, conf.sampleReviewWords, conf.commonReviewWordsLen, 3, conf.commonReviewWords, conf.commonReviewWordsLen)
-
-        select one random word from sampleReviewWords AND select three random words from commonReviewWords
-
-	example: 
-		"query": { "query": "+aboard +Towel +Rate +Noise" }
-
-    40: // 94.7% misses
-	repl = buildBasicRandomQueryTwoNumWords(1, conf.sampleReviewWords, conf.commonReviewWordsLen, 4, conf.commonReviewWords, conf.commonReviewWordsLen)
-
-        select one random word from sampleReviewWords AND select four random words from commonReviewWords
-
-	example: 
-		"query": { "query": "+abby +Store +Downtown +Dine +Dinner" }
-
-    41: // 74.1% misses
-	repl = buildFuzzyRandomQuery(1, 5, conf.commonReviewWords, conf.commonReviewWordsLen)
-
-        select one random word from commonReviewWords with length at least 5 chars and apply fuzziness of 1
-	AND then randomly replace one char [a-z] in any position of the term to search for from commonReviewWords
-
-	example (the word was Sheet):
-		"query": { "term": "Shdet", "fuzziness": 1 } 
-
-    42: //  0.0% misses
-	repl = buildFuzzyRandomQuery(2, 5, conf.commonReviewWords, conf.commonReviewWordsLen)
-
-        select one random word from commonReviewWords with length at least 5 chars and apply fuzziness of 2
-	AND then randomly replace one char [a-z] in any position of the term to search for from commonReviewWords
-
-	example (the word was Sheet):
-		"query": { "term": "Downiown", "fuzziness": 2 } 
-
-    43: // 14.0% misses
-	repl = buildPseudoGeoRandomQuery(0.25, conf, c) // 2 range conjuncts
-
-	Take a random hotel location lat/lon and apply some random offsets to get a new lat/lon point then build a
-        bounding box by adjusting the centroid by random amounts - this is a pseudo geo search - via conjuncts
-
-	example:
-		"query": {"conjuncts":[ 
-			{  "min": -122.479164, "max": -122.345833,  "inclusive_min": false,  "inclusive_max": false,  "field": "geo.lon" },
-			{  "min": 37.801128, "max": 37.909462,  "inclusive_min": false,  "inclusive_max": false,  "field": "geo.lat" }
-		]}
-
-    99: //  9.9% misses
-	repl = buildBasicRandomRandomTermsQuery(conf, c)
-
-        select two random word from commonReviewWords
-	50% probability add another random word from commonReviewWords
-	50% probability add another random word from commonEnglishWords
-	50% probability add another random word from commonVerbWords
-
-	example:
-		"query": { "query": "+Noisy +Affordable +Staff +have" } 
-	
-
-    OTHER: // 16.5% misses
-
-	If no test is selected we randomly apply the following
-
-	// 80% or 8 out of 10 queries are radom query terms
-	repl = buildBasicRandomRandomTermsQuery(conf, c)
-
-	// 10% or 1 out of 10 queries are simple fuzzy
-	 repl = buildFuzzyRandomQuery(1, 5, conf.commonReviewWords, conf.commonReviewWordsLen)
-
-	// 10% ro 1 out of 10 queries are 2-"conjuncts" min/max
-	repl = buildPseudoGeoRandomQuery(0.25, conf, c)
 `
 
 // Inc increments the counter for the given key.
@@ -344,7 +219,20 @@ func newBombardier(c config) (*bombardier, error) {
 	b.ackCodes = SafeCounter{v: make(map[string]int)}
 
 	b.latencies = uhist.Default()
+	b.attemptLatencies = uhist.Default()
 	b.requests = fhist.Default()
+	b.recall = fhist.Default()
+	if c.pages > 1 {
+		b.pages = newPageStats(c.pages)
+	}
+	if c.bucketStats {
+		b.buckets = newBucketStatsMap()
+	}
+	b.retry = c.retry
+	if b.retry == nil {
+		b.retry = defaultRetryPolicy(c.minBackoff)
+	}
+	b.retries = newRetryStats(b.retry)
 	b.reqno = 0
 
 	if b.conf.testType() == counted {
@@ -489,11 +377,45 @@ func newBombardier(c config) (*bombardier, error) {
 		}
 	}
 
+	if pbody != nil {
+		if err := c.requestOptions.checkBody(*pbody); err != nil {
+			return nil, err
+		}
+		// a sort or facets of the -b body or --requestOptions win over
+		// the ones of the FTS query generators and --facets
+		b.conf.hasSort = setsMember(*pbody, c.requestOptions, "sort")
+		b.conf.hasFacets = setsMember(*pbody, c.requestOptions, "facets")
+	}
+
 	if b.conf.basicAuth != "" {
 		sEnc := b64.StdEncoding.EncodeToString([]byte(b.conf.basicAuth))
 		b.conf.headers.Set("Authorization: Basic " + sEnc)
 	}
 
+	if c.discover != "" {
+		nodes, err := discoverSearchNodes(c.discover, c.basicAuth, c.insecure, c.timeout)
+		if err != nil {
+			return nil, err
+		}
+		https := strings.HasPrefix(c.url, "https://")
+		for _, n := range nodes {
+			b.conf.targets = append(b.conf.targets, n.addr(https))
+		}
+		b.conf.meteringURLs = meteringURLs(nodes, https)
+	}
+	if len(b.conf.targets) > 0 {
+		b.nodes, err = newNodeBalancer(b.conf.targets, c.url, c.balance, c.ejectAfter, c.ejectFor)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// a tenant with a client cert has no Authorization header, over http
+	// the cert isn't presented either
+	if c.credentials != nil && len(c.credentials.certs()) > 0 && !strings.HasPrefix(strings.ToLower(c.url), "https://") {
+		return nil, errCertCredentialsNeedTLS
+	}
+
 	cc := &clientOpts{
 		HTTP2:             false,
 		maxConns:          c.numConns,
@@ -508,6 +430,8 @@ func newBombardier(c config) (*bombardier, error) {
 		bodProd:      bsp,
 		bytesRead:    &b.bytesRead,
 		bytesWritten: &b.bytesWritten,
+		credentials:  c.credentials,
+		balancer:     b.nodes,
 	}
 	b.client = makeHTTPClient(c.clientType, cc)
 
@@ -600,11 +524,45 @@ func newBombardier(c config) (*bombardier, error) {
 		b.bar.NotPrint = true
 	}
 
+	b.queryMix, err = queryMixFromConfig(b.conf)
+	if err != nil {
+		return nil, err
+	}
+	// the FTS queries to send may need longer words than the --wordList files have
+	if c.template || (pbody != nil && strings.Contains(*pbody, fts_query_pat)) {
+		if err = b.queryMix.checkWords(b.conf); err != nil {
+			return nil, err
+		}
+	}
+
 	b.template, err = b.prepareTemplate()
 	if err != nil {
 		return nil, err
 	}
 
+	if c.template {
+		if pbody == nil {
+			return nil, errTemplateNeedsBody
+		}
+		b.reqTemplates, err = newRequestTemplates(b.conf, b.queryMix, *pbody, c.url)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.data != nil {
+		texts := []string{c.url}
+		if pbody != nil {
+			texts = append(texts, *pbody)
+		}
+		for _, h := range *c.headers {
+			texts = append(texts, h.value)
+		}
+		if err := c.data.check(c.numConns, texts...); err != nil {
+			return nil, err
+		}
+	}
+
 	b.wg.Add(int(c.numConns))
 	b.errors = newErrorMap()
 	b.doneChan = make(chan struct{}, 2)
@@ -662,12 +620,9 @@ func (b *bombardier) prepareTemplate() (*template.Template, error) {
 			"StringToBytes": func(s string) []byte {
 				return []byte(s)
 			},
-			"UUIDV1": uuid.NewV1,
-			"UUIDV2": uuid.NewV2,
-			"UUIDV3": uuid.NewV3,
-			"UUIDV4": uuid.NewV4,
-			"UUIDV5": uuid.NewV5,
-		}).Parse(string(templateBytes))
+		}).
+		Funcs(uuidTemplateFuncs).
+		Parse(string(templateBytes))
 
 	if err != nil {
 		return nil, err
@@ -700,7 +655,7 @@ func (b *bombardier) writeStatistics(
 	atomic.AddUint64(counter, 1)
 }
 
-func (b *bombardier) performSingleRequest() {
+func (b *bombardier) performSingleRequest(conf config) {
 
 	// fmt.Println(b.client)
 	// fmt.Println(b.conf.customAck)
@@ -715,7 +670,7 @@ func (b *bombardier) performSingleRequest() {
 	}
 	// fmt.Println("preqno",preqno,"b.conf.dynDoc",b.conf.dynDoc,"b.conf.dynDocSz",b.conf.dynDocSz);
 
-	code, usTaken, ackBody, numToAck, err := b.client.do(b, preqno, b.conf, "", 0)
+	code, usTaken, ackBody, numToAck, err := b.client.do(b, preqno, conf, "", 0)
 	if err != nil {
 		b.errors.add(err)
 	}
@@ -730,7 +685,7 @@ func (b *bombardier) performSingleRequest() {
 			// fmt.Println("COUCHBASE (customAck num "+strconv.Itoa(numToAck)+") sending a customAck based on prior HTTP couchbase /deq or /deq/bulk, size req was ", b.conf.reqBatchSz)
 			// fmt.Println("COUCHBASE (customAck num "+strconv.Itoa(numToAck)+") with body\n" + string(ackBody) + "\n")
 		}
-		code, _, _, _, err := b.ack_client.do(b, preqno, b.conf, string(ackBody), numToAck)
+		code, _, _, _, err := b.ack_client.do(b, preqno, conf, string(ackBody), numToAck)
 		if err != nil {
 			fmt.Println("COUCHBASE (customAck) failed in send of customAck based on prior HTTP couchbase /deq or /deq/bulk", code)
 		} else {
@@ -745,13 +700,21 @@ func (b *bombardier) performSingleRequest() {
 	}
 }
 
-func (b *bombardier) worker() {
+func (b *bombardier) worker(id uint64) {
+	conf := b.conf
+	conf.rng = workerRand(b.conf.seed, id)
+	if b.reqTemplates != nil {
+		conf.tmpl = b.reqTemplates.forWorker(conf, b.queryMix)
+	}
+	if b.conf.data != nil {
+		conf.dataRows = b.conf.data.forWorker(id, b.conf.numConns)
+	}
 	done := b.barrier.done()
 	for b.barrier.tryGrabWork() {
 		if b.ratelimiter.pace(done) == brk {
 			break
 		}
-		b.performSingleRequest()
+		b.performSingleRequest(conf)
 		b.barrier.jobDone()
 	}
 }
@@ -821,10 +784,10 @@ func (b *bombardier) bombard() {
 	bombardmentBegin := time.Now()
 	b.start = time.Now()
 	for i := uint64(0); i < b.conf.numConns; i++ {
-		go func() {
+		go func(id uint64) {
 			defer b.wg.Done()
-			b.worker()
-		}()
+			b.worker(id)
+		}(i)
 	}
 	go b.rateMeter()
 	go b.barUpdater()
@@ -843,6 +806,32 @@ func (b *bombardier) printIntro() {
 		fmt.Fprintf(b.out, "Bombarding %v for %v using %v connection(s)\n",
 			b.conf.url, *b.conf.duration, b.conf.numConns)
 	}
+	if b.conf.replay != nil {
+		fmt.Fprintln(b.out, b.conf.replay.describe())
+	} else {
+		fmt.Fprintf(b.out, "Query seed %d\n", b.conf.seed)
+	}
+	if b.conf.requestOptions != nil {
+		fmt.Fprintf(b.out, "Request options %v\n", b.conf.requestOptions)
+	}
+	if b.conf.urlSeqs != nil {
+		fmt.Fprintf(b.out, "URL sequences %v\n", b.conf.urlSeqs)
+	}
+	if b.conf.data != nil {
+		fmt.Fprintln(b.out, b.conf.data.describe())
+	}
+	if b.conf.credentials != nil {
+		fmt.Fprintln(b.out, b.conf.credentials.describe())
+	}
+	if b.conf.discover != "" {
+		fmt.Fprintf(b.out, "Search nodes discovered via %s\n", b.conf.discover)
+	}
+	if b.nodes != nil {
+		fmt.Fprintln(b.out, b.nodes.describe())
+	}
+	if b.conf.retry != nil {
+		fmt.Fprintln(b.out, b.retry.describe())
+	}
 }
 
 func (b *bombardier) gatherInfo() internal.TestInfo {
@@ -864,6 +853,9 @@ func (b *bombardier) gatherInfo() internal.TestInfo {
 			ClientType: internal.ClientType(b.conf.clientType),
 
 			Rate: b.conf.rate,
+
+			RequestOptions: b.conf.requestOptions.String(),
+			Seed:           b.conf.seed,
 		},
 		Result: internal.Results{
 			BytesRead:    b.bytesRead,
@@ -892,6 +884,9 @@ func (b *bombardier) gatherInfo() internal.TestInfo {
 
 			Latencies: b.latencies,
 			Requests:  b.requests,
+			Recall:    b.recall,
+
+			AttemptLatencies: b.attemptLatencies,
 		},
 	}
 
@@ -913,6 +908,14 @@ func (b *bombardier) gatherInfo() internal.TestInfo {
 		}
 	}
 
+	if b.buckets != nil {
+		info.Result.Buckets = b.buckets.results()
+	}
+	if b.nodes != nil {
+		info.Result.Nodes = b.nodes.results()
+	}
+	info.Result.Retries = b.retries.result()
+
 	for _, ewc := range b.errors.byFrequency() {
 		info.Result.Errors = append(info.Result.Errors,
 			internal.ErrorWithCount{
@@ -955,9 +958,10 @@ func LinesFromReader(r io.Reader) ([]string, error) {
         return lines, nil
 }
 
-func getLinesFromUrl( username string, passwd string, host string) ([]string, error) {
-    client := &http.Client{}
-    req, err := http.NewRequest("GET", "http://" + host + ":8094/_metering", nil)
+func getLinesFromUrl( username string, passwd string, baseURL string, insecure bool) ([]string, error) {
+    /* #nosec */
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure}}}
+    req, err := http.NewRequest("GET", baseURL + "/_metering", nil)
     req.SetBasicAuth(username, passwd)
     resp, err := client.Do(req)
     if err != nil{
@@ -967,24 +971,36 @@ func getLinesFromUrl( username string, passwd string, host string) ([]string, er
     return LinesFromReader(resp.Body)
 }
 
-func dumpMetering( username string, passwd string, host string, altMeteringHost string, tag string) (*big.Int) {
+// meteringBaseURLs returns the search nodes to read _metering from, the
+// --discover ones or else the URL host and --altMeteringHost.
+func meteringBaseURLs(cfg config, host string) []string {
+	if len(cfg.meteringURLs) > 0 {
+		return cfg.meteringURLs
+	}
+	urls := []string{"http://" + host + ":8094"}
+	if len(cfg.altMeteringHost) > 0 {
+		urls = append(urls, "http://" + cfg.altMeteringHost + ":8094")
+	}
+	return urls
+}
 
-	total := big.NewInt(0)
+// dumpMetering returns the total and the per bucket meter_ru_total of FTS
+// summed over the _metering of urls.
+func dumpMetering( username string, passwd string, urls []string, insecure bool, tag string) (*big.Int, map[string]*big.Int) {
 
-        lines, err := getLinesFromUrl(username,passwd,host)
-        if err != nil {
-                log.Fatal(err)
-        }
+	total := big.NewInt(0)
 
-        lh1 := len(lines)
-	if len(altMeteringHost) > 0 {
-		lines2, err2 := getLinesFromUrl(username,passwd,altMeteringHost)
-		if err2 != nil {
+	var lines []string
+	// srcs[i] is the index in urls of lines[i]
+	var srcs []int
+	for sn, u := range urls {
+		ulines, err := getLinesFromUrl(username,passwd,u,insecure)
+		if err != nil {
 			log.Fatal(err)
 		}
-
-		for _, x := range lines2 {
+		for _, x := range ulines {
 			lines = append(lines, x)
+			srcs = append(srcs, sn)
 		}
 	}
 
@@ -1007,25 +1023,24 @@ func dumpMetering( username string, passwd string, host string, altMeteringHost
 
 			total.Add(total,i);
 
-			sn := 1
-			src := host
-			if (cnt > lh1) {
-			    src = altMeteringHost
-			    sn = 2
-			} 
-                        log.Printf("%s -or- %d %v (src %d %s)\n", line, i, acc, sn, src)
+			sn := srcs[cnt-1]
+                        log.Printf("%s -or- %d %v (src %d %s)\n", line, i, acc, sn+1, urls[sn])
                 }
         }
         log.Printf("TOTAL RU %-10s = %12d\n", tag, total)
-	return total
+	return total, meteringRUByBucket(lines)
 }
 
 func main() {
 
+	if len(os.Args) > 1 && os.Args[1] == harvestCommand {
+		os.Exit(runHarvest(os.Args))
+	}
+
 	arg_len:= len(os.Args[1:])
 	for i := 0; i < arg_len; i++ {
 		if os.Args[i+1] == "-J" || os.Args[i+1] == "--ftsTestHelp" {
-			fmt.Println(TEST_HELP);
+			fmt.Print(ftsTestHelp())
 			os.Exit(0)
 		}
 	}
@@ -1035,37 +1050,47 @@ func main() {
 		fmt.Println(err)
 		os.Exit(exitFailure)
 	}
+	if cfg.dryRun != "" {
+		os.Exit(runDryRun(cfg))
+	}
+
+	initKvCollections(cfg)
 
         up := strings.Split(cfg.basicAuth, ":")
 	url, _ := url.Parse(cfg.url)
 	host := url.Hostname()
 
-	begRU := big.NewInt(0)
-	if cfg.showMetering {
-		fmt.Printf("\n")
-		begRU = dumpMetering(up[0],up[1],host,cfg.altMeteringHost,"(begRU)")
-		fmt.Printf("\n")
-	}
 
 
 if cfg.dynFtsShow {
-	fmt.Printf("# %-20s has %9d items\n","sampleReviewWords",cfg.sampleReviewWordsLen);
-	fmt.Printf("# %-20s has %9d items\n","commonReviewWords",cfg.commonReviewWordsLen);
-	fmt.Printf("# %-20s has %9d items\n","commonEnglishWords",cfg.commonEnglishWordsLen);
-	fmt.Printf("# %-20s has %9d items\n","commonVerbWords",cfg.commonVerbWordsLen);
+	for _, name := range cfg.wordListNames() {
+		fmt.Printf("# %-20s has %9d items\n",name,len(cfg.wordList(name)));
+	}
 	fmt.Printf("# %-20s has %9d items\n","hotelLocationLatLons",cfg.hotelLocationLatLonsLen);
+	if len(cfg.vectors) > 0 {
+		fmt.Printf("# %-20s has %9d items of %d dims\n","vectors",len(cfg.vectors),len(cfg.vectors[0]));
+	}
 }
 
-        start := time.Now()
-
-        // fmt.Println(elapsed.Microseconds(),"uS")
-
-
 	bombardier, err := newBombardier(cfg)
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(exitFailure)
 	}
+
+	// after newBombardier, --discover may have found the search nodes
+	begRU := big.NewInt(0)
+	var begBucketRU map[string]*big.Int
+	if cfg.showMetering {
+		fmt.Printf("\n")
+		begRU, begBucketRU = dumpMetering(up[0],up[1],meteringBaseURLs(bombardier.conf,host),cfg.insecure,"(begRU)")
+		fmt.Printf("\n")
+	}
+
+        start := time.Now()
+
+        // fmt.Println(elapsed.Microseconds(),"uS")
+
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
 	go func() {
@@ -1073,6 +1098,15 @@ if cfg.dynFtsShow {
 		bombardier.barrier.cancel()
 	}()
 	bombardier.bombard()
+	var endRU *big.Int
+	if cfg.showMetering {
+		// before the stats so that they can show the RU per bucket
+		var endBucketRU map[string]*big.Int
+		endRU, endBucketRU = dumpMetering(up[0],up[1],meteringBaseURLs(bombardier.conf,host),cfg.insecure,"(endRU)")
+		if bombardier.buckets != nil {
+			bombardier.buckets.setRU(begBucketRU, endBucketRU)
+		}
+	}
 	if bombardier.conf.printResult {
 		bombardier.printStats()
 	}
@@ -1100,10 +1134,27 @@ if cfg.dynFtsShow {
 		fmt.Printf("    resp.body.hit[]: %9s bytes (across %9d reqs having hits [%4.2f%%])\n", "n/a", bombardier.resp_withhits_cnt, 
 			float64(bombardier.resp_withhits_cnt)/float64(bombardier.resp_cnt) * 100)
 	}
+	if len(cfg.facets) > 0 {
+		if bombardier.resp_withfacets_cnt != 0 {
+			fmt.Printf("    resp.body.facets:%9d bytes (across %9d reqs having facets [%4.2f%%])\n", bombardier.facets_tot_bytes / bombardier.resp_withfacets_cnt,
+				bombardier.resp_withfacets_cnt,
+				float64(bombardier.resp_withfacets_cnt)/float64(bombardier.resp_cnt) * 100)
+		} else {
+			fmt.Printf("    resp.body.facets:%9s bytes (across %9d reqs having facets)\n", "n/a", bombardier.resp_withfacets_cnt)
+		}
+	}
 
 	fmt.Println("  Other");
 	fmt.Printf("    total_hits        %12d, ave %9.3f\n",bombardier.resp_tot_hits, float64(bombardier.resp_tot_hits)/float64(bombardier.resp_cnt))
 	fmt.Printf("    tot_hits_docreads %12d, ave %9.3f\n",bombardier.resp_tot_hits_docreads, float64(bombardier.resp_tot_hits_docreads)/float64(bombardier.resp_cnt))
+	if len(cfg.facets) > 0 {
+		fmt.Printf("    facet_buckets     %12d, ave %9.3f\n",bombardier.facets_tot_buckets, float64(bombardier.facets_tot_buckets)/float64(bombardier.resp_cnt))
+		fmt.Printf("    facet_bytes       %12d, ave %9.3f\n",bombardier.facets_tot_bytes, float64(bombardier.facets_tot_bytes)/float64(bombardier.resp_cnt))
+	}
+	fmt.Printf("    seed              %12d\n", cfg.seed)
+	if cfg.requestOptions != nil {
+		fmt.Printf("    request options   %v\n", cfg.requestOptions)
+	}
 	fmt.Printf("    status.total      %12d\n",bombardier.resp_tot_status_total)
 	fmt.Printf("    status.failed     %12d\n",bombardier.resp_tot_status_failed)
 	fmt.Printf("    status.successful %12d\n",bombardier.resp_tot_status_successful)
@@ -1116,6 +1167,9 @@ if cfg.dynFtsShow {
 		float64(bombardier.resp_tot_bytesRead)/float64(elapsedUs/1000.0/1000.0), 
 		float64(bombardier.resp_tot_bytesRead)/float64(elapsedUs/1000.0/1000.0)/1000.0/1000.0)
 
+	if len(bombardier.pages) > 0 {
+		printPageStats(cfg, bombardier.pages)
+	}
         if len(cfg.kvDocLookups) > 0 {
 	    //fmt.Printf("Arg -K 'host' ... read the above tot_hits_docreads directly from KV .. need stats ...")
 	    fmt.Println("  KV reads (due to -K)");
@@ -1128,8 +1182,6 @@ if cfg.dynFtsShow {
 	fmt.Println();
 
 	if cfg.showMetering {
-	    endRU := dumpMetering(up[0],up[1],host,cfg.altMeteringHost,"(endRU)")
-
 	    deltaRU := big.NewInt(0).Sub(endRU, begRU)
 	    zero := big.NewInt(0)
 
@@ -1145,8 +1197,8 @@ if cfg.dynFtsShow {
 		log.Printf("bytes/RU            = %12.3f\n", byte_per_ru)
 
 		numbkts := big.NewInt(1)
-		if cfg.lenBucketSeq > 0 {
-		    numbkts = big.NewInt(int64(cfg.endBucketSeq - cfg.begBucketSeq + 1))
+		if cfg.urlSeqs != nil {
+		    numbkts = big.NewInt(int64(cfg.urlSeqs.bucket.group.size))
 		}
 		nbfloat := new(big.Float).SetInt(numbkts)
 		log.Printf("numbkts             = %12d\n", numbkts)
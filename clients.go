@@ -18,7 +18,6 @@ import (
 	"fmt"
 	// "os"
 	"encoding/json"
-	"math/rand"
 
         "github.com/tidwall/gjson"
 
@@ -30,6 +29,23 @@ import (
 
 type client interface {
 	do(b *bombardier, preqno uint64, conf config, altbody string, acksToSend int) (code int, usTaken uint64, ackBody []byte, numToAck int, err error)
+	// builder returns the requestBuilder of the requests of do
+	builder() *requestBuilder
+}
+
+// exchanger sends a prepared request.
+type exchanger interface {
+	exchange(p *preparedRequest) (resp response, err error)
+}
+
+// response is what an exchanger got back, code is -1 on an error and body
+// is valid until release is called.
+type response struct {
+	code int
+	body []byte
+	// retryAfter is the Retry-After header, "" without
+	retryAfter string
+	release    func()
 }
 
 type bodyStreamProducer func() (io.ReadCloser, error)
@@ -49,16 +65,25 @@ type clientOpts struct {
 	bodProd bodyStreamProducer
 
 	bytesRead, bytesWritten *int64
+
+	// the tenants of --credentials with a client cert get their own
+	// connections
+	credentials *credentialsMap
+
+	// with --target the requests go to the node the balancer picks
+	balancer *nodeBalancer
 }
 
 type fasthttpClient struct {
-	client *fasthttp.HostClient
-
-	headers                  *fasthttp.RequestHeader
-	host, requestURI, method string
+	requestBuilder
 
-	body    *string
-	bodProd bodyStreamProducer
+	client *fasthttp.HostClient
+	// certClients are the clients of the --credentials client certs
+	certClients map[*credential]*fasthttp.HostClient
+	// nodeClients are the clients (and client cert clients) of the
+	// --target nodes
+	nodeClients     map[*targetNode]*fasthttp.HostClient
+	nodeCertClients map[*targetNode]map[*credential]*fasthttp.HostClient
 }
 
 // ========= JAS =========
@@ -76,6 +101,7 @@ type CbFtsRespShort struct {
 	Hits[] struct {
 		Id      string `json:"id"`
 	} `json:"hits"`
+	Facets map[string]ftsFacetResult `json:"facets"`
 }
 
 type CbQueueOneRespShort struct {
@@ -139,245 +165,67 @@ func formatJSON(data []byte) ([]byte, error) {
 
 // =======================
 
-func replaceAtIndex(in string, r rune, i int) string {
-    out := []rune(in)
-    out[i] = r
-    return string(out)
-}
-
-func randIntFromRange(min int, max int) int {
-    return rand.Intn(max - min + 1) + min
-}
-
-func buildBasicRandomQueryMatch(numterms int, words []string, wordsLen int) string {
-	var num int
-	repl := "";
-
-	for i := 1; i <= numterms; i++ {
-	    num = randIntFromRange(0,wordsLen -1);
-            if i == 1 {
-	        repl = words[num];
-            } else {
-	        repl = repl + words[num];
-	    }
-	}
-
-	// picks up only 'hotel' (not 'landmark')
-	// repl = "\"query\": {  \"match\": \"balcony\",  \"field\": \"reviews.content\" }"
-
-	repl = "\"query\": {  \"match\": \""+ repl + "\",  \"field\": \"reviews.content\" }"
-
-	return repl
-} 
-
-func buildBasicRandomQueryNumWords(numterms int, words []string, wordsLen int) string {
-	var num int
-	repl := "";
-
-	for i := 1; i <= numterms; i++ {
-	    num = randIntFromRange(0,wordsLen -1);
-            if i == 1 {
-	        repl = "+" + words[num];
-            } else {
-	        repl = repl + " +" + words[num];
-	    }
-	}
-
-	// picks up both 'hotel' and 'landmark'
-	repl = "\"query\": { \"query\": \""+ repl + "\" }"
-
-
-	return repl
-} 
-
-func buildBasicRandomQueryTwoNumWords(numterms int, words []string, wordsLen int, numterms2 int, words2 []string, words2Len int) string {
-	var num int
-	repl := "";
-
-	for i := 1; i <= numterms; i++ {
-	    num = randIntFromRange(0,wordsLen -1);
-            if i == 1 {
-	        repl = "+" + words[num];
-            } else {
-	        repl = repl + " +" + words[num];
-	    }
-	}
-
-	for i := 1; i <= numterms2; i++ {
-	    num = randIntFromRange(0,words2Len -1);
-	    repl = repl + " +" + words2[num];
-	}
-
-	repl = "\"query\": { \"query\": \""+ repl + "\" }"
-
-	return repl
-} 
-
-func buildFuzzyRandomQuery(fuzziness int, termminlen int, words []string, wordsLen int) string {
-	var num int
-	var word1 string;
-	repl := "";
-
-	for {
-		num = randIntFromRange(0,wordsLen -1);
-		word1 = words[num];
-		if len(word1) >= termminlen {
-		    break;
+func newFastHTTPClient(opts *clientOpts) client {
+	c := &fasthttpClient{requestBuilder: newRequestBuilder(opts)}
+	isTLS := c.scheme == "https"
+	c.client = newFastHTTPHostClient(c.host, isTLS, opts, opts.tlsConfig)
+	c.certClients = newCertHostClients(c.host, isTLS, opts)
+	if opts.balancer != nil {
+		c.nodeClients = map[*targetNode]*fasthttp.HostClient{}
+		c.nodeCertClients = map[*targetNode]map[*credential]*fasthttp.HostClient{}
+		for _, n := range opts.balancer.nodes {
+			c.nodeClients[n] = newFastHTTPHostClient(n.addr, isTLS, opts, opts.tlsConfig)
+			c.nodeCertClients[n] = newCertHostClients(n.addr, isTLS, opts)
 		}
 	}
-
-	mychar := string(randIntFromRange(97,122))
-	// fmt.Printf("A mychar %s\n",mychar)
-        mypos := randIntFromRange(0,len(word1)-1)
-	// fmt.Printf("A mychar %s mypos %d word1[%d] <%s> len(word1)=%d\n",mychar,mypos,mypos,word1, len(word1));
-
-        s := ""
-        for i := 0; i < len(word1); i++ {
-	    if i != mypos {
-	       s = s + string(word1[i])
-	    } else {
-	       s = s + mychar
-    	    }
-        }
-        word1 = s
-
-	// fmt.Printf("B mychar %s mypos %d word1[%d] <%s>\n",mychar,mypos,mypos,word1);
-
-	repl = fmt.Sprintf("\"query\": { \"term\": \"%s\", \"fuzziness\": %d }",word1, fuzziness)
-	// fmt.Println(repl);
-
-	//aaa := "\"query\": { \"term\": \"Breakfasz\", \"fuzziness\": 2 }"
-	//fmt.Println("OK %s",aaa);
-	//fmt.Println("?? %s",repl);
-
-	// repl = "\"query\": { \"term\": \"Breakfasz\", \"fuzziness\": 2 }"
-	return repl
-} 
-
-
-func buildBasicRandomRandomTermsQuery(conf config, c *fasthttpClient) string {
-	var num int
-	var word1 string;
-	var word1a string;
-	var word2 string;
-	var word3 string;
-	var word4 string;
-	var repl string;
-
-	// num = (rand.Intn(conf.commonReviewWordsLen - 1) + 1)
-	num = randIntFromRange(0,conf.commonReviewWordsLen -1);
-	word1 = "+" + conf.commonReviewWords[num];
-	// num = (rand.Intn(conf.commonReviewWordsLen - 1) + 1)
-	num = randIntFromRange(0,conf.commonReviewWordsLen -1);
-	word1a = "+" + conf.commonReviewWords[num];
-	repl = word1 + " " + word1a
-
-	if randIntFromRange(1,10) < 6 {
-		// num = (rand.Intn(conf.commonReviewWordsLen - 1) + 1)
-		num = randIntFromRange(0,conf.commonReviewWordsLen -1);
-		word2 = "+" + conf.commonReviewWords[num];
-		repl = repl + " " + word2
-	}
-
-	if randIntFromRange(1,10) < 6 {
-		// num = (rand.Intn(conf.commonEnglishWordsLen - 1) + 1)
-		num = randIntFromRange(0,conf.commonEnglishWordsLen - 1);
-		word3 = "+" + conf.commonEnglishWords[num];
-		repl = repl + " " + word3
-	}
-
-	if randIntFromRange(1,10) < 6 {
-		// num = (rand.Intn(conf.commonVerbWordsLen - 1) + 1)
-		num = randIntFromRange(0,conf.commonVerbWordsLen - 1);
-		word4 = "+" + conf.commonVerbWords[num];
-		repl = repl + " " + word4
-	}
-	repl = "\"query\": { \"query\": \""+ repl + "\" }"
-
-	return repl
-} 
-
-func buildPseudoGeoRandomQuery(scale float32, conf config, c *fasthttpClient) string {
-	repl := "";
-
-	num := randIntFromRange(0,conf.hotelLocationLatLonsLen -1);
-        center := conf.hotelLocationLatLons[num]
-	lat := center[1]
-	lon := center[0]
-
-	delta_lat := float32(randIntFromRange(0,1000))/float32(10000) - 0.05 * scale;
-	delta_lon := float32(randIntFromRange(0,1000))/float32(10000) - 0.05;
-
-	new_lat := lat + delta_lat
-	new_lon := lon + delta_lon
-
-	// fmt.Println(lat,lon);
-	// fmt.Println(new_lat,new_lon);
-
-	lat_min := new_lat - float32(randIntFromRange(1,15))/30.0 * scale
-	lat_max := new_lat + float32(randIntFromRange(1,15))/30.0 * scale
-
-	lon_min := new_lon - float32(randIntFromRange(1,15))/30.0 * scale
-	lon_max := new_lon + float32(randIntFromRange(1,15))/30.0 * scale
-
-	lat_min_str := fmt.Sprintf("%f", lat_min)
-	lat_max_str := fmt.Sprintf("%f", lat_max)
-	lon_min_str := fmt.Sprintf("%f", lon_min)
-	lon_max_str := fmt.Sprintf("%f", lon_max)
-
-    
-	// aaa := "\"query\": {\"conjuncts\":[ {  \"min\": -94, \"max\": -93,  \"inclusive_min\": false,  \"inclusive_max\": false,  \"field\": \"geo.lon\" },{  \"min\": 45, \"max\": 46,  \"inclusive_min\": false,  \"inclusive_max\": false,  \"field\": \"geo.lat\" }]} "
-
-	// repl = aaa
-
-	repl = "\"query\": {\"conjuncts\":[ {  \"min\": " +
-		lon_min_str +", \"max\": " + 
-		lon_max_str +",  \"inclusive_min\": false,  \"inclusive_max\": false,  \"field\": \"geo.lon\" },{  \"min\": " +
-		lat_min_str +", \"max\": " +
-		lat_max_str +",  \"inclusive_min\": false,  \"inclusive_max\": false,  \"field\": \"geo.lat\" }]} "
-
-	return repl
+	return client(c)
 }
 
-func newFastHTTPClient(opts *clientOpts) client {
-	c := new(fasthttpClient)
-	u, err := url.Parse(opts.url)
-	if err != nil {
-		// opts.url guaranteed to be valid at this point
-		panic(err)
-	}
-	c.host = u.Host
-	c.requestURI = u.RequestURI()
-	c.client = &fasthttp.HostClient{
-		Addr:                          u.Host,
-		IsTLS:                         u.Scheme == "https",
+func newFastHTTPHostClient(addr string, isTLS bool, opts *clientOpts, tlsConfig *tls.Config) *fasthttp.HostClient {
+	return &fasthttp.HostClient{
+		Addr:                          addr,
+		IsTLS:                         isTLS,
 		MaxConns:                      int(opts.maxConns),
 		ReadTimeout:                   opts.timeout,
 		WriteTimeout:                  opts.timeout,
 		DisableHeaderNamesNormalizing: true,
-		TLSConfig:                     opts.tlsConfig,
+		TLSConfig:                     tlsConfig,
 		Dial: fasthttpDialFunc(
 			opts.bytesRead, opts.bytesWritten,
 		),
 	}
-	c.headers = headersToFastHTTPHeaders(opts.headers)
-	c.method, c.body = opts.method, opts.body
-	c.bodProd = opts.bodProd
-	return client(c)
+}
+
+// newCertHostClients returns a client of addr for each --credentials
+// client cert, nil without.
+func newCertHostClients(addr string, isTLS bool, opts *clientOpts) map[*credential]*fasthttp.HostClient {
+	if opts.credentials == nil {
+		return nil
+	}
+	certClients := map[*credential]*fasthttp.HostClient{}
+	for _, cred := range opts.credentials.certs() {
+		tlsConfig := &tls.Config{}
+		if opts.tlsConfig != nil {
+			tlsConfig = opts.tlsConfig.Clone()
+		}
+		tlsConfig.Certificates = []tls.Certificate{*cred.cert}
+		certClients[cred] = newFastHTTPHostClient(addr, isTLS, opts, tlsConfig)
+	}
+	return certClients
 }
 
 func (c *fasthttpClient) do(b *bombardier, preqno uint64, conf config, altbody string, acksToSend int) (
 	code int, usTaken uint64, ackBody []byte, numToAck int, err error,
 ) {
-	retries := 0
-	var uSdelay uint64 = 500 
-
-	if conf.minBackoff > 0 {
-		uSdelay = uint64(conf.minBackoff * 1000);
-	}
-
+	return b.doRequest(&c.requestBuilder, c, preqno, conf, altbody, acksToSend)
+}
 
-	REDO_LOOP:
+// doRequest is the do of both clients: it prepares the next request with
+// rb, sends it with x (again as the retry policy says) and analyzes the
+// response.
+func (b *bombardier) doRequest(rb *requestBuilder, x exchanger, preqno uint64, conf config, altbody string, acksToSend int) (
+	code int, usTaken uint64, ackBody []byte, numToAck int, err error,
+) {
 	if conf.customAck && len(altbody) > 0 {
 		if altbody == "_SKIP_ONE_" {
 			// there was no data so we don't need to ACK
@@ -391,241 +239,49 @@ func (c *fasthttpClient) do(b *bombardier, preqno uint64, conf config, altbody s
 		}
 	}
 
-	var repl string
-
 	// prepare the request
-	req := fasthttp.AcquireRequest()
-	resp := fasthttp.AcquireResponse()
-	if c.headers != nil {
-		c.headers.CopyTo(&req.Header)
+	p, perr := rb.prepareRequest(b, preqno, conf, altbody)
+	if perr != nil {
+		return 0, 0, nil, 0, perr
 	}
-	if len(req.Header.Host()) == 0 {
-		req.Header.SetHost(c.host)
-	}
-	req.Header.SetMethod(c.method)
-	if c.client.IsTLS {
-		req.URI().SetScheme("https")
-	} else {
-		req.URI().SetScheme("http")
-	}
-/* FTS SUBS HERE "[[SEQ:#:##]] 
-        conf.begBucketSeq int
-        conf.endBucketSeq int
-        conf.lenBucketSeq int // if ZERO we didn't have [[SEQ:#:##]] in the URL
-	conf.patBucketSeq     // what we need to substitute for
-*/
-
-        var newuri string = ""
-        var bktstr string = ""
-        var bktseq int = 0
-	if conf.lenBucketSeq > 0 {
-/*
-	    for nn := 0; nn < 100; nn++ {
-		tmp := randIntFromRange(conf.begBucketSeq,conf.endBucketSeq);
-		fmt.Println(tmp)
-	    }
-	    os.Exit(exitFailure)
-*/
-	    num := randIntFromRange(conf.begBucketSeq,conf.endBucketSeq);
-            strnum := strconv.Itoa(num)
-	    if len(strnum) != conf.lenBucketSeq {
-		strnum = "0" + strnum
-            }
-	    newuri = strings.ReplaceAll(c.requestURI, conf.patBucketSeq, strnum)
-	    bktstr = strings.ReplaceAll(conf.kvBucket, conf.patBucketSeq, strnum)
-	    bktseq = num
-// fmt.Printf("AAA %s b %d e %d len %d strnum %s <<%s>> bucket <<%s>>\n",conf.patBucketSeq,conf.begBucketSeq,conf.endBucketSeq,conf.lenBucketSeq, strnum, newuri, bktstr);
-
-	}
-	if len(newuri) > 0 {
-		// fmt.Printf("newuri %s\n", newuri)
-		req.SetRequestURI(newuri)
-	} else {
-		// fmt.Printf("olduri %s\n", c.requestURI)
-		req.SetRequestURI(c.requestURI)
-	}
-
-
-	doSend := false
-	tag := ""
-	if conf.customAck && len(altbody) > 0 {
-		// This is pass two (2) use the ACK body
-		req.SetBodyString(altbody)
-		tag = "ACK "
-	} else if c.body != nil {
-		doSend = true
-		// This is pass one
-		var newbody string = ""
-
-		// JAS need to trakc batches and update our body
-		if conf.nobatchnum == false && len(*c.body) > 0 && preqno > 0 {
-			startpos := strings.Index(*c.body, "\"binfo\": \"")
-			if startpos >= 0 {
-				// pfxstr := (*c.body)[0:startpos+10];
-				// oldstr := (*c.body)[startpos+10:startpos+20];
-				// newstr := fmt.Sprintf("%010d", preqno)
-				//sfxstr := (*c.body)[startpos+20:len(*c.body)];
-				// newbody = pfxstr+newstr+sfxstr;
-
-				oldstr := (*c.body)[startpos+10 : startpos+20]
-				newstr := fmt.Sprintf("%010d", preqno)
-				newbody = strings.ReplaceAll(*c.body, oldstr, newstr)
-
-				// fmt.Fprintf(os.Stderr, "preqno %d: %s\n", preqno,newbody)
-			}
+	var bs *bucketStats
+	var respHits, respBytesRead int
+	if b.buckets != nil {
+		kvBucket := conf.kvBucket
+		if conf.urlSeqs != nil {
+			kvBucket = p.bktstr
 		}
-
-
-/* FTS SUBS HERE "__FTS_QUERY__" */
-		if strings.Index(*c.body, fts_query_pat) != -1 {
-
-
-
-			if conf.dynFtsLimit >0 {
-				switch conf.dynFtsLimit {
-
-				    case  1: //  0.0% misses
-					repl = buildBasicRandomQueryMatch(1, conf.sampleReviewWords, conf.sampleReviewWordsLen)
-
-
-				    case 31: //  0.0% misses
-					repl = buildBasicRandomQueryNumWords(1, conf.sampleReviewWords, conf.sampleReviewWordsLen)
-				    case 32: // 90.4% misses
-					repl = buildBasicRandomQueryNumWords(2, conf.sampleReviewWords, conf.sampleReviewWordsLen)
-
-
-				    case 33: //  0.0% misses
-					repl = buildBasicRandomQueryNumWords(1, conf.commonReviewWords, conf.commonReviewWordsLen)
-				    case 34: //  1.3%  misses
-					repl = buildBasicRandomQueryNumWords(2, conf.commonReviewWords, conf.commonReviewWordsLen)
-				    case 35: // 10.6% misses
-					repl = buildBasicRandomQueryNumWords(3, conf.commonReviewWords, conf.commonReviewWordsLen)
-				    case 36: // 30.4% misses
-					repl = buildBasicRandomQueryNumWords(4, conf.commonReviewWords, conf.commonReviewWordsLen)
-
-
-				    case 37: // 51.0% misses
-					repl = buildBasicRandomQueryTwoNumWords(1, conf.sampleReviewWords, conf.commonReviewWordsLen, 1, conf.commonReviewWords, conf.commonReviewWordsLen)
-				    case 38: // 77.0% misses
-					repl = buildBasicRandomQueryTwoNumWords(1, conf.sampleReviewWords, conf.commonReviewWordsLen, 2, conf.commonReviewWords, conf.commonReviewWordsLen)
-				    case 39: // 88.4% misses
-					repl = buildBasicRandomQueryTwoNumWords(1, conf.sampleReviewWords, conf.commonReviewWordsLen, 3, conf.commonReviewWords, conf.commonReviewWordsLen)
-				    case 40: // 94.7% misses
-					repl = buildBasicRandomQueryTwoNumWords(1, conf.sampleReviewWords, conf.commonReviewWordsLen, 4, conf.commonReviewWords, conf.commonReviewWordsLen)
-
-
-				    case 41: // 74.1% misses
-					repl = buildFuzzyRandomQuery(1 /*"fuzziness*/,5 /*termminlen*/, conf.commonReviewWords, conf.commonReviewWordsLen)
-				    case 42: //  0.0% misses
-					repl = buildFuzzyRandomQuery(2 /*"fuzziness*/,5 /*termminlen*/, conf.commonReviewWords, conf.commonReviewWordsLen)
-
-
-				    case 43: // 14.0% misses
-					repl = buildPseudoGeoRandomQuery(0.25, conf, c) // 2 range conjuncts
-
-				    case 99: //  9.9% misses
-					repl = buildBasicRandomRandomTermsQuery(conf, c)
-				}
-
-			} else {
-				num := randIntFromRange(1,10)
-
-				if num == 10 {
-					// 1 out of 10 queries are 2-"conjuncts" min/max
-					repl = buildPseudoGeoRandomQuery(0.25, conf, c)
-				} else
-				if num == 9 {
-					// 1 out of 10 queries are simple fuzzy
-					 repl = buildFuzzyRandomQuery(1 /*"fuzziness*/,5 /*termminlen*/, conf.commonReviewWords, conf.commonReviewWordsLen)
-				} else {
-					// 8 out of 10 queries are radom query terms
-					repl = buildBasicRandomRandomTermsQuery(conf, c)
-				}
-			}
-
-
-			//newbody = strings.ReplaceAll(*c.body, fts_query_pat, "+very +nice +food +part")
-			newbody = strings.ReplaceAll(*c.body, fts_query_pat, repl)
-		}
-
-		if len(newbody) > 0 {
-			req.SetBodyString(newbody)
-		} else {
-			req.SetBodyString(*c.body)
-		}
-	} else {
-		bs, bserr := c.bodProd()
-		if bserr != nil {
-			return 0, 0, nil, 0, bserr
-		}
-		req.SetBodyStream(bs, -1)
+		bs = b.buckets.get(requestBucket(p.requestURI, kvBucket))
 	}
 
 	// fire the request
 	if conf.trace {
-		// fmt.Printf("REQ\n%v\n",string(req.Body()));
-		str, _ := formatJSON(req.Body())
-		fmt.Printf("%sREQ %d\n%s\n", tag, preqno, str)
+		// fmt.Printf("REQ\n%v\n",p.body);
+		str, _ := formatJSON([]byte(p.body))
+		fmt.Printf("%sREQ %d\n%s\n", p.tag, preqno, str)
 	}
+	waitUntil(p.due)
+	// start is when the first attempt was sent, usTaken is end-to-end
+	// (retries, backoffs and --pages included) and doUs the latency of the
+	// last attempt
 	start := time.Now()
-// fmt.Println("000",req)
-	err = c.client.Do(req, resp)
+	resp, doUs, err, perr := b.exchangeRetrying(rb, x, conf, &p, bs)
+	if perr != nil {
+		return 0, 0, nil, 0, perr
+	}
+	code = resp.code
+	respBody := resp.body
 	if err != nil {
-		code = -1
 		if conf.dynFtsShow {
-			fmt.Println(repl,"nohitserr?")
+			fmt.Println(p.repl,"nohitserr?")
 		}
 	} else {
-		code = resp.StatusCode()
-		if code != 200 {
-		    if code != 429 {
+		if code != 200 && !b.retry.statuses[code] {
 		        fmt.Printf("HTTP status %d\n",code)
-		    } else {
-			// HTTP 429 here what should we do
-			// a) back off just the thread (but we randomly go to N buckets)
-			// b) try to tune a rate request?
-			// *) this is tough to do.
-			// *) lock the bucket??
-
-			retries++
-			// 0.5 2 8 16 32 64 128  loop delays
-
-			if retries < 2 {
-		            /* leave alone */
-			} else if retries < 4 {
-			    uSdelay = uSdelay * 4
-			} else if retries < 8 {
-			    uSdelay = uSdelay * 2
-			} else {
-			    uSdelay = uint64(float64(uSdelay) * 1.2) // slow the growth rate down
-			}
-
-			if uSdelay > 1000000 {
-			    uSdelay =  uint64(1000000)
-			}
-
-			tst := atomic.AddUint64(&b.retryReq429, 1)
-			if (tst == 1) {
-			    if conf.minBackoff == 0 {
-			        fmt.Printf("HTTP status %d, adaptive retry (min=0.5 ms.) then inc. for 17 loops, consider '-r #' rate limit -or- '-c #' to lower client threads:\n",code)
-			    } else {
-			        fmt.Printf("HTTP status %d, adaptive retry (min=%d ms.) then inc. for 17 loops, consider '-r #' rate limit -or- '-c #' to lower client threads:\n",code,conf.minBackoff)
-			    }
-			    // fmt.Printf("%v\n", resp);
-			    // fmt.Printf("sleep %d uSec. refer to final HTTP 429 retries stat (this message only prints once)\n",uSdelay);
-			}
-
-			if (retries <= 18) { 
-		            // sleep uSdelay Microseconds
-                            time.Sleep(time.Duration(uSdelay) * time.Microsecond)
-		            goto REDO_LOOP
-			}
-			// tried a delay up to N seconds things are just stuck, so fail
-		    }
 		}
 
 /* XXX ANALYZE RESPONSE */
-		resp_bytes := len(resp.Body())
+		resp_bytes := len(respBody)
 		hits_bytes := 0
 		total_hits := 0
 		bytesRead := 0
@@ -637,43 +293,54 @@ func (c *fasthttpClient) do(b *bombardier, preqno uint64, conf config, altbody s
 
 		var result CbFtsRespShort
 if (code == 200) {
-		s := gjson.Get(string(resp.Body()), "total_hits")
+		s := gjson.Get(string(respBody), "total_hits")
 		n, err := strconv.Atoi(s.String())
 		if err == nil {
 			total_hits = n
 			if total_hits == 0 {
 				hits_bytes = 0
 			} else {
-				value := gjson.Get(string(resp.Body()), "hits")
+				value := gjson.Get(string(respBody), "hits")
 				hits_bytes = len(value.String())
 			}
 		}
 
-		ts := gjson.Get(string(resp.Body()), "bytesRead")
+		ts := gjson.Get(string(respBody), "bytesRead")
 		tn, terr := strconv.Atoi(ts.String())
 		if terr == nil {
 			bytesRead = tn
 		}
 
 
-		if err := json.Unmarshal(resp.Body(), &result); err != nil { // Parse []byte to the go struct pointer
+		if err := json.Unmarshal(respBody, &result); err != nil { // Parse []byte to the go struct pointer
 			fmt.Println("Can not unmarshal JSON for Couchbase Fts Resp")
-			fmt.Println(repl,"unmarhall_issue does the index exist?")
+			fmt.Println(p.repl,"unmarhall_issue does the index exist?")
 		} 
 
 		resp_status_total = result.Status.Total
 		resp_status_failed = result.Status.Failed
 		resp_status_successful = result.Status.Successful
+
+		if p.probe != nil {
+			b.recordRecall(conf, p.probe, &result)
+		}
+
+		if len(result.Facets) > 0 {
+			atomic.AddUint64(&b.resp_withfacets_cnt, 1)
+			atomic.AddUint64(&b.facets_tot_buckets, uint64(facetBuckets(result.Facets)))
+			atomic.AddUint64(&b.facets_tot_bytes, uint64(len(gjson.GetBytes(respBody, "facets").Raw)))
+		}
 }
 /*
 		if total_hits > 0 {
-			curindex := gjson.Get(string(resp.Body()), "hits.0.index")
+			curindex := gjson.Get(string(respBody), "hits.0.index")
 fmt.Printf("HHHHH hits %v GGGG %v\n",result.Hits[0],curindex)
 		}
 */
 		atomic.AddUint64(&b.resp_cnt, 1)
 		atomic.AddUint64(&b.resp_tot_hits, uint64(total_hits))
 		atomic.AddUint64(&b.resp_tot_bytesRead, uint64(bytesRead))
+		respHits, respBytesRead = total_hits, bytesRead
 		// min10 := math.Min(float64(total_hits),10)
 if (code == 200) {
 		min10 := len(result.Hits);
@@ -691,31 +358,35 @@ if (code == 200) {
 
 			if len(conf.kvDocLookups) > 0 {
 				// read the min10 docs we emulate an end-to-end application
-// fmt.Printf("BBB bktseq %d <<%s>> bucket <<%s>> %v\n", bktseq, newuri, bktstr, req);
-				doKvRead(bktstr,bktseq,b,conf,result)
+// fmt.Printf("BBB p.bktseq %d <<%s>> bucket <<%s>> %v\n", p.bktseq, newuri, p.bktstr, req);
+				doKvRead(p.bktstr,p.bktseq,b,conf,result)
 			}
 		}
 
 		if conf.dynFtsShow {
-			fmt.Println(repl,result.TotalHits)
+			fmt.Println(p.repl,result.TotalHits)
 			// just the Hits
-			fmt.Printf("resp_sz_bytes: %d, totalhits %d, hits_returned %d, ids_returned: %v\n",len(resp.Body()), result.TotalHits, len(result.Hits), result.Hits)
+			fmt.Printf("resp_sz_bytes: %d, totalhits %d, hits_returned %d, ids_returned: %v\n",len(respBody), result.TotalHits, len(result.Hits), result.Hits)
 			fmt.Println("NEW",resp_bytes,hits_bytes,total_hits)
 			fmt.Printf("total_hits %d\n",total_hits)
 		}
 
 		if conf.trace {
-			str, _ := formatJSON(resp.Body())
-			fmt.Printf("%sRESP for req %d, code: %d\n%s\n", tag, preqno, code, str)
+			str, _ := formatJSON(respBody)
+			fmt.Printf("%sRESP for req %d, code: %d\n%s\n", p.tag, preqno, code, str)
+		}
+
+		if code == 200 && p.pageBody != nil {
+			b.crawlPages(conf, rb, x, &p, respBody, doUs, bs)
 		}
 
-		// fmt.Fprintf(os.Stderr, "\nJAS ALL fasthttpClient do() %v\n\n",string(resp.Body()));
+		// fmt.Fprintf(os.Stderr, "\nJAS ALL fasthttpClient do() %v\n\n",string(respBody));
 
 		// =================== JAS ENQUEUE ====================
-		if doSend && conf.isEnqueue {
+		if p.doSend && conf.isEnqueue {
 			if conf.isBulk == false {
 				var result CbQueueOneRespShort
-				if err := json.Unmarshal(resp.Body(), &result); err != nil { // Parse []byte to the go struct pointer
+				if err := json.Unmarshal(respBody, &result); err != nil { // Parse []byte to the go struct pointer
 					fmt.Println("Can not unmarshal JSON for Couchbase Queue One Send")
 				} else {
 					ekey := fmt.Sprintf("\"%v %v %v %v\"", result.Status.Type, result.Status.Code, result.Status.Name, result.Status.Desc)
@@ -734,7 +405,7 @@ if (code == 200) {
 				}
 			} else {
 				var result CbQueueBatchRespShort
-				if err := json.Unmarshal(resp.Body(), &result); err != nil { // Parse []byte to the go struct pointer
+				if err := json.Unmarshal(respBody, &result); err != nil { // Parse []byte to the go struct pointer
 					fmt.Println("Can not unmarshal JSON for Couchbase Queue Batch Send")
 				} else {
 					var alen = len(result.Responses)
@@ -768,7 +439,7 @@ if (code == 200) {
 			if conf.isBulk == false {
 
 				var result CbQueueOneRespShort
-				if err := json.Unmarshal(resp.Body(), &result); err != nil { // Parse []byte to the go struct pointer
+				if err := json.Unmarshal(respBody, &result); err != nil { // Parse []byte to the go struct pointer
 					fmt.Println("Can not unmarshal JSON for Couchbase Queue One Receive")
 				} else {
 					ekey := fmt.Sprintf("\"%v %v %v %v\"", result.Status.Type, result.Status.Code, result.Status.Name, result.Status.Desc)
@@ -815,7 +486,7 @@ if (code == 200) {
 			} else {
 
 				var result CbQueueBatchRespShort
-				if err := json.Unmarshal(resp.Body(), &result); err != nil { // Parse []byte to the go struct pointer
+				if err := json.Unmarshal(respBody, &result); err != nil { // Parse []byte to the go struct pointer
 					fmt.Println("Can not unmarshal JSON for Couchbase Queue Batch Receive")
 				} else {
 					// fmt.Println(PrettyPrint(result));
@@ -882,7 +553,7 @@ if (code == 200) {
 		if conf.customAck && len(altbody) > 0 && conf.isDequeue {
 			if conf.isBulk == false {
 				var result CbQueueOneRespShort
-				if err := json.Unmarshal(resp.Body(), &result); err != nil { // Parse []byte to the go struct pointer
+				if err := json.Unmarshal(respBody, &result); err != nil { // Parse []byte to the go struct pointer
 					fmt.Println("Can not unmarshal JSON for Couchbase Queue One Send")
 				} else {
 					ekey := fmt.Sprintf("\"%v %v %v %v\"", result.Status.Type, result.Status.Code, result.Status.Name, result.Status.Desc)
@@ -902,7 +573,7 @@ if (code == 200) {
 				}
 			} else {
 				var result CbQueueBatchRespShort
-				if err := json.Unmarshal(resp.Body(), &result); err != nil { // Parse []byte to the go struct pointer
+				if err := json.Unmarshal(respBody, &result); err != nil { // Parse []byte to the go struct pointer
 					fmt.Println("Can not unmarshal JSON for Couchbase ACK Batch Send")
 				} else {
 					var alen = len(result.Responses)
@@ -934,29 +605,393 @@ if (code == 200) {
 
 	}
 	usTaken = uint64(time.Since(start).Nanoseconds() / 1000)
+	if bs != nil {
+		bs.record(code, usTaken, respHits, respBytesRead)
+	}
+	if p.node != nil {
+		// a node only served the last attempt
+		p.node.stats.record(code, doUs, respHits, respBytesRead)
+	}
 
 	// release resources
-	fasthttp.ReleaseRequest(req)
-	fasthttp.ReleaseResponse(resp)
+	resp.release()
 
 	return
 }
 
-type httpClient struct {
-	client *http.Client
+// preparedRequest is the next request of a connection after all
+// substitutions with what prepareRequest worked out for it (the generated
+// FTS query, the bucket picked for [[SEQ:#:##]]), the clients turn it into
+// their own request type.
+type preparedRequest struct {
+	method string
+	// addr is the host:port to connect to, host the Host header
+	addr, host string
+	requestURI string
+	// header holds the other headers in order
+	header headersList
+	body   string
+	// bodyStream is sent instead of body with --stream
+	bodyStream io.ReadCloser
+
+	repl     string
+	probe    *recallProbe
+	pageBody func(extra string) string
+	bktstr   string
+	bktseq   int
+	tag      string
+	doSend   bool
+	// due is when a --replay record with timing is to be sent
+	due time.Time
+	// node is the --target node addr is, nil without --target
+	node *targetNode
+	// cert is the tenant whose client cert the request is sent with
+	cert *credential
+}
+
+// getHeader returns the value of the header key, "" if there is none.
+func (p *preparedRequest) getHeader(key string) string {
+	for _, h := range p.header {
+		if strings.EqualFold(h.key, key) {
+			return h.value
+		}
+	}
+	return ""
+}
 
-	headers http.Header
-	url     *url.URL
-	method  string
+// setHeader replaces the header key or adds it.
+func (p *preparedRequest) setHeader(key, value string) {
+	p.delHeader(key)
+	p.header = append(p.header, header{key, value})
+}
+
+func (p *preparedRequest) delHeader(key string) {
+	kept := p.header[:0]
+	for _, h := range p.header {
+		if !strings.EqualFold(h.key, key) {
+			kept = append(kept, h)
+		}
+	}
+	p.header = kept
+}
+
+// url returns the absolute URL of the request.
+func (p *preparedRequest) url(scheme string) string {
+	return scheme + "://" + p.addr + p.requestURI
+}
+
+// requestBuilder builds the requests of a client, the same way for
+// fasthttp and net/http.
+type requestBuilder struct {
+	headers                          *headersList
+	scheme, host, requestURI, method string
 
 	body    *string
 	bodProd bodyStreamProducer
+
+	// with --target the requests go to the node the balancer picks
+	balancer *nodeBalancer
+}
+
+func newRequestBuilder(opts *clientOpts) requestBuilder {
+	u, err := url.Parse(opts.url)
+	if err != nil {
+		// opts.url guaranteed to be valid at this point
+		panic(err)
+	}
+	return requestBuilder{
+		headers:    opts.headers,
+		scheme:     u.Scheme,
+		host:       u.Host,
+		requestURI: u.RequestURI(),
+		method:     opts.method,
+		body:       opts.body,
+		bodProd:    opts.bodProd,
+		balancer:   opts.balancer,
+	}
+}
+
+func (c *requestBuilder) builder() *requestBuilder {
+	return c
+}
+
+// exchangeRetrying sends p with x and again as the retry policy says,
+// each attempt to a --target node between acquire and release. It returns
+// the last response and the latency of its attempt, perr is an error
+// readying p for a retry.
+func (b *bombardier) exchangeRetrying(rb *requestBuilder, x exchanger, conf config, p *preparedRequest, bs *bucketStats) (
+	resp response, doUs uint64, err, perr error,
+) {
+	var backoff time.Duration
+	for attempt := 1; ; attempt++ {
+		attemptStart := time.Now()
+		if p.node != nil {
+			rb.balancer.acquire(p.node)
+		}
+		resp, err = x.exchange(p)
+		doUs = uint64(time.Since(attemptStart).Nanoseconds() / 1000)
+		b.attemptLatencies.Increment(doUs)
+		if p.node != nil {
+			rb.balancer.release(p.node, resp.code)
+		}
+		reason := b.retry.retryReason(resp.code, err)
+		if reason == "" || attempt >= b.retry.maxAttempts {
+			b.retries.done(attempt, reason != "")
+			return resp, doUs, err, nil
+		}
+		backoff = b.retry.backoff(attempt, backoff, resp.retryAfter, globalRand{})
+		b.countRetry(reason, resp.code, backoff, bs, p.node)
+		resp.release()
+		time.Sleep(backoff)
+		if perr = rb.prepareRetry(conf, p); perr != nil {
+			return response{}, 0, nil, perr
+		}
+	}
+}
+
+// prepareRequest returns the headers, the URI and the body of the next
+// request after all substitutions ([[SEQ:#:##]], binfo numbering,
+// __FTS_QUERY__), do sends it and --dry-run writes it out.
+func (c *requestBuilder) prepareRequest(b *bombardier, preqno uint64, conf config, altbody string) (
+	p preparedRequest, err error,
+) {
+	p.method, p.addr = c.method, c.host
+	if c.headers != nil {
+		p.header = make(headersList, 0, len(*c.headers)+1)
+		for _, h := range *c.headers {
+			p.setHeader(h.key, h.value)
+		}
+	}
+	if c.balancer != nil {
+		// not conf.rnd(), the nodes must not shift the --seed query stream
+		p.node = c.balancer.pick(globalRand{})
+		p.addr = p.node.addr
+	}
+	p.host = p.addr
+	if host := p.getHeader("Host"); host != "" {
+		p.host = host
+	}
+	p.delHeader("Host")
+	if conf.replay != nil && !(conf.customAck && len(altbody) > 0) {
+		rec, due := conf.replay.next()
+		setReplayHeaders(rec, p.setHeader)
+		p.method = rec.Method
+		p.requestURI = rec.requestURI
+		p.body = rec.Body
+		p.due, p.doSend = due, true
+		if conf.credentials != nil {
+			setCredentials(conf, &p)
+		}
+		return p, nil
+	}
+	requestURI, body := c.requestURI, c.body
+	if conf.tmpl != nil && conf.tmpl.uri != nil {
+		if requestURI, err = executeTemplate(conf.tmpl.uri); err != nil {
+			return p, err
+		}
+	}
+	if conf.tmpl != nil && conf.tmpl.body != nil && body != nil && !(conf.customAck && len(altbody) > 0) {
+		var s string
+		if s, err = executeTemplate(conf.tmpl.body); err != nil {
+			return p, err
+		}
+		body = &s
+	}
+	if conf.dataRows != nil {
+		row := conf.dataRows.next(conf.rnd())
+		requestURI = substituteDataURI(requestURI, row)
+		for _, h := range *conf.headers {
+			if strings.Contains(h.value, "[[DATA:") {
+				p.setHeader(h.key, substituteData(h.value, row, noEscape))
+			}
+		}
+		if body != nil {
+			s := substituteData(*body, row, jsonStringEscape)
+			body = &s
+		}
+	}
+
+/* FTS SUBS HERE "[[SEQ:#:##]]", the bucket placeholder also names the -C bucket */
+	if conf.urlSeqs != nil {
+		d := conf.urlSeqs.draw(conf.rnd())
+		requestURI = conf.urlSeqs.substitute(requestURI, d)
+		p.bktstr = conf.urlSeqs.substitute(conf.kvBucket, d)
+		p.bktseq = conf.urlSeqs.bucket.num(d)
+	}
+	p.requestURI = requestURI
+	if conf.credentials != nil {
+		setCredentials(conf, &p)
+	}
+
+	if conf.customAck && len(altbody) > 0 {
+		// This is pass two (2) use the ACK body
+		p.body = altbody
+		p.tag = "ACK "
+	} else if body != nil {
+		p.doSend = true
+		// This is pass one
+		var newbody string = ""
+
+		// JAS need to trakc batches and update our body
+		if conf.nobatchnum == false && len(*body) > 0 && preqno > 0 {
+			startpos := strings.Index(*body, "\"binfo\": \"")
+			if startpos >= 0 {
+				// pfxstr := (*body)[0:startpos+10];
+				// oldstr := (*body)[startpos+10:startpos+20];
+				// newstr := fmt.Sprintf("%010d", preqno)
+				//sfxstr := (*body)[startpos+20:len(*body)];
+				// newbody = pfxstr+newstr+sfxstr;
+
+				oldstr := (*body)[startpos+10 : startpos+20]
+				newstr := fmt.Sprintf("%010d", preqno)
+				newbody = strings.ReplaceAll(*body, oldstr, newstr)
+
+				// fmt.Fprintf(os.Stderr, "preqno %d: %s\n", preqno,newbody)
+			}
+		}
+
+
+/* FTS SUBS HERE "__FTS_QUERY__" */
+		if strings.Index(*body, fts_query_pat) != -1 {
+			p.repl, p.probe = b.queryMix.generateProbe(conf)
+			if len(conf.facets) > 0 && !conf.hasFacets {
+				p.repl = p.repl + ", " + conf.facets
+			}
+
+			//newbody = strings.ReplaceAll(*body, fts_query_pat, "+very +nice +food +part")
+			newbody = conf.requestOptions.apply(strings.ReplaceAll(*body, fts_query_pat, p.repl))
+
+			if conf.pages > 1 {
+				pageRepl := p.repl + pageSortExtra(conf, newbody)
+				p.pageBody = func(extra string) string {
+					return conf.requestOptions.apply(strings.ReplaceAll(*body, fts_query_pat, pageRepl+extra))
+				}
+				newbody = p.pageBody(firstPageExtra(conf, newbody))
+			}
+		}
+
+		if len(newbody) > 0 {
+			p.body = newbody
+		} else {
+			p.body = *body
+		}
+	} else {
+		bs, bserr := c.bodProd()
+		if bserr != nil {
+			return p, bserr
+		}
+		p.bodyStream = bs
+	}
+	return p, nil
+}
+
+// prepareRetry readies p to be sent again, with a new --stream body and
+// with --target to the node the balancer picks now.
+func (c *requestBuilder) prepareRetry(conf config, p *preparedRequest) error {
+	if p.bodyStream != nil {
+		bs, err := c.bodProd()
+		if err != nil {
+			return err
+		}
+		p.bodyStream = bs
+	}
+	if p.node != nil {
+		sameHost := p.host == p.addr
+		// the retries depend on the server, they must not shift the --seed
+		// query stream
+		p.node = c.balancer.pick(globalRand{})
+		p.addr = p.node.addr
+		if sameHost {
+			p.host = p.addr
+		}
+	}
+	return nil
+}
+
+// setCredentials applies the --credentials of the tenant of the request,
+// the requests of a tenant with a client cert go out without -u.
+func setCredentials(conf config, p *preparedRequest) {
+	kvBucket, num := conf.kvBucket, 0
+	var seq *seqPlaceholder
+	// the URIs of --replay have no [[SEQ:#:##]] to draw
+	if conf.urlSeqs != nil && conf.replay == nil {
+		kvBucket, seq, num = p.bktstr, conf.urlSeqs.bucket, p.bktseq
+	}
+	cred := conf.credentials.lookup(requestBucket(p.requestURI, kvBucket), seq, num)
+	switch {
+	case cred == nil:
+	case cred.cert != nil:
+		p.delHeader("Authorization")
+		p.cert = cred
+	default:
+		p.setHeader("Authorization", cred.authHeader)
+	}
+}
+
+// exchange sends p with the fasthttp client of its node and client cert.
+func (c *fasthttpClient) exchange(p *preparedRequest) (r response, err error) {
+	hc := c.client
+	switch {
+	case p.node != nil && p.cert != nil:
+		hc = c.nodeCertClients[p.node][p.cert]
+	case p.node != nil:
+		hc = c.nodeClients[p.node]
+	case p.cert != nil:
+		hc = c.certClients[p.cert]
+	}
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+	r.code, r.release = -1, func() { fasthttp.ReleaseResponse(resp) }
+	req.Header.SetMethod(p.method)
+	for _, h := range p.header {
+		req.Header.Set(h.key, h.value)
+	}
+	req.Header.SetHost(p.host)
+	req.SetRequestURI(p.requestURI)
+	req.URI().SetScheme(c.scheme)
+	if p.bodyStream != nil {
+		req.SetBodyStream(p.bodyStream, -1)
+	} else {
+		req.SetBodyString(p.body)
+	}
+	err = hc.Do(req, resp)
+	fasthttp.ReleaseRequest(req)
+	if err != nil {
+		return r, err
+	}
+	r.code, r.body = resp.StatusCode(), resp.Body()
+	r.retryAfter = string(resp.Header.Peek("Retry-After"))
+	return r, nil
+}
+
+type httpClient struct {
+	requestBuilder
+
+	client *http.Client
+	// certClients are the clients of the --credentials client certs
+	certClients map[*credential]*http.Client
 }
 
 func newHTTPClient(opts *clientOpts) client {
-	c := new(httpClient)
+	c := &httpClient{requestBuilder: newRequestBuilder(opts)}
+	c.client = newHTTPTransportClient(opts, opts.tlsConfig)
+	if opts.credentials != nil {
+		c.certClients = map[*credential]*http.Client{}
+		for _, cred := range opts.credentials.certs() {
+			tlsConfig := &tls.Config{}
+			if opts.tlsConfig != nil {
+				tlsConfig = opts.tlsConfig.Clone()
+			}
+			tlsConfig.Certificates = []tls.Certificate{*cred.cert}
+			c.certClients[cred] = newHTTPTransportClient(opts, tlsConfig)
+		}
+	}
+	return client(c)
+}
+
+func newHTTPTransportClient(opts *clientOpts, tlsConfig *tls.Config) *http.Client {
 	tr := &http.Transport{
-		TLSClientConfig:     opts.tlsConfig,
+		TLSClientConfig:     tlsConfig,
 		MaxIdleConnsPerHost: int(opts.maxConns),
 		DisableKeepAlives:   opts.disableKeepAlives,
 	}
@@ -969,113 +1004,75 @@ func newHTTPClient(opts *clientOpts) client {
 		)
 	}
 
-	cl := &http.Client{
+	return &http.Client{
 		Transport: tr,
 		Timeout:   opts.timeout,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
 	}
-	c.client = cl
-
-	c.headers = headersToHTTPHeaders(opts.headers)
-	c.method, c.body, c.bodProd = opts.method, opts.body, opts.bodProd
-	var err error
-	c.url, err = url.Parse(opts.url)
-	if err != nil {
-		// opts.url guaranteed to be valid at this point
-		panic(err)
-	}
-
-	return client(c)
 }
 
 func (c *httpClient) do(b *bombardier, preqno uint64, conf config, altbody string, acksToSend int) (
 	code int, usTaken uint64, ackBody []byte, numToAck int, err error,
 ) {
-	req := &http.Request{}
-
-	req.Header = c.headers
-	req.Method = c.method
-	req.URL = c.url
+	return b.doRequest(&c.requestBuilder, c, preqno, conf, altbody, acksToSend)
+}
 
-	if host := req.Header.Get("Host"); host != "" {
-		req.Host = host
+// exchange sends p with the net/http client of its client cert, the body
+// of the response is read in full.
+func (c *httpClient) exchange(p *preparedRequest) (r response, err error) {
+	r.code, r.release = -1, func() {}
+	u, err := url.Parse(p.url(c.scheme))
+	if err != nil {
+		return r, err
 	}
-
-	if c.body != nil {
-		br := strings.NewReader(*c.body)
-		req.ContentLength = int64(len(*c.body))
-		req.Body = ioutil.NopCloser(br)
-	} else {
-		bs, bserr := c.bodProd()
-		if bserr != nil {
-			return 0, 0, nil, 0, bserr
-		}
-		req.Body = bs
+	req := &http.Request{
+		Method: p.method,
+		URL:    u,
+		Header: make(http.Header, len(p.header)),
+		Host:   p.host,
 	}
-
-	start := time.Now()
-	resp, err := c.client.Do(req)
-	if err != nil {
-		code = -1
+	for _, h := range p.header {
+		req.Header[h.key] = []string{h.value}
+	}
+	if p.bodyStream != nil {
+		req.Body = p.bodyStream
+	} else if len(p.body) > 0 {
+		req.ContentLength = int64(len(p.body))
+		req.Body = ioutil.NopCloser(strings.NewReader(p.body))
 	} else {
-		code = resp.StatusCode
-
-		_, berr := io.Copy(ioutil.Discard, resp.Body)
-
-		// ==== JAS NOT IMPLEMENTED ====
-		// b, berr := io.Copy(ioutil.Discard, resp.Body)
-		// fmt.Fprintf(os.Stderr, "\nJAS httpClient do() %v\n\n",b);
-		// =============================
-
-		if berr != nil {
-			err = berr
-		}
-
-		if cerr := resp.Body.Close(); cerr != nil {
-			err = cerr
-		}
+		req.Body = http.NoBody
 	}
-	usTaken = uint64(time.Since(start).Nanoseconds() / 1000)
 
-	return
-}
-
-func headersToFastHTTPHeaders(h *headersList) *fasthttp.RequestHeader {
-	if len(*h) == 0 {
-		return nil
+	hc := c.client
+	if p.cert != nil {
+		hc = c.certClients[p.cert]
 	}
-	res := new(fasthttp.RequestHeader)
-	for _, header := range *h {
-		res.Set(header.key, header.value)
+	resp, err := hc.Do(req)
+	if err != nil {
+		return r, err
 	}
-	return res
-}
-
-func headersToHTTPHeaders(h *headersList) http.Header {
-	if len(*h) == 0 {
-		return http.Header{}
+	body, err := ioutil.ReadAll(resp.Body)
+	if cerr := resp.Body.Close(); err == nil {
+		err = cerr
 	}
-	headers := http.Header{}
-
-	for _, header := range *h {
-		headers[header.key] = []string{header.value}
+	if err != nil {
+		return r, err
 	}
-	return headers
+	r.code, r.body = resp.StatusCode, body
+	r.retryAfter = resp.Header.Get("Retry-After")
+	return r, nil
 }
 
 
 // var collection *gocb.Collection
-var collections [80]*gocb.Collection
+// collections has one collection per number of the bucket [[SEQ]] placeholder
+var collections []*gocb.Collection
 
-func init() {
-        cfg, err := parser.parse(os.Args)
-        if err != nil {
-                fmt.Println(err)
-                os.Exit(exitFailure)
-        }
-//fmt.Println(cfg.kvDocLookups)
+// initKvCollections opens the bucket(s) used by -K and -C to perform
+// document lookups for FTS hits.
+func initKvCollections(cfg config) {
 	if len(cfg.kvDocLookups) == 0 {
 		return;
 	}
@@ -1106,35 +1103,34 @@ func init() {
   // cluster, err := gocb.Connect("couchbase://10.0.1.28", gocb.ClusterOptions{
   // cluster, err := gocb.Connect("couchbase://127.0.0.1", gocb.ClusterOptions{
 
-    connstr := "couchbase://"+ cfg.kvDocLookups
-    fmt.Println("INIT KV connect to: " + connstr);
-
-    cluster, err := gocb.Connect("couchbase://"+ cfg.kvDocLookups, gocb.ClusterOptions{
-        Authenticator: gocb.PasswordAuthenticator{
-          Username: "admin",
-          Password: "jtester",
-        },
-    })
-    if err != nil {
-        log.Fatal(err)
+    // a cluster connection per --credentials tenant, nil is the default one
+    clusters := map[*credential]*gocb.Cluster{}
+    kvCluster := func(cred *credential) *gocb.Cluster {
+	if cluster, ok := clusters[cred]; ok {
+	    return cluster
+	}
+	cluster, err := connectKv(cfg, cred)
+	if err != nil {
+	    log.Fatal(err)
+	}
+	clusters[cred] = cluster
+	return cluster
     }
-
+    var err error
 
     var bucket *gocb.Bucket
 
     // we could have more than one bucket
     var curbkt string = ""
-    if cfg.lenBucketSeq > 0 {
-	for num := cfg.begBucketSeq; num <= cfg.endBucketSeq; num++ {
-	    strnum := strconv.Itoa(num)
-	    if len(strnum) != cfg.lenBucketSeq {
-		strnum = "0" + strnum
-	    }
-	    curbkt = strings.ReplaceAll(cfg.kvBucket, cfg.patBucketSeq, strnum)
-	    // fmt.Printf("%s b %d e %d len %d strnum %s <<%s>>\n",cfg.patBucketSeq,cfg.begBucketSeq,cfg.endBucketSeq,cfg.lenBucketSeq, strnum, curbkt);
+    if cfg.urlSeqs != nil {
+	bseq := cfg.urlSeqs.bucket
+	collections = make([]*gocb.Collection, bseq.end-bseq.beg+1)
+	for num := bseq.beg; num <= bseq.end; num++ {
+	    curbkt = strings.ReplaceAll(cfg.kvBucket, bseq.text, bseq.format(num))
+	    // fmt.Printf("%s b %d e %d strnum %s <<%s>>\n",bseq.text,bseq.beg,bseq.end, bseq.format(num), curbkt);
 
    
-	    bucket = cluster.Bucket(curbkt)
+	    bucket = kvCluster(cfg.credentials.lookup(curbkt, bseq, num)).Bucket(curbkt)
 	    // bucket := cluster.Bucket("ts01")
 	    err = bucket.WaitUntilReady(5*time.Second, nil)
 	    if err != nil {
@@ -1142,14 +1138,14 @@ func init() {
 	    }
 	    // fmt.Println("INIT open scope.collection of _default._default");
 	    collection := bucket.DefaultCollection()
-	    collections[num-1] = collection
+	    collections[num-bseq.beg] = collection
 	}
 	fmt.Println("INIT open bucket(s): " + cfg.kvBucket + ", on scope.collection of _default._default");
     } else {
 	curbkt = cfg.kvBucket
 	fmt.Println("INIT open bucket: " + curbkt + ", on scope.collection of _default._default");
    
-        bucket = cluster.Bucket(curbkt)
+        bucket = kvCluster(cfg.credentials.lookup(curbkt, nil, 0)).Bucket(curbkt)
 	// bucket := cluster.Bucket("ts01")
 	err = bucket.WaitUntilReady(5*time.Second, nil)
 	if err != nil {
@@ -1157,7 +1153,7 @@ func init() {
 	}
 	// fmt.Println("INIT open scope.collection of _default._default");
         collection := bucket.DefaultCollection()
-        collections[0] = collection
+        collections = []*gocb.Collection{collection}
     }
 
    
@@ -1165,6 +1161,31 @@ fmt.Println("INIT done");
 
 }
 
+// connectKv connects to the -K cluster with the credentials of a tenant,
+// with a client cert over TLS.
+func connectKv(cfg config, cred *credential) (*gocb.Cluster, error) {
+    connstr := "couchbase://"+ cfg.kvDocLookups
+    opts := gocb.ClusterOptions{
+        Authenticator: gocb.PasswordAuthenticator{
+          Username: "admin",
+          Password: "jtester",
+        },
+    }
+    if cred != nil {
+	if cred.cert != nil {
+	    connstr = "couchbases://"+ cfg.kvDocLookups
+	    opts.Authenticator = gocb.CertificateAuthenticator{ClientCertificate: cred.cert}
+	    opts.SecurityConfig = gocb.SecurityConfig{TLSSkipVerify: cfg.insecure}
+	} else {
+	    opts.Authenticator = gocb.PasswordAuthenticator{Username: cred.user, Password: cred.password}
+	}
+	fmt.Println("INIT KV connect to: " + connstr + " as tenant " + cred.name);
+    } else {
+	fmt.Println("INIT KV connect to: " + connstr);
+    }
+    return gocb.Connect(connstr, opts)
+}
+
 /*
 //GetInstanceA - get singleton instance pre-initialized
 func GetCollection() *gocb.Collection {
@@ -1177,9 +1198,9 @@ func doKvRead(bktstr string, bktseq int, b *bombardier, conf config, result CbFt
 
     var err error
     var collection = collections[0]
-    if conf.lenBucketSeq > 0 {
+    if conf.urlSeqs != nil {
 	 // fmt.Printf("CCC source collection from idx %d bucket %s._default._default\n", bktseq, bktstr)
-	 collection = collections[bktseq-1]
+	 collection = collections[bktseq-conf.urlSeqs.bucket.beg]
     }
 
     // break up into batches of 128
@@ -1216,7 +1237,7 @@ func doKvRead(bktstr string, bktseq int, b *bombardier, conf config, result CbFt
 
                 if getOp.Err != nil {
                         // fmt.Println("Expected GetOp Err to be nil");
-                        fmt.Println("Expected GetOp Err to be nil but was %v", getOp.Err)
+                        fmt.Printf("Expected GetOp Err to be nil but was %v\n", getOp.Err)
                 } else {
 
                         if getOp.Result.Cas() == 0 {
@@ -1227,7 +1248,7 @@ func doKvRead(bktstr string, bktseq int, b *bombardier, conf config, result CbFt
                         var content interface{}
                         err = getOp.Result.Content(&content)
                         if err != nil {
-                                fmt.Println("F\nailed to get content from GetOp %v", err)
+                                fmt.Printf("Failed to get content from GetOp %v\n", err)
                                 os.Exit(0);
                         }
                         // prettyJSON, err := json.MarshalIndent(content, "", "    ")
@@ -29,7 +29,7 @@ var (
 	defaultReqBatchSz    = uint64(1)
 	defaultEsLimit       = int(5)
 	defaultIsBulk        = false
-        defaultMinBackoff    = int(0)
+	defaultMinBackoff    = int(0)
 
 	httpMethods = []string{
 		"GET", "POST", "PUT", "DELETE", "HEAD", "OPTIONS",
@@ -60,6 +60,35 @@ var (
 	errInvalidHeaderFormat = errors.New("Invalid header format")
 	errEmptyPrintSpec      = errors.New(
 		"Empty print spec is not a valid print spec")
+	errEmptyQueryMix             = errors.New("FTS query mix has no generators")
+	errQueryMixAndLimit          = errors.New("Use either --dynFtsLimit or --queryMix")
+	errInvalidNamedPathFormat    = errors.New("Invalid format, expected name=path")
+	errNoHarvestFields           = errors.New("Give at least one --field, --numeric or --geo to harvest")
+	errInvalidCommonMinDf        = errors.New("--commonMinDf must be > 0 and <= 1")
+	errNoVectorsFile             = errors.New("no query vectors, load them with --vectors")
+	errGroundTruthWithoutVectors = errors.New("--groundTruth needs the query vectors given via --vectors")
+	errNoFacets                  = errors.New("no facets defined")
+	errInvalidPages              = errors.New("--pages must be >= 0")
+	errInvalidPageSort           = errors.New("--pageSort must be a JSON array, e.g. [\"-_score\", \"_id\"]")
+	errInvalidScore              = errors.New("--score must be none")
+	errInvalidHighlightStyle     = errors.New("--highlight must be html or ansi")
+	errInvalidSort               = errors.New("--sort must be a JSON array, e.g. [\"-_score\"]")
+	errNegativeCtlTimeout        = errors.New("--ctlTimeout can't be negative")
+	errOptionsNeedJSONBody       = errors.New("request options need a -b or -f body that is a JSON object with __FTS_QUERY__")
+	errDryRunNeedsNumReqs        = errors.New("--dry-run needs the number of requests to write, give it via -n")
+	errReplayNoTimestamp         = errors.New("--replayTiming original needs a timestamp in every record")
+	errReplayEmpty               = errors.New("no requests to replay")
+	errNoDataRows                = errors.New("no data rows")
+	errInvalidSeqPlaceholder     = errors.New("expected [[SEQ:beg:end]] with 0 <= beg <= end, optionally followed by :option")
+	errInvalidCredentials        = errors.New("expected user:pass, {user, password} or {cert, key}")
+	errCertCredentialsNeedTLS    = errors.New("--credentials client certs need an https URL")
+	errTemplateNeedsBody         = errors.New("--template needs a -b or -f body (not --stream)")
+	errInvalidTarget             = errors.New("expected a --target host[:port]")
+	errNegativeEjectFor          = errors.New("--ejectFor can't be negative")
+	errNoSearchNodes             = errors.New("--discover found no node with the search (fts) service")
+	errInvalidRetryOn            = errors.New("--retryOn takes HTTP statuses (100-599) and the error classes timeout, connection and error")
+	errInvalidBackoff            = errors.New("--minBackoff and --maxBackoff can't be negative and --backoffMultiplier must be 0 or at least 1")
+	errInvalidMaxAttempts        = errors.New("--maxAttempts must be at least 1")
 )
 
 func init() {
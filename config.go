@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"math/rand"
 	"net/url"
 	"sort"
 	"time"
@@ -29,6 +30,7 @@ type config struct {
 	dynKvShow                      bool
 	ftsTestHelp                    bool
 	dynFtsLimit                    uint64
+	queryMixPath                   string
 	dynDocSz                       uint64
 	dynDocBatchSz                  uint64
 	reqBatchSz                     uint64
@@ -55,10 +57,7 @@ type config struct {
 	format format
 
 	// BEG cb_fts_bench only
-        begBucketSeq int
-        endBucketSeq int
-        lenBucketSeq int // if ZERO we didn't have [[SEQ:#:##]] in the URL 
-        patBucketSeq string
+        urlSeqs *urlSeqs // nil if we didn't have [[SEQ:#:##]] in the URL
 
 	commonReviewWords []string
 	commonReviewWordsLen int
@@ -71,6 +70,59 @@ type config struct {
         hotelLocationLatLons [][]float32
         hotelLocationLatLonsLen int
 
+	// extra word lists added via --wordList
+	wordLists map[string][]string
+	// knn query vectors loaded via --vectors
+	vectors [][]float32
+	// neighbor ids of each query vector loaded via --groundTruth
+	groundTruth [][]string
+	// "facets" member built from --facets, added to each FTS query
+	facets string
+	// hasSort tells whether the -b body or --requestOptions sort the
+	// hits, the generated FTS queries then don't add their own sort
+	hasSort bool
+	// hasFacets is hasSort for "facets", --facets is then not added
+	hasFacets bool
+	// with pages > 1 each FTS query walks that many pages
+	pages    int
+	pageMode string
+	pageSort string
+	// request members merged into each FTS query, nil if none
+	requestOptions *requestOptions
+	// seed of the query streams and, set per worker, its generator
+	seed int64
+	rng  *rand.Rand
+	// with dryRun set the requests are written to that file, not sent
+	dryRun string
+	// requests replayed from --replay instead of built from the body
+	replay *replayWorkload
+	// with template set the body and URL are text/templates, evaluated
+	// by the tmpl of the worker
+	template   bool
+	tmpl       *workerTemplates
+	dataTables map[string]*dataTable
+	// rows of --data for the [[DATA:column]] placeholders, dataRows picks
+	// them for the worker
+	data     *dataSource
+	dataRows *dataCursor
+	// keep the statistics per bucket too
+	bucketStats bool
+	// credentials of the tenants, nil without --credentials
+	credentials *credentialsMap
+	// host[:port] of the nodes to spread the requests over, with the
+	// balance mode and the ejection of failing nodes
+	targets    []string
+	balance    string
+	ejectAfter int
+	ejectFor   time.Duration
+	// cluster manager the targets were discovered on, the _metering of
+	// those nodes is read instead of the URL host's
+	discover     string
+	meteringURLs []string
+	// retry policy of --retryOn and the backoff flags, nil is the
+	// default policy (429 with --minBackoff)
+	retry *retryPolicy
+
 
 	// END cb_fts_bench only
 }
@@ -164,6 +216,9 @@ func (c *config) checkTimeoutDuration() error {
 	if c.timeout < 0 {
 		return errNegativeTimeout
 	}
+	if c.ejectFor < 0 {
+		return errNegativeEjectFor
+	}
 	return nil
 }
 
@@ -189,6 +244,22 @@ func (c *config) checkCertPaths() error {
 	return nil
 }
 
+// wordList returns the FTS word list with the given name, nil if
+// there is no such list.
+func (c *config) wordList(name string) []string {
+	switch name {
+	case "sampleReviewWords":
+		return c.sampleReviewWords
+	case "commonReviewWords":
+		return c.commonReviewWords
+	case "commonEnglishWords":
+		return c.commonEnglishWords
+	case "commonVerbWords":
+		return c.commonVerbWords
+	}
+	return c.wordLists[name]
+}
+
 func (c *config) timeoutMillis() uint64 {
 	return uint64(c.timeout.Nanoseconds() / 1000)
 }
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	b64 "encoding/base64"
+
+	"gopkg.in/yaml.v3"
+)
+
+// A --credentials file maps a bucket name or a [[SEQ:#:##]] value to the
+// credentials of its tenant, "*" matches any other bucket:
+//
+//	ts01: user1:pass1
+//	"02": {user: user2, password: pass2}
+//	ts03: {cert: ts03.pem, key: ts03.key}
+//	"*": admin:password
+//
+// the requests to a bucket without an entry use -u.
+
+type credential struct {
+	name string
+
+	user, password    string
+	certPath, keyPath string
+
+	// authHeader is the Authorization of user and password
+	authHeader string
+	cert       *tls.Certificate
+}
+
+type credentialsMap struct {
+	path  string
+	byKey map[string]*credential
+}
+
+// credentialEntry is a value of the file, user:pass or a mapping.
+type credentialEntry struct {
+	User     string `json:"user" yaml:"user"`
+	Password string `json:"password" yaml:"password"`
+	Cert     string `json:"cert" yaml:"cert"`
+	Key      string `json:"key" yaml:"key"`
+}
+
+func (e *credentialEntry) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		return e.setUserPass(s)
+	}
+	type plain credentialEntry
+	return json.Unmarshal(data, (*plain)(e))
+}
+
+func (e *credentialEntry) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		return e.setUserPass(value.Value)
+	}
+	type plain credentialEntry
+	return value.Decode((*plain)(e))
+}
+
+func (e *credentialEntry) setUserPass(s string) error {
+	i := strings.Index(s, ":")
+	if i < 0 {
+		return errInvalidCredentials
+	}
+	e.User, e.Password = s[:i], s[i+1:]
+	return nil
+}
+
+func loadCredentialsFile(path string) (*credentialsMap, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var entries map[string]credentialEntry
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &entries)
+	} else {
+		err = yaml.Unmarshal(data, &entries)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("credentials %s: %v", path, err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("credentials %s: no entries", path)
+	}
+
+	m := &credentialsMap{path: path, byKey: map[string]*credential{}}
+	for key, e := range entries {
+		cred := &credential{name: key, user: e.User, password: e.Password, certPath: e.Cert, keyPath: e.Key}
+		switch {
+		case e.Cert != "" || e.Key != "":
+			if e.User != "" {
+				return nil, fmt.Errorf("credentials %s: %s: %v", path, key, errInvalidCredentials)
+			}
+			certs, err := readClientCert(e.Cert, e.Key)
+			if err != nil || certs == nil {
+				return nil, fmt.Errorf("credentials %s: %s: a cert and a key are needed: %v", path, key, err)
+			}
+			cred.cert = &certs[0]
+		case e.User != "":
+			cred.authHeader = "Basic " + b64.StdEncoding.EncodeToString([]byte(e.User+":"+e.Password))
+		default:
+			return nil, fmt.Errorf("credentials %s: %s: %v", path, key, errInvalidCredentials)
+		}
+		m.byKey[key] = cred
+	}
+	return m, nil
+}
+
+// lookup returns the credentials of bucket or else of the number seq of
+// the [[SEQ:#:##]] placeholder (formatted as in the URL or plain), or else
+// of "*", nil if there are none.
+func (m *credentialsMap) lookup(bucket string, seq *seqPlaceholder, num int) *credential {
+	if m == nil {
+		return nil
+	}
+	keys := []string{bucket}
+	if seq != nil {
+		keys = append(keys, seq.format(num), strconv.Itoa(num))
+	}
+	for _, key := range append(keys, "*") {
+		if cred, ok := m.byKey[key]; ok {
+			return cred
+		}
+	}
+	return nil
+}
+
+// certs returns the credentials with a client certificate by name.
+func (m *credentialsMap) certs() []*credential {
+	var certs []*credential
+	for _, cred := range m.byKey {
+		if cred.cert != nil {
+			certs = append(certs, cred)
+		}
+	}
+	sort.Slice(certs, func(i, j int) bool { return certs[i].name < certs[j].name })
+	return certs
+}
+
+func (m *credentialsMap) describe() string {
+	users, certs := 0, 0
+	for _, cred := range m.byKey {
+		if cred.cert != nil {
+			certs++
+		} else {
+			users++
+		}
+	}
+	return fmt.Sprintf("Credentials of %d tenant(s) from %s, %d user(s) and %d client cert(s)", len(m.byKey), m.path, users, certs)
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	b64 "encoding/base64"
+)
+
+func basicAuthHeader(userPass string) string {
+	return "Basic " + b64.StdEncoding.EncodeToString([]byte(userPass))
+}
+
+func TestLoadCredentialsFile(t *testing.T) {
+	yamlPath := writeTempFile(t, "tenants.yaml", `
+ts01: user1:pa:ss1
+"02": {user: user2, password: pass2}
+ts03: {cert: testclient.cert, key: testclient.key}
+"*": admin:password
+`)
+	m, err := loadCredentialsFile(yamlPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.byKey) != 4 {
+		t.Fatalf("expected 4 tenants, got %v", m.byKey)
+	}
+	if c := m.byKey["ts01"]; c.user != "user1" || c.password != "pa:ss1" || c.authHeader != basicAuthHeader("user1:pa:ss1") {
+		t.Errorf("unexpected ts01 %+v", c)
+	}
+	if c := m.byKey["02"]; c.authHeader != basicAuthHeader("user2:pass2") {
+		t.Errorf("unexpected 02 %+v", c)
+	}
+	if certs := m.certs(); len(certs) != 1 || certs[0].name != "ts03" || certs[0].authHeader != "" {
+		t.Errorf("unexpected certs %+v", certs)
+	}
+
+	jsonPath := writeTempFile(t, "tenants.json", `{"ts01": "user1:pass1", "ts02": {"user": "user2", "password": "pass2"}}`)
+	m, err = loadCredentialsFile(jsonPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.byKey) != 2 || m.byKey["ts02"].authHeader != basicAuthHeader("user2:pass2") {
+		t.Errorf("unexpected tenants %v", m.byKey)
+	}
+
+	for _, content := range []string{
+		``,
+		`ts01: nocolon`,
+		`ts01: {password: pass}`,
+		`ts01: {cert: testclient.cert}`,
+		`ts01: {user: u, cert: testclient.cert, key: testclient.key}`,
+		`ts01: {cert: doesnotexist.pem, key: doesnotexist.pem}`,
+		`[ts01]`,
+	} {
+		if _, err := loadCredentialsFile(writeTempFile(t, "bad.yaml", content)); err == nil {
+			t.Errorf("expected an error for %q", content)
+		}
+	}
+	if _, err := loadCredentialsFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestCredentialsLookup(t *testing.T) {
+	m := &credentialsMap{byKey: map[string]*credential{
+		"ts01": {name: "ts01"},
+		"02":   {name: "02"},
+		"3":    {name: "3"},
+	}}
+	seq := &seqPlaceholder{beg: 1, end: 4, pad: 2}
+	expectations := []struct {
+		bucket string
+		num    int
+		name   string
+	}{
+		{"ts01", 2, "ts01"},
+		{"ts02", 2, "02"},
+		{"ts03", 3, "3"},
+		{"ts04", 4, ""},
+	}
+	for _, e := range expectations {
+		name := ""
+		if c := m.lookup(e.bucket, seq, e.num); c != nil {
+			name = c.name
+		}
+		if name != e.name {
+			t.Errorf("%s %d: expected %q, got %q", e.bucket, e.num, e.name, name)
+		}
+	}
+	if c := m.lookup("ts02", nil, 0); c != nil {
+		t.Errorf("expected no credentials without a SEQ, got %+v", c)
+	}
+	m.byKey["*"] = &credential{name: "*"}
+	if c := m.lookup("ts04", seq, 4); c == nil || c.name != "*" {
+		t.Errorf("expected the * credentials, got %+v", c)
+	}
+	if c := (*credentialsMap)(nil).lookup("ts01", seq, 1); c != nil {
+		t.Errorf("expected no credentials without a map, got %+v", c)
+	}
+}
+
+func TestBombardierSendsCredentials(t *testing.T) {
+	testAllClients(t, testBombardierSendsCredentials)
+}
+
+func testBombardierSendsCredentials(clientType clientTyp, t *testing.T) {
+	var (
+		mu   sync.Mutex
+		auth = map[string]map[string]bool{}
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			bucket := strings.Split(r.URL.Path, "/")[3]
+			mu.Lock()
+			if auth[bucket] == nil {
+				auth[bucket] = map[string]bool{}
+			}
+			auth[bucket][r.Header.Get("Authorization")] = true
+			mu.Unlock()
+		}),
+	)
+	defer s.Close()
+
+	creds, err := loadCredentialsFile(writeTempFile(t, "tenants.yaml", "ts01: user1:pass1\n\"02\": user2:pass2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	numReqs := uint64(30)
+	conf := config{
+		numConns:    3,
+		numReqs:     &numReqs,
+		url:         s.URL + "/api/bucket/ts[[SEQ:1:3:rr]]/scope/s/index/i/query",
+		headers:     new(headersList),
+		timeout:     defaultTimeout,
+		method:      "GET",
+		basicAuth:   "admin:password",
+		clientType:  clientType,
+		format:      knownFormat("plain-text"),
+		credentials: creds,
+	}
+	conf.urlSeqs, _ = parseURLSeqs(conf.url, "")
+	b, err := newBombardier(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.disableOutput()
+	b.bombard()
+
+	expected := map[string]string{
+		"ts01": basicAuthHeader("user1:pass1"),
+		"ts02": basicAuthHeader("user2:pass2"),
+		"ts03": basicAuthHeader("admin:password"),
+	}
+	if len(auth) != len(expected) {
+		t.Fatalf("expected requests to %d buckets, got %v", len(expected), auth)
+	}
+	for bucket, header := range expected {
+		if len(auth[bucket]) != 1 || !auth[bucket][header] {
+			t.Errorf("%s: expected only %q, got %v", bucket, header, auth[bucket])
+		}
+	}
+}
+
+func TestBombardierReplaysCredentials(t *testing.T) {
+	testAllClients(t, testBombardierReplaysCredentials)
+}
+
+func testBombardierReplaysCredentials(clientType clientTyp, t *testing.T) {
+	var (
+		mu   sync.Mutex
+		auth = map[string]map[string]bool{}
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			if auth[r.URL.Path] == nil {
+				auth[r.URL.Path] = map[string]bool{}
+			}
+			auth[r.URL.Path][r.Header.Get("Authorization")] = true
+			mu.Unlock()
+		}),
+	)
+	defer s.Close()
+
+	creds, err := loadCredentialsFile(writeTempFile(t, "tenants.yaml", "a: user1:pass1\n\"*\": user2:pass2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := loadReplayFile(writeTempFile(t, "replay.jsonl", testReplayJSONL), replayTimingASAP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	numReqs := uint64(6)
+	b, err := newBombardier(config{
+		numConns:    2,
+		numReqs:     &numReqs,
+		url:         s.URL,
+		headers:     new(headersList),
+		timeout:     defaultTimeout,
+		method:      "GET",
+		basicAuth:   "admin:password",
+		clientType:  clientType,
+		format:      knownFormat("plain-text"),
+		replay:      w,
+		credentials: creds,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.disableOutput()
+	b.bombard()
+
+	expected := map[string]string{
+		"/api/index/a/query": basicAuthHeader("user1:pass1"),
+		"/api/index/b/query": basicAuthHeader("user2:pass2"),
+		"/api/index/c":       basicAuthHeader("user2:pass2"),
+	}
+	for path, header := range expected {
+		if len(auth[path]) != 1 || !auth[path][header] {
+			t.Errorf("%s: expected only %q, got %v", path, header, auth[path])
+		}
+	}
+}
+
+func TestCertCredentialsNeedTLS(t *testing.T) {
+	creds, err := loadCredentialsFile(writeTempFile(t, "tenants.yaml", "ts01: user1:pass1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	certs, err := loadCredentialsFile(writeTempFile(t, "tenants.yaml", "ts03: {cert: testclient.cert, key: testclient.key}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	numReqs := uint64(1)
+	conf := config{
+		numConns:   1,
+		numReqs:    &numReqs,
+		url:        "http://localhost:8094/api/bucket/ts03/scope/s/index/i/query",
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		clientType: fhttp,
+		format:     knownFormat("plain-text"),
+	}
+	expectations := []struct {
+		creds *credentialsMap
+		url   string
+		err   error
+	}{
+		{creds, conf.url, nil},
+		{certs, conf.url, errCertCredentialsNeedTLS},
+		{certs, "https://localhost:18094/api/bucket/ts03/scope/s/index/i/query", nil},
+	}
+	for _, e := range expectations {
+		conf.credentials, conf.url = e.creds, e.url
+		if _, err := newBombardier(conf); err != e.err {
+			t.Errorf("%s: expected %v, got %v", e.url, e.err, err)
+		}
+	}
+}
+
+func TestDryRunCredentials(t *testing.T) {
+	numReqs := uint64(8)
+	conf := dryRunConf(&numReqs)
+	conf.credentials = &credentialsMap{byKey: map[string]*credential{
+		"ts02": {name: "ts02", authHeader: basicAuthHeader("user2:pass2")},
+		"*":    {name: "*", authHeader: basicAuthHeader("other:pass")},
+	}}
+	b, err := newBombardier(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	if err := b.dryRun(out, numReqs); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var dr dryRunRequest
+		if err := json.Unmarshal([]byte(line), &dr); err != nil {
+			t.Fatalf("%v in %s", err, line)
+		}
+		expected := basicAuthHeader("other:pass")
+		if strings.Contains(dr.URL, "/bucket/ts02/") {
+			expected = basicAuthHeader("user2:pass2")
+		}
+		if dr.Headers["Authorization"] != expected {
+			t.Errorf("expected %q in %s", expected, line)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// dataTable is a table loaded via --csv or --data, the first line of a
+// CSV file names the columns, in JSONL each line is an object of them.
+type dataTable struct {
+	columns []string
+	rows    []map[string]string
+}
+
+// readDataTable reads path as JSONL if it ends in .jsonl, .ndjson or
+// .json and as CSV otherwise.
+func readDataTable(path string) (*dataTable, error) {
+	switch ext := strings.ToLower(path); {
+	case strings.HasSuffix(ext, ".jsonl"), strings.HasSuffix(ext, ".ndjson"), strings.HasSuffix(ext, ".json"):
+		return readJSONLTable(path)
+	}
+	return readCSVTable(path)
+}
+
+func readCSVTable(path string) (*dataTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.ReuseRecord = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("no header line: %v", err)
+	}
+	t := &dataTable{columns: append([]string(nil), header...)}
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(t.columns))
+		for i, col := range t.columns {
+			row[col] = rec[i]
+		}
+		t.rows = append(t.rows, row)
+	}
+	if len(t.rows) == 0 {
+		return nil, errNoDataRows
+	}
+	return t, nil
+}
+
+// readJSONLTable reads one object per line, strings are used as they are
+// and other values as JSON, the columns are all keys in order of first
+// appearance.
+func readJSONLTable(path string) (*dataTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t := new(dataTable)
+	known := map[string]bool{}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), maxReplayLineSize)
+	for line := 1; sc.Scan(); line++ {
+		if len(strings.TrimSpace(sc.Text())) == 0 {
+			continue
+		}
+		dec := json.NewDecoder(strings.NewReader(sc.Text()))
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return nil, fmt.Errorf("line %d: not a JSON object", line)
+		}
+		row := map[string]string{}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			col := key.(string) // keys of an object are strings
+			var s string
+			if err := json.Unmarshal(value, &s); err != nil {
+				s = string(value)
+				if s == "null" {
+					s = ""
+				}
+			}
+			row[col] = s
+			if !known[col] {
+				known[col] = true
+				t.columns = append(t.columns, col)
+			}
+		}
+		t.rows = append(t.rows, row)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(t.rows) == 0 {
+		return nil, errNoDataRows
+	}
+	return t, nil
+}
+
+// row returns row i, wrapping around at the end of the table.
+func (t *dataTable) row(i int) map[string]string {
+	if i < 0 {
+		i = -i
+	}
+	return t.rows[i%len(t.rows)]
+}
+
+// The rows of --data fill the [[DATA:column]] placeholders of the URL, the
+// -H values and the body, all placeholders of a request use the same row.
+const (
+	dataModeSequential  = "sequential"
+	dataModeRandom      = "random"
+	dataModePartitioned = "partitioned"
+)
+
+var (
+	dataModes = []string{dataModeSequential, dataModeRandom, dataModePartitioned}
+
+	dataPatRegexp = regexp.MustCompile(`\[\[DATA:([^\]]+)\]\]`)
+)
+
+// dataSource is the --data table and how its rows are picked: in file
+// order by all connections together, at random, or with partitioned
+// connection i of c takes rows i, i+c, i+2c, ... in order.
+type dataSource struct {
+	path  string
+	table *dataTable
+	mode  string
+
+	n uint64
+}
+
+func loadDataSource(path, mode string) (*dataSource, error) {
+	table, err := readDataTable(path)
+	if err != nil {
+		return nil, fmt.Errorf("data %s: %v", path, err)
+	}
+	return &dataSource{path: path, table: table, mode: mode}, nil
+}
+
+// check verifies that the placeholders of texts name columns of the table
+// and, when partitioned, that every connection gets rows.
+func (s *dataSource) check(numConns uint64, texts ...string) error {
+	known := map[string]bool{}
+	for _, col := range s.table.columns {
+		known[col] = true
+	}
+	for _, text := range texts {
+		for _, m := range dataPatRegexp.FindAllStringSubmatch(text, -1) {
+			if !known[m[1]] {
+				return fmt.Errorf("data %s: no column %q, have %v", s.path, m[1], s.table.columns)
+			}
+		}
+	}
+	if s.mode == dataModePartitioned && uint64(len(s.table.rows)) < numConns {
+		return fmt.Errorf("data %s: %d row(s) can't be partitioned over %d connections",
+			s.path, len(s.table.rows), numConns)
+	}
+	return nil
+}
+
+func (s *dataSource) describe() string {
+	return fmt.Sprintf("Data %d row(s) of %v from %s, %s", len(s.table.rows), s.table.columns, s.path, s.mode)
+}
+
+// dataCursor picks the rows of one connection.
+type dataCursor struct {
+	src     *dataSource
+	worker  uint64
+	workers uint64
+	n       uint64
+}
+
+func (s *dataSource) forWorker(worker, workers uint64) *dataCursor {
+	return &dataCursor{src: s, worker: worker, workers: workers}
+}
+
+// next returns the row of the next request, r is the random stream of
+// the connection.
+func (c *dataCursor) next(r randGen) map[string]string {
+	t := c.src.table
+	switch c.src.mode {
+	case dataModeRandom:
+		return t.rows[r.Intn(len(t.rows))]
+	case dataModePartitioned:
+		// rows worker, worker+workers, ... below len(t.rows)
+		own := (uint64(len(t.rows)) - c.worker + c.workers - 1) / c.workers
+		i := c.worker + (c.n%own)*c.workers
+		c.n++
+		return t.rows[i]
+	}
+	return t.row(int((atomic.AddUint64(&c.src.n, 1) - 1) % uint64(len(t.rows))))
+}
+
+// substituteData replaces the [[DATA:column]] placeholders of text by the
+// escaped values of row.
+func substituteData(text string, row map[string]string, escape func(string) string) string {
+	if !strings.Contains(text, "[[DATA:") {
+		return text
+	}
+	return dataPatRegexp.ReplaceAllStringFunc(text, func(m string) string {
+		return escape(row[m[len("[[DATA:"):len(m)-2]])
+	})
+}
+
+// substituteDataURI escapes the values for the path and the query.
+func substituteDataURI(requestURI string, row map[string]string) string {
+	path, query := requestURI, ""
+	if i := strings.IndexByte(requestURI, '?'); i >= 0 {
+		path, query = requestURI[:i], requestURI[i:]
+	}
+	return substituteData(path, row, url.PathEscape) + substituteData(query, row, url.QueryEscape)
+}
+
+// jsonStringEscape escapes s to go inside a JSON string, the body places
+// the quotes: "query": "[[DATA:phrase]]".
+func jsonStringEscape(s string) string {
+	data, _ := marshalNoEscape(s)
+	return string(data[1 : len(data)-1])
+}
+
+func noEscape(s string) string { return s }
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestReadCSVTable(t *testing.T) {
+	table, err := readCSVTable(writeTempFile(t, "users.csv", "id,name\n7,alice\n8,\"bob, jr\"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.columns) != 2 || len(table.rows) != 2 {
+		t.Fatalf("unexpected table %+v", table)
+	}
+	if row := table.row(3); row["id"] != "8" || row["name"] != "bob, jr" {
+		t.Errorf("expected row 3 to wrap around to bob, got %v", row)
+	}
+
+	for _, content := range []string{"", "id,name\n", "id,name\n1,2,3\n"} {
+		if _, err := readCSVTable(writeTempFile(t, "bad.csv", content)); err == nil {
+			t.Errorf("expected an error for %q", content)
+		}
+	}
+}
+
+func TestReadJSONLTable(t *testing.T) {
+	table, err := readDataTable(writeTempFile(t, "rows.jsonl",
+		`{"phrase": "cozy room", "n": 3}`+"\n\n"+`{"phrase": "say \"hi\"", "tags": ["a"], "n": null}`+"\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(table.columns, ",") != "phrase,n,tags" {
+		t.Errorf("unexpected columns %v", table.columns)
+	}
+	if r := table.rows[0]; r["phrase"] != "cozy room" || r["n"] != "3" {
+		t.Errorf("unexpected row %v", r)
+	}
+	if r := table.rows[1]; r["phrase"] != `say "hi"` || r["n"] != "" || r["tags"] != `["a"]` {
+		t.Errorf("unexpected row %v", r)
+	}
+	for _, content := range []string{"", "[1, 2]\n", `{"a": }` + "\n"} {
+		if _, err := readDataTable(writeTempFile(t, "bad.jsonl", content)); err == nil {
+			t.Errorf("expected an error for %q", content)
+		}
+	}
+}
+
+func testDataSource(t *testing.T, mode string) *dataSource {
+	src, err := loadDataSource(writeTempFile(t, "rows.csv", "id\n0\n1\n2\n3\n4\n"), mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return src
+}
+
+func TestDataCursor(t *testing.T) {
+	next := func(c *dataCursor, n int) string {
+		r := workerRand(1, c.worker)
+		var ids []string
+		for i := 0; i < n; i++ {
+			ids = append(ids, c.next(r)["id"])
+		}
+		return strings.Join(ids, "")
+	}
+
+	seq := testDataSource(t, dataModeSequential)
+	a, b := seq.forWorker(0, 2), seq.forWorker(1, 2)
+	if got := next(a, 3) + next(b, 4); got != "0123401" {
+		t.Errorf("expected the rows in order across connections, got %s", got)
+	}
+
+	part := testDataSource(t, dataModePartitioned)
+	if got := next(part.forWorker(0, 2), 4); got != "0240" {
+		t.Errorf("expected rows 0, 2, 4 for connection 0, got %s", got)
+	}
+	if got := next(part.forWorker(1, 2), 3); got != "131" {
+		t.Errorf("expected rows 1, 3 for connection 1, got %s", got)
+	}
+
+	seen := map[rune]bool{}
+	for _, id := range next(testDataSource(t, dataModeRandom).forWorker(0, 1), 100) {
+		seen[id] = true
+	}
+	if len(seen) != 5 {
+		t.Errorf("expected all rows at random, got %v", seen)
+	}
+
+	if err := part.check(6, "[[DATA:id]]"); err == nil {
+		t.Error("expected an error for more connections than rows")
+	}
+	if err := seq.check(6, "/[[DATA:id]]", "[[DATA:name]]"); err == nil {
+		t.Error("expected an error for an unknown column")
+	}
+	if err := seq.check(6, "/[[DATA:id]]"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSubstituteData(t *testing.T) {
+	row := map[string]string{"t": "t 1", "q": `a&b "c"/d`}
+	if got := substituteDataURI("/api/[[DATA:t]]/query?q=[[DATA:q]]&x=1", row); got != "/api/t%201/query?q=a%26b+%22c%22%2Fd&x=1" {
+		t.Errorf("unexpected uri %s", got)
+	}
+	if got := substituteData(`{"match": "[[DATA:q]]"}`, row, jsonStringEscape); got != `{"match": "a&b \"c\"/d"}` {
+		t.Errorf("unexpected body %s", got)
+	}
+	if got := substituteData("[[DATA:t]]-[[DATA:missing]]", row, noEscape); got != "t 1-" {
+		t.Errorf("unexpected value %s", got)
+	}
+}
+
+func TestBombardierSendsDataRows(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen = map[string]int{}
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			mu.Lock()
+			seen[r.URL.Path+" "+r.Header.Get("X-Row")+" "+string(body)]++
+			mu.Unlock()
+			rw.Write([]byte(`{"status": {"total": 1, "successful": 1}, "total_hits": 0, "hits": []}`))
+		}),
+	)
+	defer s.Close()
+
+	headers := new(headersList)
+	headers.Set("X-Row: [[DATA:id]]")
+	numReqs := uint64(10)
+	b, err := newBombardier(config{
+		numConns:   2,
+		numReqs:    &numReqs,
+		url:        s.URL + "/index/[[DATA:id]]",
+		headers:    headers,
+		timeout:    defaultTimeout,
+		method:     "POST",
+		body:       `{"id": "[[DATA:id]]"}`,
+		clientType: fhttp,
+		format:     knownFormat("plain-text"),
+		data:       testDataSource(t, dataModePartitioned),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.disableOutput()
+	b.bombard()
+
+	total := 0
+	for i := 0; i < 5; i++ {
+		id := strconv.Itoa(i)
+		total += seen["/index/"+id+" "+id+` {"id": "`+id+`"}`]
+	}
+	if total != int(numReqs) {
+		t.Errorf("expected %d requests with consistent rows, got %v", numReqs, seen)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// With --discover the search nodes are looked up on the cluster manager:
+// /pools/default lists the nodes and their services and
+// /pools/default/nodeServices the ports of the services (fts and ftsSSL),
+// a node missing there gets the default ports.
+
+const (
+	defaultManagerPort = "8091"
+	defaultFtsPort     = 8094
+	defaultFtsSSLPort  = 18094
+)
+
+type searchNode struct {
+	host          string
+	port, sslPort int
+}
+
+// addr returns the host:port of the node for the scheme of the URL.
+func (n searchNode) addr(https bool) string {
+	if https {
+		return net.JoinHostPort(n.host, strconv.Itoa(n.sslPort))
+	}
+	return net.JoinHostPort(n.host, strconv.Itoa(n.port))
+}
+
+type poolsDefault struct {
+	Nodes []struct {
+		Hostname string   `json:"hostname"`
+		Services []string `json:"services"`
+	} `json:"nodes"`
+}
+
+type nodeServices struct {
+	NodesExt []struct {
+		Hostname string         `json:"hostname"`
+		Services map[string]int `json:"services"`
+	} `json:"nodesExt"`
+}
+
+// managerURL returns the base URL of the --discover cluster manager, a
+// host without a scheme or port is http on 8091.
+func managerURL(manager string) (*url.URL, error) {
+	if !strings.Contains(manager, "://") {
+		manager = "http://" + manager
+	}
+	u, err := url.Parse(manager)
+	if err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("--discover %s: expected a cluster manager host[:port] or URL", manager)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), defaultManagerPort)
+	}
+	u.Path, u.RawQuery = "", ""
+	return u, nil
+}
+
+// discoverSearchNodes returns the nodes running the search service of the
+// cluster managed by manager.
+func discoverSearchNodes(manager, basicAuth string, insecure bool, timeout time.Duration) ([]searchNode, error) {
+	u, err := managerURL(manager)
+	if err != nil {
+		return nil, err
+	}
+	/* #nosec */
+	client := &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: insecure}},
+	}
+	get := func(path string, v interface{}) error {
+		req, err := http.NewRequest("GET", u.String()+path, nil)
+		if err != nil {
+			return err
+		}
+		if up := strings.SplitN(basicAuth, ":", 2); len(up) == 2 {
+			req.SetBasicAuth(up[0], up[1])
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("--discover %s%s: HTTP status %d", u, path, resp.StatusCode)
+		}
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			return fmt.Errorf("--discover %s%s: %v", u, path, err)
+		}
+		return nil
+	}
+
+	var pools poolsDefault
+	if err := get("/pools/default", &pools); err != nil {
+		return nil, err
+	}
+	var services nodeServices
+	if err := get("/pools/default/nodeServices", &services); err != nil {
+		return nil, err
+	}
+	return searchNodesOf(pools, services, u.Hostname())
+}
+
+// searchNodesOf returns the fts nodes of pools with their ports from
+// services, managerHost stands in for a node without a hostname (a single
+// node cluster).
+func searchNodesOf(pools poolsDefault, services nodeServices, managerHost string) ([]searchNode, error) {
+	ports := map[string]map[string]int{}
+	for _, n := range services.NodesExt {
+		host := n.Hostname
+		if host == "" {
+			host = managerHost
+		}
+		ports[host] = n.Services
+	}
+	var nodes []searchNode
+	for _, n := range pools.Nodes {
+		if !containsString(n.Services, "fts") {
+			continue
+		}
+		host := n.Hostname
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if host == "" {
+			host = managerHost
+		}
+		node := searchNode{host: host, port: defaultFtsPort, sslPort: defaultFtsSSLPort}
+		if p, ok := ports[host]["fts"]; ok {
+			node.port = p
+		}
+		if p, ok := ports[host]["ftsSSL"]; ok {
+			node.sslPort = p
+		}
+		nodes = append(nodes, node)
+	}
+	if len(nodes) == 0 {
+		return nil, errNoSearchNodes
+	}
+	return nodes, nil
+}
+
+// meteringURLs returns the base URLs of the _metering endpoints of nodes.
+func meteringURLs(nodes []searchNode, https bool) []string {
+	scheme := "http"
+	if https {
+		scheme = "https"
+	}
+	urls := make([]string, len(nodes))
+	for i, n := range nodes {
+		urls[i] = scheme + "://" + n.addr(https)
+	}
+	return urls
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestManagerURL(t *testing.T) {
+	expectations := []struct {
+		in, out string
+	}{
+		{"cbhost", "http://cbhost:8091"},
+		{"cbhost:9000", "http://cbhost:9000"},
+		{"https://cbhost:18091/pools", "https://cbhost:18091"},
+	}
+	for _, e := range expectations {
+		u, err := managerURL(e.in)
+		if err != nil || u.String() != e.out {
+			t.Errorf("%s: expected %s, got %v %v", e.in, e.out, u, err)
+		}
+	}
+	if _, err := managerURL("ftp://cbhost"); err == nil {
+		t.Error("expected an error for ftp")
+	}
+}
+
+// mockManager serves /pools/default and /pools/default/nodeServices of a
+// cluster with a kv node and three search nodes, two on ftsPort, one the
+// manager itself (without a hostname in nodeServices).
+func mockManager(ftsPort string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "admin" || pass != "password" {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/pools/default":
+			fmt.Fprint(rw, `{"name": "default", "nodes": [
+				{"hostname": "10.0.0.9:8091", "services": ["kv", "index"]},
+				{"hostname": "127.0.0.1:8091", "services": ["fts", "kv"]},
+				{"hostname": "localhost:8091", "services": ["fts"]},
+				{"hostname": "10.0.0.7:8091", "services": ["fts"]}
+			]}`)
+		case "/pools/default/nodeServices":
+			fmt.Fprintf(rw, `{"rev": 1, "nodesExt": [
+				{"services": {"mgmt": 8091, "fts": %s, "ftsSSL": 19094}, "thisNode": true},
+				{"hostname": "localhost", "services": {"mgmt": 8091, "fts": %s}},
+				{"hostname": "10.0.0.9", "services": {"mgmt": 8091, "kv": 11210}}
+			]}`, ftsPort, ftsPort)
+		default:
+			rw.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestDiscoverSearchNodes(t *testing.T) {
+	s := mockManager("9094")
+	defer s.Close()
+
+	nodes, err := discoverSearchNodes(s.URL, "admin:password", false, defaultTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var addrs, sslAddrs []string
+	for _, n := range nodes {
+		addrs = append(addrs, n.addr(false))
+		sslAddrs = append(sslAddrs, n.addr(true))
+	}
+	if got := strings.Join(addrs, " "); got != "127.0.0.1:9094 localhost:9094 10.0.0.7:8094" {
+		t.Errorf("unexpected nodes %s", got)
+	}
+	if got := strings.Join(sslAddrs, " "); got != "127.0.0.1:19094 localhost:18094 10.0.0.7:18094" {
+		t.Errorf("unexpected TLS nodes %s", got)
+	}
+	if got := meteringURLs(nodes[:1], true); len(got) != 1 || got[0] != "https://127.0.0.1:19094" {
+		t.Errorf("unexpected metering URLs %v", got)
+	}
+
+	if _, err := discoverSearchNodes(s.URL, "admin:wrong", false, defaultTimeout); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected an HTTP 401 error, got %v", err)
+	}
+	var pools poolsDefault
+	pools.Nodes = append(pools.Nodes, struct {
+		Hostname string   `json:"hostname"`
+		Services []string `json:"services"`
+	}{"10.0.0.9:8091", []string{"kv"}})
+	if _, err := searchNodesOf(pools, nodeServices{}, "cbhost"); err != errNoSearchNodes {
+		t.Errorf("expected %v, got %v", errNoSearchNodes, err)
+	}
+}
+
+func TestBombardierDiscovers(t *testing.T) {
+	s := mockManager("9094")
+	defer s.Close()
+
+	conf := config{
+		numConns:   1,
+		url:        "http://cbhost:8094/api/index/ix/query",
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "GET",
+		basicAuth:  "admin:password",
+		clientType: fhttp,
+		format:     knownFormat("plain-text"),
+		targets:    []string{"10.0.0.8"},
+		balance:    balanceRoundRobin,
+		discover:   s.URL,
+	}
+	b, err := newBombardier(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(b.conf.targets, " "); got != "10.0.0.8 127.0.0.1:9094 localhost:9094 10.0.0.7:8094" || len(b.nodes.nodes) != 4 {
+		t.Errorf("expected the discovered nodes added to --target, got %s", got)
+	}
+	if len(b.conf.meteringURLs) != 3 {
+		t.Errorf("expected the metering URLs of the discovered nodes, got %v", b.conf.meteringURLs)
+	}
+
+	conf.basicAuth = "admin:wrong"
+	if _, err := newBombardier(conf); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected an HTTP 401 error, got %v", err)
+	}
+}
+
+func TestBombardierDiscoveredNodes(t *testing.T) {
+	fts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/_metering" {
+			fmt.Fprintf(rw, "# TYPE meter_ru_total counter\nmeter_ru_total{bucket=%q,for=\"fts\"} 100\n", r.Host)
+			return
+		}
+		rw.Write([]byte(`{"status": {"total": 1, "successful": 1}, "total_hits": 0, "hits": []}`))
+	}))
+	defer fts.Close()
+	port := fts.URL[strings.LastIndex(fts.URL, ":")+1:]
+	s := mockManager(port)
+	defer s.Close()
+
+	nodes, err := discoverSearchNodes(s.URL, "admin:password", false, defaultTimeout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the unreachable 10.0.0.7 is left out
+	nodes = nodes[:2]
+
+	numReqs := uint64(20)
+	conf := config{
+		numConns:     2,
+		numReqs:      &numReqs,
+		url:          "http://cbhost:8094/api/index/ix/query",
+		headers:      new(headersList),
+		timeout:      defaultTimeout,
+		method:       "GET",
+		clientType:   fhttp,
+		format:       knownFormat("plain-text"),
+		balance:      balanceRoundRobin,
+		meteringURLs: meteringURLs(nodes, false),
+	}
+	for _, n := range nodes {
+		conf.targets = append(conf.targets, n.addr(false))
+	}
+	b, err := newBombardier(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.disableOutput()
+	b.bombard()
+	for _, n := range b.nodes.results() {
+		if n.Req2XX != 10 {
+			t.Errorf("expected 10 requests to %s, got %+v", n.Name, n)
+		}
+	}
+
+	total, byBucket := dumpMetering("admin", "password", meteringBaseURLs(conf, "cbhost"), false, "(test)")
+	if total.Int64() != 200 || len(byBucket) != 2 {
+		t.Errorf("expected the RU of both nodes, got %v %v", total, byBucket)
+	}
+}
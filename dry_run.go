@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// dryRunRequest is a line of the --dry-run output, a request as it would
+// have been sent.
+type dryRunRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body"`
+}
+
+// runDryRun writes the -n requests of cfg to cfg.dryRun ("-" is stdout)
+// instead of sending them.
+func runDryRun(cfg config) int {
+	b, err := newBombardier(cfg)
+	if err != nil {
+		fmt.Println(err)
+		return exitFailure
+	}
+	w := io.Writer(os.Stdout)
+	if cfg.dryRun != "-" {
+		f, err := os.Create(cfg.dryRun)
+		if err != nil {
+			fmt.Println(err)
+			return exitFailure
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := b.dryRun(w, *cfg.numReqs); err != nil {
+		fmt.Println(err)
+		return exitFailure
+	}
+	if cfg.dryRun != "-" {
+		fmt.Printf("wrote %d request(s) to %s\n", *cfg.numReqs, cfg.dryRun)
+	}
+	return 0
+}
+
+// dryRun writes n requests as JSONL, they are generated exactly as by
+// do, request i by the stream of connection i % numConns. Only the first
+// page of --pages is written as the later ones depend on the responses.
+func (b *bombardier) dryRun(w io.Writer, n uint64) error {
+	rb := b.client.builder()
+	confs := make([]config, b.conf.numConns)
+	for i := range confs {
+		confs[i] = b.conf
+		confs[i].rng = workerRand(b.conf.seed, uint64(i))
+		if b.reqTemplates != nil {
+			confs[i].tmpl = b.reqTemplates.forWorker(confs[i], b.queryMix)
+		}
+		if b.conf.data != nil {
+			confs[i].dataRows = b.conf.data.forWorker(uint64(i), b.conf.numConns)
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	for i := uint64(0); i < n; i++ {
+		conf := confs[i%uint64(len(confs))]
+		var preqno uint64
+		if conf.dynDoc && conf.dynDocSz >= 40 {
+			preqno = i + 1
+		}
+
+		p, err := rb.prepareRequest(b, preqno, conf, "")
+		if err != nil {
+			return err
+		}
+		dr := dryRunRequest{
+			Method: p.method,
+			URL:    rb.scheme + "://" + p.host + p.requestURI,
+			Body:   p.body,
+		}
+		if p.bodyStream != nil {
+			p.bodyStream.Close()
+		}
+		for _, h := range p.header {
+			if dr.Headers == nil {
+				dr.Headers = map[string]string{}
+			}
+			dr.Headers[h.key] = h.value
+		}
+		if err := enc.Encode(dr); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func dryRunConf(numReqs *uint64) config {
+	headers := new(headersList)
+	headers.Set("Content-Type: application/json")
+	conf := config{
+		numConns:   2,
+		numReqs:    numReqs,
+		url:        "http://localhost:8094/api/bucket/ts[[SEQ:01:04]]/scope/s/index/ix/query",
+		headers:    headers,
+		timeout:    defaultTimeout,
+		method:     "POST",
+		body:       "{" + fts_query_pat + ", \"size\": 3}",
+		clientType: fhttp,
+		format:     knownFormat("plain-text"),
+		seed:       42,
+	}
+	conf.urlSeqs, _ = parseURLSeqs(conf.url, "")
+	setBuiltinFtsData(&conf)
+	return conf
+}
+
+func TestDryRun(t *testing.T) {
+	numReqs := uint64(20)
+	run := func() string {
+		b, err := newBombardier(dryRunConf(&numReqs))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := new(bytes.Buffer)
+		if err := b.dryRun(out, numReqs); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+	out := run()
+	if run() != out {
+		t.Error("expected the same requests for the same seed")
+	}
+
+	lines := 0
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		lines++
+		var dr dryRunRequest
+		if err := json.Unmarshal(sc.Bytes(), &dr); err != nil {
+			t.Fatalf("%v in %s", err, sc.Text())
+		}
+		if dr.Method != "POST" || dr.Headers["Content-Type"] != "application/json" {
+			t.Errorf("unexpected method or headers in %s", sc.Text())
+		}
+		if !strings.HasPrefix(dr.URL, "http://localhost:8094/api/bucket/ts0") || strings.Contains(dr.URL, "[[SEQ") {
+			t.Errorf("expected a ts01 to ts04 bucket in %s", dr.URL)
+		}
+		if strings.Contains(dr.Body, fts_query_pat) || !json.Valid([]byte(dr.Body)) {
+			t.Errorf("expected a generated query in %s", dr.Body)
+		}
+	}
+	if lines != int(numReqs) {
+		t.Errorf("expected %d requests, got %d", numReqs, lines)
+	}
+}
+
+func TestDryRunNetHTTP(t *testing.T) {
+	numReqs := uint64(10)
+	run := func(clientType clientTyp) string {
+		conf := dryRunConf(&numReqs)
+		conf.clientType = clientType
+		b, err := newBombardier(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := new(bytes.Buffer)
+		if err := b.dryRun(out, numReqs); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+	if out := run(fhttp); run(nhttp1) != out || run(nhttp2) != out {
+		t.Error("expected the same requests for all clients")
+	}
+}
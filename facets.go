@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// facetsFile is the layout of a --facets file, e.g. in YAML
+//
+//	facets:
+//	  types:
+//	    type: term
+//	    field: type
+//	    size: 10
+//	  ratings:
+//	    type: numeric
+//	    field: reviews.ratings.Overall
+//	    size: 3
+//	    ranges: [{name: low, max: 2}, {name: mid, min: 2, max: 4}, {name: high, min: 4}]
+//	  reviewed:
+//	    type: date
+//	    field: reviews.date
+//	    size: 2
+//	    ranges: [{name: old, end: "2014-01-01"}, {name: new, start: "2014-01-01"}]
+//
+// the same structure is accepted as JSON.
+type facetsFile struct {
+	Facets map[string]facetSpec `json:"facets" yaml:"facets"`
+}
+
+type facetSpec struct {
+	Type   string       `json:"type" yaml:"type"`
+	Field  string       `json:"field" yaml:"field"`
+	Size   int          `json:"size" yaml:"size"`
+	Ranges []facetRange `json:"ranges" yaml:"ranges"`
+}
+
+type facetRange struct {
+	Name  string   `json:"name" yaml:"name"`
+	Min   *float64 `json:"min" yaml:"min"`
+	Max   *float64 `json:"max" yaml:"max"`
+	Start string   `json:"start" yaml:"start"`
+	End   string   `json:"end" yaml:"end"`
+}
+
+// facetRequest is a facet as sent to FTS.
+type facetRequest struct {
+	Field         string              `json:"field"`
+	Size          int                 `json:"size"`
+	NumericRanges []numericRangeFacet `json:"numeric_ranges,omitempty"`
+	DateRanges    []dateRangeFacet    `json:"date_ranges,omitempty"`
+}
+
+type numericRangeFacet struct {
+	Name string   `json:"name"`
+	Min  *float64 `json:"min,omitempty"`
+	Max  *float64 `json:"max,omitempty"`
+}
+
+type dateRangeFacet struct {
+	Name  string `json:"name"`
+	Start string `json:"start,omitempty"`
+	End   string `json:"end,omitempty"`
+}
+
+// loadFacetsFile returns the "facets" member added to every generated
+// query, files ending in .json are read as JSON, anything else as YAML.
+func loadFacetsFile(path string) (string, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	ff := new(facetsFile)
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, ff)
+	} else {
+		err = yaml.Unmarshal(data, ff)
+	}
+	if err != nil {
+		return "", fmt.Errorf("facets %s: %v", path, err)
+	}
+	frag, err := buildFacetsFragment(ff.Facets)
+	if err != nil {
+		return "", fmt.Errorf("facets %s: %v", path, err)
+	}
+	return frag, nil
+}
+
+func buildFacetsFragment(specs map[string]facetSpec) (string, error) {
+	if len(specs) == 0 {
+		return "", errNoFacets
+	}
+	names := make([]string, 0, len(specs))
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	facets := make(map[string]facetRequest, len(specs))
+	for _, name := range names {
+		spec := specs[name]
+		if spec.Field == "" || spec.Size < 1 {
+			return "", fmt.Errorf("facet %s: needs a field and a size > 0", name)
+		}
+		fr := facetRequest{Field: spec.Field, Size: spec.Size}
+		switch spec.Type {
+		case "", "term":
+			if len(spec.Ranges) > 0 {
+				return "", fmt.Errorf("facet %s: term facets have no ranges", name)
+			}
+		case "numeric":
+			for i, r := range spec.Ranges {
+				if r.Name == "" || (r.Min == nil && r.Max == nil) || r.Start != "" || r.End != "" {
+					return "", fmt.Errorf("facet %s: range %d needs a name and min and/or max", name, i+1)
+				}
+				fr.NumericRanges = append(fr.NumericRanges, numericRangeFacet{Name: r.Name, Min: r.Min, Max: r.Max})
+			}
+		case "date":
+			for i, r := range spec.Ranges {
+				if r.Name == "" || (r.Start == "" && r.End == "") || r.Min != nil || r.Max != nil {
+					return "", fmt.Errorf("facet %s: range %d needs a name and start and/or end", name, i+1)
+				}
+				fr.DateRanges = append(fr.DateRanges, dateRangeFacet{Name: r.Name, Start: r.Start, End: r.End})
+			}
+		default:
+			return "", fmt.Errorf("facet %s: type must be term, numeric or date", name)
+		}
+		if spec.Type != "" && spec.Type != "term" && len(spec.Ranges) == 0 {
+			return "", fmt.Errorf("facet %s: %s facets need ranges", name, spec.Type)
+		}
+		facets[name] = fr
+	}
+	data, err := json.Marshal(facets)
+	if err != nil {
+		return "", err
+	}
+	return "\"facets\": " + string(data), nil
+}
+
+// ftsFacetResult is the part of a facet in a search response needed
+// to count its buckets.
+type ftsFacetResult struct {
+	Terms         []json.RawMessage `json:"terms"`
+	NumericRanges []json.RawMessage `json:"numeric_ranges"`
+	DateRanges    []json.RawMessage `json:"date_ranges"`
+}
+
+// facetBuckets is the number of buckets over all facets of a response.
+func facetBuckets(facets map[string]ftsFacetResult) int {
+	n := 0
+	for _, f := range facets {
+		n += len(f.Terms) + len(f.NumericRanges) + len(f.DateRanges)
+	}
+	return n
+}
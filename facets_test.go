@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testFacetsYAML = `
+facets:
+  types: {field: type, size: 10}
+  ratings:
+    type: numeric
+    field: reviews.ratings.Overall
+    size: 2
+    ranges: [{name: low, max: 2}, {name: high, min: 2}]
+  reviewed:
+    type: date
+    field: reviews.date
+    size: 1
+    ranges: [{name: new, start: "2014-01-01"}]
+`
+
+func TestLoadFacetsFile(t *testing.T) {
+	frag, err := loadFacetsFile(writeTempFile(t, "facets.yaml", testFacetsYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal([]byte("{"+frag+"}"), &got); err != nil {
+		t.Fatalf("%v in %s", err, frag)
+	}
+	var want map[string]interface{}
+	json.Unmarshal([]byte(`{"facets": {
+		"types": {"field": "type", "size": 10},
+		"ratings": {"field": "reviews.ratings.Overall", "size": 2,
+			"numeric_ranges": [{"name": "low", "max": 2}, {"name": "high", "min": 2}]},
+		"reviewed": {"field": "reviews.date", "size": 1, "date_ranges": [{"name": "new", "start": "2014-01-01"}]}
+	}}`), &want)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	for name, content := range map[string]string{
+		"empty.json":   `{"facets": {}}`,
+		"nosize.json":  `{"facets": {"a": {"field": "type"}}}`,
+		"noranges.yml": "facets: {a: {type: numeric, field: x, size: 1}}",
+		"badtype.yml":  "facets: {a: {type: geo, field: x, size: 1}}",
+		"mixed.yml":    "facets: {a: {type: date, field: x, size: 1, ranges: [{name: r, min: 1}]}}",
+	} {
+		if _, err := loadFacetsFile(writeTempFile(t, name, content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestBombardierCountsFacets(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			var req struct {
+				Facets map[string]interface{} `json:"facets"`
+			}
+			if err := json.Unmarshal(body, &req); err != nil || len(req.Facets) != 3 {
+				t.Errorf("expected 3 facets in %s", body)
+			}
+			rw.Write([]byte(`{"status": {"total": 1, "successful": 1}, "total_hits": 0, "hits": [],
+				"facets": {"types": {"field": "type", "total": 3, "terms": [{"term": "hotel", "count": 2}, {"term": "landmark", "count": 1}]},
+					"ratings": {"field": "reviews.ratings.Overall", "total": 1, "numeric_ranges": [{"name": "high", "min": 2, "count": 1}]},
+					"reviewed": {"field": "reviews.date", "total": 0}}}`))
+		}),
+	)
+	defer s.Close()
+
+	numReqs := uint64(10)
+	conf := config{
+		numConns:   defaultNumberOfConns,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "POST",
+		body:       "{" + fts_query_pat + "}",
+		clientType: fhttp,
+		format:     knownFormat("plain-text"),
+	}
+	setBuiltinFtsData(&conf)
+	frag, err := loadFacetsFile(writeTempFile(t, "facets.yaml", testFacetsYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.facets = frag
+	b, err := newBombardier(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.disableOutput()
+	b.bombard()
+	if b.resp_withfacets_cnt != numReqs || b.facets_tot_buckets != 3*numReqs || b.facets_tot_bytes == 0 {
+		t.Errorf("unexpected facet stats: %d responses, %d buckets, %d bytes",
+			b.resp_withfacets_cnt, b.facets_tot_buckets, b.facets_tot_bytes)
+	}
+}
+
+func TestFacetsKeepRequestFacets(t *testing.T) {
+	numReqs := uint64(1)
+	conf := config{
+		numConns:   1,
+		numReqs:    &numReqs,
+		url:        "http://localhost:8094/api/index/ix/query",
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "POST",
+		body:       `{` + fts_query_pat + `, "facets": {"own": {"field": "type", "size": 1}}}`,
+		clientType: fhttp,
+		format:     knownFormat("plain-text"),
+	}
+	setBuiltinFtsData(&conf)
+	frag, err := loadFacetsFile(writeTempFile(t, "facets.yaml", testFacetsYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.facets = frag
+	b, err := newBombardier(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := b.client.builder().prepareRequest(b, 1, b.conf, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(p.body, `"facets"`); n != 1 {
+		t.Errorf("expected only the facets of the body, got %s", p.body)
+	}
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"fmt"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -30,6 +32,27 @@ func (n *nullableUint64) Set(value string) error {
 	return nil
 }
 
+type nullableInt64 struct {
+	val *int64
+}
+
+func (n *nullableInt64) String() string {
+	if n.val == nil {
+		return nilStr
+	}
+	return strconv.FormatInt(*n.val, 10)
+}
+
+func (n *nullableInt64) Set(value string) error {
+	res, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return err
+	}
+	n.val = new(int64)
+	*n.val = res
+	return nil
+}
+
 type nullableDuration struct {
 	val *time.Duration
 }
@@ -66,3 +89,27 @@ func (n *nullableString) Set(value string) error {
 	*n.val = value
 	return nil
 }
+
+type namedPath struct {
+	name, path string
+}
+
+// namedPathsList is a repeatable "name=path" flag.
+type namedPathsList []namedPath
+
+func (n *namedPathsList) String() string {
+	return fmt.Sprint(*n)
+}
+
+func (n *namedPathsList) IsCumulative() bool {
+	return true
+}
+
+func (n *namedPathsList) Set(value string) error {
+	res := strings.SplitN(value, "=", 2)
+	if len(res) != 2 || res[0] == "" || res[1] == "" {
+		return errInvalidNamedPathFormat
+	}
+	*n = append(*n, namedPath{res[0], res[1]})
+	return nil
+}
@@ -40,6 +40,19 @@ func TestNullableUint64Parsing(t *testing.T) {
 	}
 }
 
+func TestNullableInt64(t *testing.T) {
+	n := &nullableInt64{}
+	if s := n.String(); s != "nil" {
+		t.Errorf("Expected \"nil\", but got %v", s)
+	}
+	if err := n.Set(""); err == nil {
+		t.Error("Should fail on empty string")
+	}
+	if err := n.Set("-42"); err != nil || *n.val != -42 || n.String() != "-42" {
+		t.Error("Shouldn't fail on negative values")
+	}
+}
+
 func TestNullableDurationConversionToString(t *testing.T) {
 	nildur := &nullableDuration{val: nil}
 	if s := nildur.String(); s != "nil" {
@@ -78,3 +91,18 @@ func TestNullableStringConversionToString(t *testing.T) {
 		t.Errorf("Expected %q, but got %q", someVal, act)
 	}
 }
+
+func TestNamedPathsListParsing(t *testing.T) {
+	n := new(namedPathsList)
+	for _, bad := range []string{"", "name", "=path", "name="} {
+		if err := n.Set(bad); err != errInvalidNamedPathFormat {
+			t.Errorf("%q: expected %v, got %v", bad, errInvalidNamedPathFormat, err)
+		}
+	}
+	if err := n.Set("words=/tmp/a=b.txt"); err != nil {
+		t.Error(err)
+	}
+	if len(*n) != 1 || (*n)[0].name != "words" || (*n)[0].path != "/tmp/a=b.txt" {
+		t.Errorf("unexpected value %v", *n)
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// builtinWordListNames are the word lists compiled into cb_fts_bench,
+// a --wordList with one of these names replaces the built-in list.
+var builtinWordListNames = []string{
+	"sampleReviewWords",
+	"commonReviewWords",
+	"commonEnglishWords",
+	"commonVerbWords",
+}
+
+// wordListNames returns the names of all word lists known to c.
+func (c *config) wordListNames() []string {
+	names := append([]string{}, builtinWordListNames...)
+	extra := make([]string, 0, len(c.wordLists))
+	for name := range c.wordLists {
+		extra = append(extra, name)
+	}
+	sort.Strings(extra)
+	return append(names, extra...)
+}
+
+// setWordList replaces a built-in word list or adds a new one.
+func (c *config) setWordList(name string, words []string) {
+	switch name {
+	case "sampleReviewWords":
+		c.sampleReviewWords, c.sampleReviewWordsLen = words, len(words)
+	case "commonReviewWords":
+		c.commonReviewWords, c.commonReviewWordsLen = words, len(words)
+	case "commonEnglishWords":
+		c.commonEnglishWords, c.commonEnglishWordsLen = words, len(words)
+	case "commonVerbWords":
+		c.commonVerbWords, c.commonVerbWordsLen = words, len(words)
+	default:
+		if c.wordLists == nil {
+			c.wordLists = map[string][]string{}
+		}
+		c.wordLists[name] = words
+	}
+}
+
+// ftsDataFiles are the files given on the command line that replace or
+// add to the built-in data used by the query generators.
+type ftsDataFiles struct {
+	wordLists           namedPathsList
+	geoPoints           string
+	vectors             string
+	groundTruth         string
+	groundTruthIDFormat string
+	csvTables           namedPathsList
+}
+
+// loadFtsDataFiles applies --wordList and --geoPoints on top of the
+// built-in data and loads the --vectors and --groundTruth files.
+func loadFtsDataFiles(c *config, files ftsDataFiles) error {
+	for _, np := range files.csvTables {
+		table, err := readDataTable(np.path)
+		if err != nil {
+			return fmt.Errorf("csv %s: %v", np.name, err)
+		}
+		if c.dataTables == nil {
+			c.dataTables = map[string]*dataTable{}
+		}
+		c.dataTables[np.name] = table
+	}
+	for _, np := range files.wordLists {
+		words, err := readWordListFile(np.path)
+		if err != nil {
+			return fmt.Errorf("word list %s: %v", np.name, err)
+		}
+		c.setWordList(np.name, words)
+	}
+	if files.geoPoints != "" {
+		points, err := readGeoPointsFile(files.geoPoints)
+		if err != nil {
+			return fmt.Errorf("geo points: %v", err)
+		}
+		c.hotelLocationLatLons, c.hotelLocationLatLonsLen = points, len(points)
+	}
+	if files.vectors != "" {
+		vectors, err := readVectorsFile(files.vectors)
+		if err != nil {
+			return fmt.Errorf("vectors: %v", err)
+		}
+		c.vectors = vectors
+	}
+	if files.groundTruth != "" {
+		if c.vectors == nil {
+			return errGroundTruthWithoutVectors
+		}
+		truth, err := readGroundTruthFile(files.groundTruth, files.groundTruthIDFormat)
+		if err != nil {
+			return fmt.Errorf("ground truth: %v", err)
+		}
+		if len(truth) < len(c.vectors) {
+			return fmt.Errorf("ground truth: %d neighbor lists for %d query vectors", len(truth), len(c.vectors))
+		}
+		c.groundTruth = truth
+	}
+	return nil
+}
+
+// readWordListFile reads terms from a .json file (an array of strings),
+// a .csv file (first column of each row) or a text file (one term per
+// line). Empty lines and lines starting with # are skipped.
+func readWordListFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var words []string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := json.NewDecoder(f).Decode(&words); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+	case ".csv":
+		rows, err := readCSVRows(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		for _, row := range rows {
+			words = append(words, row[0])
+		}
+	default:
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			words = append(words, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	res := words[:0]
+	for _, w := range words {
+		w = strings.TrimSpace(w)
+		if w == "" || strings.HasPrefix(w, "#") {
+			continue
+		}
+		res = append(res, w)
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("%s: no terms found", path)
+	}
+	return res, nil
+}
+
+// readGeoPointsFile reads lon/lat points from a .json file, either an
+// array of [lon, lat] pairs (the built-in order) or an array of
+// {"lat": ..., "lon": ...} objects, or from a .csv file. A CSV file may
+// have a header naming its lat/lon (or latitude/longitude) columns,
+// without one the columns are lon,lat.
+func readGeoPointsFile(path string) ([][]float32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var points [][]float32
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		points, err = decodeGeoPointsJSON(f)
+	case ".csv":
+		points, err = decodeGeoPointsCSV(f)
+	default:
+		err = fmt.Errorf("unsupported file type, use .csv or .json")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(points) == 0 {
+		return nil, fmt.Errorf("%s: no points found", path)
+	}
+	for i, p := range points {
+		if p[0] < -180 || p[0] > 180 || p[1] < -90 || p[1] > 90 {
+			return nil, fmt.Errorf("%s: point %d (lon %v, lat %v) out of range", path, i+1, p[0], p[1])
+		}
+	}
+	return points, nil
+}
+
+func decodeGeoPointsJSON(r io.Reader) ([][]float32, error) {
+	var raw []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, err
+	}
+	points := make([][]float32, 0, len(raw))
+	for i, m := range raw {
+		var pair []float32
+		if err := json.Unmarshal(m, &pair); err == nil {
+			if len(pair) != 2 {
+				return nil, fmt.Errorf("point %d: expected [lon, lat]", i+1)
+			}
+			points = append(points, pair)
+			continue
+		}
+		var obj struct {
+			Lat *float32 `json:"lat"`
+			Lon *float32 `json:"lon"`
+		}
+		if err := json.Unmarshal(m, &obj); err != nil || obj.Lat == nil || obj.Lon == nil {
+			return nil, fmt.Errorf("point %d: expected [lon, lat] or {\"lat\": .., \"lon\": ..}", i+1)
+		}
+		points = append(points, []float32{*obj.Lon, *obj.Lat})
+	}
+	return points, nil
+}
+
+func decodeGeoPointsCSV(r io.Reader) ([][]float32, error) {
+	rows, err := readCSVRows(r)
+	if err != nil {
+		return nil, err
+	}
+	lonCol, latCol := 0, 1
+	if len(rows) > 0 {
+		if _, err := strconv.ParseFloat(strings.TrimSpace(rows[0][0]), 32); err != nil {
+			lonCol, latCol = -1, -1
+			for i, h := range rows[0] {
+				switch strings.ToLower(strings.TrimSpace(h)) {
+				case "lon", "lng", "longitude":
+					lonCol = i
+				case "lat", "latitude":
+					latCol = i
+				}
+			}
+			if lonCol < 0 || latCol < 0 {
+				return nil, fmt.Errorf("header must name lat and lon columns")
+			}
+			rows = rows[1:]
+		}
+	}
+	points := make([][]float32, 0, len(rows))
+	for i, row := range rows {
+		if len(row) <= lonCol || len(row) <= latCol {
+			return nil, fmt.Errorf("row %d: missing lat/lon", i+1)
+		}
+		lon, err := strconv.ParseFloat(strings.TrimSpace(row[lonCol]), 32)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", i+1, err)
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(row[latCol]), 32)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", i+1, err)
+		}
+		points = append(points, []float32{float32(lon), float32(lat)})
+	}
+	return points, nil
+}
+
+func readCSVRows(r io.Reader) ([][]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.Comment = '#'
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	res := rows[:0]
+	for _, row := range rows {
+		if len(row) > 0 && row[0] != "" {
+			res = append(res, row)
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadWordListFile(t *testing.T) {
+	expectations := []struct {
+		name, content string
+		out           []string
+	}{
+		{"words.txt", "# comment\nbalcony\n\n  pool \nview\n", []string{"balcony", "pool", "view"}},
+		{"words.csv", "balcony,12\npool,7\n# skipped\nview\n", []string{"balcony", "pool", "view"}},
+		{"words.json", `["balcony", " pool", ""]`, []string{"balcony", "pool"}},
+	}
+	for _, e := range expectations {
+		words, err := readWordListFile(writeTempFile(t, e.name, e.content))
+		if err != nil {
+			t.Error(e.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(words, e.out) {
+			t.Errorf("%s: expected %v, got %v", e.name, e.out, words)
+		}
+	}
+	if _, err := readWordListFile(writeTempFile(t, "empty.txt", "\n# nothing\n")); err == nil {
+		t.Error("expected an error for an empty word list")
+	}
+}
+
+func TestReadGeoPointsFile(t *testing.T) {
+	want := [][]float32{{-122.5, 37.75}, {0.5, 51.25}}
+	expectations := []struct {
+		name, content string
+	}{
+		{"points.csv", "-122.5,37.75\n0.5,51.25\n"},
+		{"points.csv", "name,latitude,longitude\nsf,37.75,-122.5\nuk,51.25,0.5\n"},
+		{"points.json", `[[-122.5, 37.75], [0.5, 51.25]]`},
+		{"points.json", `[{"lat": 37.75, "lon": -122.5}, {"lon": 0.5, "lat": 51.25, "accuracy": "x"}]`},
+	}
+	for _, e := range expectations {
+		points, err := readGeoPointsFile(writeTempFile(t, e.name, e.content))
+		if err != nil {
+			t.Error(e.content, err)
+			continue
+		}
+		if !reflect.DeepEqual(points, want) {
+			t.Errorf("%s: expected %v, got %v", e.content, want, points)
+		}
+	}
+	bad := []struct {
+		name, content, errPart string
+	}{
+		{"points.csv", "a,b\n1,2\n", "header"},
+		{"points.csv", "1,95\n", "out of range"},
+		{"points.json", `[[1, 2, 3]]`, "expected [lon, lat]"},
+		{"points.txt", "1,2\n", "unsupported"},
+	}
+	for _, e := range bad {
+		_, err := readGeoPointsFile(writeTempFile(t, e.name, e.content))
+		if err == nil || !strings.Contains(err.Error(), e.errPart) {
+			t.Errorf("%s: expected error containing %q, got %v", e.content, e.errPart, err)
+		}
+	}
+}
+
+func TestLoadFtsDataFiles(t *testing.T) {
+	var conf config
+	setBuiltinFtsData(&conf)
+	lists := namedPathsList{
+		{"commonVerbWords", writeTempFile(t, "verbs.txt", "stay\nbook\n")},
+		{"productNames", writeTempFile(t, "products.txt", "couchbase\ncapella\n")},
+	}
+	geo := writeTempFile(t, "points.json", `[[1, 2]]`)
+	if err := loadFtsDataFiles(&conf, ftsDataFiles{wordLists: lists, geoPoints: geo}); err != nil {
+		t.Fatal(err)
+	}
+	if conf.commonVerbWordsLen != 2 || conf.commonVerbWords[1] != "book" {
+		t.Errorf("built-in list not replaced: %v", conf.commonVerbWords)
+	}
+	if got := conf.wordList("productNames"); len(got) != 2 {
+		t.Errorf("extra list not added: %v", got)
+	}
+	if conf.hotelLocationLatLonsLen != 1 {
+		t.Errorf("geo points not replaced: %v", conf.hotelLocationLatLons)
+	}
+	names := conf.wordListNames()
+	if names[len(names)-1] != "productNames" {
+		t.Errorf("unexpected word list names %v", names)
+	}
+}
+
+func TestLoadedWordsAreEscaped(t *testing.T) {
+	var conf config
+	setBuiltinFtsData(&conf)
+	path := writeTempFile(t, "words.txt", "say \"cheese\"\nback\\slash\n")
+	lists := namedPathsList{}
+	for _, name := range builtinWordListNames {
+		lists = append(lists, namedPath{name, path})
+	}
+	if err := loadFtsDataFiles(&conf, ftsDataFiles{wordLists: lists}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"match-sample-1", "terms-common-2", "terms-sample-1-common-1", "fuzzy-1", "random-terms", "boolean-2x3"} {
+		gen := queryGeneratorsByName[name].gen
+		for i := 0; i < 20; i++ {
+			if q := gen.generate(conf); !json.Valid([]byte("{" + q + "}")) {
+				t.Fatalf("%s: invalid JSON %s", name, q)
+			}
+		}
+	}
+}
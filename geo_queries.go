@@ -0,0 +1,207 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Real FTS geo queries against a geopoint field, unlike test 43 these
+// need the index to map the field (travel-sample "geo") as a geopoint.
+
+var geoDistanceUnits = []string{"mm", "cm", "m", "km", "in", "ft", "yd", "mi", "nm"}
+
+// geoQueryOpts are shared by all geo generators.
+type geoQueryOpts struct {
+	field string
+	// sort adds a geo_distance sort from the query's center
+	sort bool
+	unit string
+}
+
+func (o *geoQueryOpts) withParams(p *queryParams) error {
+	o.field = p.string("field", o.field)
+	o.sort = p.bool("sort", o.sort)
+	o.unit = p.string("unit", o.unit)
+	for _, u := range geoDistanceUnits {
+		if o.unit == u {
+			return nil
+		}
+	}
+	return fmt.Errorf("unit must be one of %s", strings.Join(geoDistanceUnits, ", "))
+}
+
+func (o geoQueryOpts) describeSort() string {
+	if !o.sort {
+		return ""
+	}
+	return fmt.Sprintf("\nhits are sorted by geo_distance (%s) from the center", o.unit)
+}
+
+// wrap turns a geo query clause into the __FTS_QUERY__ replacement,
+// adding the optional sort unless the request has its own.
+func (o geoQueryOpts) wrap(conf config, clause string, lon, lat float64) string {
+	repl := "\"query\": " + clause
+	if o.sort && !conf.hasSort {
+		repl += fmt.Sprintf(", \"sort\": [{\"by\": \"geo_distance\", \"field\": \"%s\", \"unit\": \"%s\", \"location\": %s}]",
+			o.field, o.unit, geoJSONPoint(lon, lat))
+	}
+	return repl
+}
+
+func geoJSONPoint(lon, lat float64) string {
+	return fmt.Sprintf("{\"lon\": %f, \"lat\": %f}", lon, lat)
+}
+
+func clampLat(lat float64) float64 { return math.Max(-90, math.Min(90, lat)) }
+func clampLon(lon float64) float64 { return math.Max(-180, math.Min(180, lon)) }
+
+// randomGeoCenter picks a random hotel location moved by up to +/-0.05
+// degrees, the same offsets as buildPseudoGeoRandomQuery.
+func randomGeoCenter(conf config) (lon, lat float64) {
+	num := randIntFromRange(conf.rnd(), 0, conf.hotelLocationLatLonsLen-1)
+	center := conf.hotelLocationLatLons[num]
+	lon = float64(center[0]) + float64(randIntFromRange(conf.rnd(), 0, 1000))/10000 - 0.05
+	lat = float64(center[1]) + float64(randIntFromRange(conf.rnd(), 0, 1000))/10000 - 0.05
+	return clampLon(lon), clampLat(lat)
+}
+
+// randomGeoExtent is a random half width in degrees, like the pseudo
+// geo bounding box it is 1/30 to 1/2 of scale.
+func randomGeoExtent(r randGen, scale float64) float64 {
+	return float64(randIntFromRange(r, 1, 15)) / 30.0 * scale
+}
+
+// geoDistanceQueryGen emits a geo_distance query with a random radius.
+type geoDistanceQueryGen struct {
+	geoQueryOpts
+	minDistance, maxDistance float64
+	miss                     float64
+}
+
+func (g geoDistanceQueryGen) generate(conf config) string {
+	lon, lat := randomGeoCenter(conf)
+	distance := g.minDistance + conf.rnd().Float64()*(g.maxDistance-g.minDistance)
+	clause := fmt.Sprintf("{\"location\": %s, \"distance\": \"%.3f%s\", \"field\": \"%s\"}",
+		geoJSONPoint(lon, lat), distance, g.unit, g.field)
+	return g.wrap(conf, clause, lon, lat)
+}
+
+func (g geoDistanceQueryGen) describe() string {
+	return fmt.Sprintf("Take a random hotel location lat/lon, apply some random offsets and search %s for points\n"+
+		"within a random distance of %g to %g %s", g.field, g.minDistance, g.maxDistance, g.unit) + g.describeSort()
+}
+
+func (g geoDistanceQueryGen) missRate() float64 { return g.miss }
+
+func (g geoDistanceQueryGen) withParams(conf config, p *queryParams) (queryGenerator, error) {
+	if err := g.geoQueryOpts.withParams(p); err != nil {
+		return nil, err
+	}
+	g.minDistance = p.float("minDistance", g.minDistance)
+	g.maxDistance = p.float("maxDistance", g.maxDistance)
+	if g.minDistance <= 0 || g.maxDistance < g.minDistance {
+		return nil, fmt.Errorf("need 0 < minDistance <= maxDistance")
+	}
+	return g, nil
+}
+
+// geoBoundingBoxQueryGen emits a top_left/bottom_right bounding box.
+type geoBoundingBoxQueryGen struct {
+	geoQueryOpts
+	scale float64
+	miss  float64
+}
+
+func (g geoBoundingBoxQueryGen) generate(conf config) string {
+	lon, lat := randomGeoCenter(conf)
+	topLeft := geoJSONPoint(clampLon(lon-randomGeoExtent(conf.rnd(), g.scale)), clampLat(lat+randomGeoExtent(conf.rnd(), g.scale)))
+	bottomRight := geoJSONPoint(clampLon(lon+randomGeoExtent(conf.rnd(), g.scale)), clampLat(lat-randomGeoExtent(conf.rnd(), g.scale)))
+	clause := fmt.Sprintf("{\"top_left\": %s, \"bottom_right\": %s, \"field\": \"%s\"}",
+		topLeft, bottomRight, g.field)
+	return g.wrap(conf, clause, lon, lat)
+}
+
+func (g geoBoundingBoxQueryGen) describe() string {
+	return fmt.Sprintf("Take a random hotel location lat/lon, apply some random offsets and search %s with a\n"+
+		"bounding box built by moving each edge by random amounts (scale %g)", g.field, g.scale) + g.describeSort()
+}
+
+func (g geoBoundingBoxQueryGen) missRate() float64 { return g.miss }
+
+func (g geoBoundingBoxQueryGen) withParams(conf config, p *queryParams) (queryGenerator, error) {
+	if err := g.geoQueryOpts.withParams(p); err != nil {
+		return nil, err
+	}
+	g.scale = p.float("scale", g.scale)
+	if g.scale <= 0 {
+		return nil, fmt.Errorf("scale must be > 0")
+	}
+	return g, nil
+}
+
+// geoPolygonQueryGen emits a polygon_points query, a star shaped polygon
+// around a random center with random vertex distances.
+type geoPolygonQueryGen struct {
+	geoQueryOpts
+	scale    float64
+	vertices int
+	miss     float64
+}
+
+func (g geoPolygonQueryGen) generate(conf config) string {
+	lon, lat := randomGeoCenter(conf)
+	points := make([]string, 0, g.vertices)
+	step := 2 * math.Pi / float64(g.vertices)
+	for i := 0; i < g.vertices; i++ {
+		// counterclockwise, each vertex jittered within its sector
+		angle := (float64(i) + 0.25 + conf.rnd().Float64()*0.5) * step
+		r := randomGeoExtent(conf.rnd(), g.scale)
+		points = append(points, fmt.Sprintf("{\"lat\": %f, \"lon\": %f}",
+			clampLat(lat+r*math.Sin(angle)), clampLon(lon+r*math.Cos(angle))))
+	}
+	clause := fmt.Sprintf("{\"polygon_points\": [%s], \"field\": \"%s\"}",
+		strings.Join(points, ", "), g.field)
+	return g.wrap(conf, clause, lon, lat)
+}
+
+func (g geoPolygonQueryGen) describe() string {
+	return fmt.Sprintf("Take a random hotel location lat/lon, apply some random offsets and search %s with a\n"+
+		"%d point polygon around it, each vertex at a random distance (scale %g)", g.field, g.vertices, g.scale) + g.describeSort()
+}
+
+func (g geoPolygonQueryGen) missRate() float64 { return g.miss }
+
+func (g geoPolygonQueryGen) withParams(conf config, p *queryParams) (queryGenerator, error) {
+	if err := g.geoQueryOpts.withParams(p); err != nil {
+		return nil, err
+	}
+	g.scale = p.float("scale", g.scale)
+	g.vertices = p.int("vertices", g.vertices)
+	if g.scale <= 0 {
+		return nil, fmt.Errorf("scale must be > 0")
+	}
+	if g.vertices < 3 {
+		return nil, fmt.Errorf("vertices must be >= 3")
+	}
+	return g, nil
+}
+
+func init() {
+	for i, sorted := range []bool{false, true} {
+		opts := geoQueryOpts{field: "geo", sort: sorted, unit: "km"}
+		suffix := ""
+		if sorted {
+			suffix = "-sorted"
+		}
+		registerQueryGenerator(uint64(44+3*i), "geo-distance"+suffix, geoDistanceQueryGen{
+			geoQueryOpts: opts, minDistance: 1, maxDistance: 50, miss: missRateUnknown,
+		})
+		registerQueryGenerator(uint64(45+3*i), "geo-bounding-box"+suffix, geoBoundingBoxQueryGen{
+			geoQueryOpts: opts, scale: 0.25, miss: missRateUnknown,
+		})
+		registerQueryGenerator(uint64(46+3*i), "geo-polygon"+suffix, geoPolygonQueryGen{
+			geoQueryOpts: opts, scale: 0.25, vertices: 5, miss: missRateUnknown,
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGeoQueriesAreValidJSON(t *testing.T) {
+	var conf config
+	setBuiltinFtsData(&conf)
+	for _, name := range []string{
+		"geo-distance", "geo-bounding-box", "geo-polygon",
+		"geo-distance-sorted", "geo-bounding-box-sorted", "geo-polygon-sorted",
+	} {
+		rg := queryGeneratorsByName[name]
+		for i := 0; i < 100; i++ {
+			q := rg.gen.generate(conf)
+			var v map[string]interface{}
+			if err := json.Unmarshal([]byte("{"+q+"}"), &v); err != nil {
+				t.Fatalf("%s: %v in %s", name, err, q)
+			}
+			if _, ok := v["sort"]; ok != strings.HasSuffix(name, "-sorted") {
+				t.Errorf("%s: unexpected sort in %s", name, q)
+			}
+			if v["query"].(map[string]interface{})["field"] != "geo" {
+				t.Errorf("%s: missing field in %s", name, q)
+			}
+		}
+	}
+}
+
+func TestGeoSortKeepsRequestSort(t *testing.T) {
+	var conf config
+	setBuiltinFtsData(&conf)
+	conf.hasSort = true
+	if q := queryGeneratorsByName["geo-distance-sorted"].gen.generate(conf); strings.Contains(q, "sort") {
+		t.Errorf("expected no second sort in %s", q)
+	}
+}
+
+func TestGeoPolygonParams(t *testing.T) {
+	var conf config
+	setBuiltinFtsData(&conf)
+	gen := queryGeneratorsByName["geo-polygon"].gen.(configurableQueryGenerator)
+	p := newQueryParams(map[string]interface{}{"vertices": 7, "field": "loc", "unit": "mi", "sort": true})
+	g, err := gen.withParams(conf, p)
+	if err == nil {
+		err = p.check()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v struct {
+		Query struct {
+			Points []map[string]float64 `json:"polygon_points"`
+			Field  string               `json:"field"`
+		} `json:"query"`
+		Sort []map[string]interface{} `json:"sort"`
+	}
+	q := g.generate(conf)
+	if err := json.Unmarshal([]byte("{"+q+"}"), &v); err != nil {
+		t.Fatal(err)
+	}
+	if len(v.Query.Points) != 7 || v.Query.Field != "loc" {
+		t.Errorf("unexpected query %s", q)
+	}
+	if len(v.Sort) != 1 || v.Sort[0]["unit"] != "mi" || v.Sort[0]["field"] != "loc" {
+		t.Errorf("unexpected sort %s", q)
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"vertices": 2},
+		{"unit": "parsec"},
+		{"scale": 0},
+	} {
+		if _, err := gen.withParams(conf, newQueryParams(bad)); err == nil {
+			t.Errorf("expected an error for %v", bad)
+		}
+	}
+}
+
+func TestGeoDistanceRejectsBadRange(t *testing.T) {
+	var conf config
+	gen := queryGeneratorsByName["geo-distance"].gen.(configurableQueryGenerator)
+	p := newQueryParams(map[string]interface{}{"minDistance": 10, "maxDistance": 5})
+	if _, err := gen.withParams(conf, p); err == nil {
+		t.Error("expected an error for maxDistance < minDistance")
+	}
+}
@@ -12,6 +12,7 @@ require (
 	github.com/satori/go.uuid v1.2.0
 	github.com/tidwall/gjson v1.14.4
 	golang.org/x/net v0.11.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
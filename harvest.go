@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/alecthomas/kingpin"
+)
+
+// harvestCommand is the first argument that selects the vocabulary
+// harvesting mode instead of a benchmark run.
+const harvestCommand = "harvest"
+
+// harvestConfig holds the options of the harvest subcommand.
+type harvestConfig struct {
+	inputs        []string
+	outDir        string
+	textFields    []string
+	numericFields []string
+	geoFields     []string
+	minTermLen    int
+	rareMaxDocs   uint64
+	commonMinDf   float64
+}
+
+func parseHarvestArgs(args []string) (harvestConfig, error) {
+	hc := harvestConfig{}
+	app := kingpin.New(args[0]+" "+harvestCommand,
+		"Harvest FTS benchmark vocabulary (word lists, numeric ranges and geo points) from a local JSON dataset. "+
+			"Inputs are directories (of .json and .jsonl files), .jsonl files (one document per line) "+
+			"or .json files (one document or an array of documents).")
+	app.Flag("field", "Text field to extract terms from, dotted path, arrays are traversed e.g. reviews.content (can be repeated)").
+		Short('f').
+		StringsVar(&hc.textFields)
+	app.Flag("numeric", "Numeric field to collect the min/max range of, e.g. reviews.ratings.Overall (can be repeated)").
+		Short('N').
+		StringsVar(&hc.numericFields)
+	app.Flag("geo", "Field holding a geo point {\"lat\":..,\"lon\":..} or [lon,lat], e.g. geo (can be repeated)").
+		Short('g').
+		StringsVar(&hc.geoFields)
+	app.Flag("out", "Directory to write the vocabulary files to").
+		Short('o').
+		Default(".").
+		StringVar(&hc.outDir)
+	app.Flag("minTermLen", "Skip terms shorter than this").
+		Default("2").
+		IntVar(&hc.minTermLen)
+	app.Flag("rareMaxDocs", "A term found in at most this many documents goes to the rare bucket").
+		Default("2").
+		Uint64Var(&hc.rareMaxDocs)
+	app.Flag("commonMinDf", "A term found in at least this fraction of the documents having the field goes to the common bucket").
+		Default("0.05").
+		Float64Var(&hc.commonMinDf)
+	app.Arg("input", "Directory or JSON/JSONL file(s) with the documents").
+		Required().
+		StringsVar(&hc.inputs)
+
+	if _, err := app.Parse(args[2:]); err != nil {
+		return hc, err
+	}
+	if len(hc.textFields)+len(hc.numericFields)+len(hc.geoFields) == 0 {
+		return hc, errNoHarvestFields
+	}
+	if hc.commonMinDf <= 0 || hc.commonMinDf > 1 {
+		return hc, errInvalidCommonMinDf
+	}
+	return hc, nil
+}
+
+// runHarvest is the entry point of "cb_fts_bench harvest ...".
+func runHarvest(args []string) int {
+	hc, err := parseHarvestArgs(args)
+	if err != nil {
+		fmt.Println(err)
+		return exitFailure
+	}
+	h := newVocabHarvester(hc)
+	for _, in := range hc.inputs {
+		if err := readDocuments(in, h.addDoc); err != nil {
+			fmt.Println(err)
+			return exitFailure
+		}
+	}
+	files, err := h.write()
+	if err != nil {
+		fmt.Println(err)
+		return exitFailure
+	}
+	fmt.Printf("harvested %d documents\n", h.numDocs)
+	for _, f := range files {
+		fmt.Printf("  wrote %s\n", f)
+	}
+	return 0
+}
+
+type termStats struct {
+	docFreq, termFreq uint64
+}
+
+type fieldVocab struct {
+	docs  uint64
+	terms map[string]*termStats
+}
+
+// numericRange is the summary written for each --numeric field.
+type numericRange struct {
+	Count uint64  `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+}
+
+type vocabHarvester struct {
+	conf    harvestConfig
+	numDocs uint64
+
+	text    map[string]*fieldVocab
+	numeric map[string]*numericRange
+	geo     map[string][][]float32
+}
+
+func newVocabHarvester(hc harvestConfig) *vocabHarvester {
+	h := &vocabHarvester{
+		conf:    hc,
+		text:    map[string]*fieldVocab{},
+		numeric: map[string]*numericRange{},
+		geo:     map[string][][]float32{},
+	}
+	for _, f := range hc.textFields {
+		h.text[f] = &fieldVocab{terms: map[string]*termStats{}}
+	}
+	for _, f := range hc.numericFields {
+		h.numeric[f] = &numericRange{Min: math.Inf(1), Max: math.Inf(-1)}
+	}
+	return h
+}
+
+func (h *vocabHarvester) addDoc(doc interface{}) {
+	h.numDocs++
+	for field, fv := range h.text {
+		values := fieldValues(doc, field)
+		if len(values) == 0 {
+			continue
+		}
+		fv.docs++
+		seen := map[string]bool{}
+		for _, v := range values {
+			s, ok := v.(string)
+			if !ok {
+				continue
+			}
+			for _, term := range tokenize(s, h.conf.minTermLen) {
+				ts, ok := fv.terms[term]
+				if !ok {
+					ts = new(termStats)
+					fv.terms[term] = ts
+				}
+				ts.termFreq++
+				if !seen[term] {
+					seen[term] = true
+					ts.docFreq++
+				}
+			}
+		}
+	}
+	for field, nr := range h.numeric {
+		for _, v := range fieldValues(doc, field) {
+			f, ok := v.(float64)
+			if !ok {
+				continue
+			}
+			nr.Count++
+			nr.Min = math.Min(nr.Min, f)
+			nr.Max = math.Max(nr.Max, f)
+		}
+	}
+	for _, field := range h.conf.geoFields {
+		for _, v := range fieldValues(doc, field) {
+			if p, ok := geoPoint(v); ok {
+				h.geo[field] = append(h.geo[field], p)
+			}
+		}
+	}
+}
+
+// write creates for each text field <field>.freq.csv (term, doc and
+// term frequency), <field>.txt (all terms), <field>.common.txt and
+// <field>.rare.txt, for each geo field <field>.geo.json and, if any
+// numeric fields were given, numeric_ranges.json. All of them can be
+// loaded via --wordList and --geoPoints.
+func (h *vocabHarvester) write() ([]string, error) {
+	if err := os.MkdirAll(h.conf.outDir, 0755); err != nil {
+		return nil, err
+	}
+	var files []string
+	out := func(name string, data []byte) error {
+		path := filepath.Join(h.conf.outDir, name)
+		files = append(files, path)
+		return ioutil.WriteFile(path, data, 0644)
+	}
+
+	for _, field := range h.conf.textFields {
+		fv := h.text[field]
+		terms := make([]string, 0, len(fv.terms))
+		for t := range fv.terms {
+			terms = append(terms, t)
+		}
+		sort.Slice(terms, func(i, j int) bool {
+			a, b := fv.terms[terms[i]], fv.terms[terms[j]]
+			if a.docFreq != b.docFreq {
+				return a.docFreq > b.docFreq
+			}
+			return terms[i] < terms[j]
+		})
+
+		var freq, all, common, rare strings.Builder
+		freq.WriteString("# term,docFreq,termFreq\n")
+		commonMin := uint64(math.Ceil(h.conf.commonMinDf * float64(fv.docs)))
+		for _, t := range terms {
+			ts := fv.terms[t]
+			fmt.Fprintf(&freq, "%s,%d,%d\n", t, ts.docFreq, ts.termFreq)
+			all.WriteString(t + "\n")
+			if ts.docFreq >= commonMin && ts.docFreq > h.conf.rareMaxDocs {
+				common.WriteString(t + "\n")
+			} else if ts.docFreq <= h.conf.rareMaxDocs {
+				rare.WriteString(t + "\n")
+			}
+		}
+		for _, f := range []struct {
+			suffix string
+			sb     *strings.Builder
+		}{{".freq.csv", &freq}, {".txt", &all}, {".common.txt", &common}, {".rare.txt", &rare}} {
+			if err := out(field+f.suffix, []byte(f.sb.String())); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, field := range h.conf.geoFields {
+		data, err := json.Marshal(h.geo[field])
+		if err != nil {
+			return nil, err
+		}
+		if err := out(field+".geo.json", data); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(h.numeric) > 0 {
+		ranges := map[string]*numericRange{}
+		for field, nr := range h.numeric {
+			if nr.Count > 0 {
+				ranges[field] = nr
+			}
+		}
+		data, err := json.MarshalIndent(ranges, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := out("numeric_ranges.json", data); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// tokenize approximates the FTS standard analyzer: split on anything
+// but letters and digits and lower case, pure numbers are skipped.
+func tokenize(s string, minLen int) []string {
+	var res []string
+	for _, tok := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if len([]rune(tok)) < minLen {
+			continue
+		}
+		if _, err := strconv.ParseFloat(tok, 64); err == nil {
+			continue
+		}
+		res = append(res, strings.ToLower(tok))
+	}
+	return res
+}
+
+// fieldValues returns all values found at the dotted path, arrays met
+// along the way are traversed.
+func fieldValues(v interface{}, path string) []interface{} {
+	if arr, ok := v.([]interface{}); ok {
+		var res []interface{}
+		for _, e := range arr {
+			res = append(res, fieldValues(e, path)...)
+		}
+		return res
+	}
+	if path == "" {
+		if v == nil {
+			return nil
+		}
+		return []interface{}{v}
+	}
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	key, rest := path, ""
+	if i := strings.IndexByte(path, '.'); i >= 0 {
+		key, rest = path[:i], path[i+1:]
+	}
+	return fieldValues(obj[key], rest)
+}
+
+// geoPoint accepts the geo point formats understood by FTS that appear
+// in JSON documents, it returns [lon, lat].
+func geoPoint(v interface{}) ([]float32, bool) {
+	switch p := v.(type) {
+	case map[string]interface{}:
+		lat, okLat := p["lat"].(float64)
+		lon, okLon := p["lon"].(float64)
+		if !okLon {
+			lon, okLon = p["lng"].(float64)
+		}
+		if okLat && okLon {
+			return []float32{float32(lon), float32(lat)}, true
+		}
+	case []interface{}:
+		if len(p) == 2 {
+			lon, okLon := p[0].(float64)
+			lat, okLat := p[1].(float64)
+			if okLat && okLon {
+				return []float32{float32(lon), float32(lat)}, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// readDocuments calls fn for every document found in path.
+func readDocuments(path string, fn func(doc interface{})) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if fi.IsDir() {
+		return filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			switch strings.ToLower(filepath.Ext(p)) {
+			case ".json", ".jsonl", ".ndjson":
+				if !info.IsDir() {
+					return readDocumentsFile(p, fn)
+				}
+			}
+			return nil
+		})
+	}
+	return readDocumentsFile(path, fn)
+}
+
+func readDocumentsFile(path string, fn func(doc interface{})) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		var doc interface{}
+		if err := json.NewDecoder(f).Decode(&doc); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		if arr, ok := doc.([]interface{}); ok {
+			for _, d := range arr {
+				fn(d)
+			}
+		} else {
+			fn(doc)
+		}
+		return nil
+	}
+
+	r := bufio.NewReader(f)
+	for lineNo := 1; ; lineNo++ {
+		line, err := r.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var doc interface{}
+			if jerr := json.Unmarshal(line, &doc); jerr != nil {
+				return fmt.Errorf("%s:%d: %v", path, lineNo, jerr)
+			}
+			fn(doc)
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestHarvestVocabulary(t *testing.T) {
+	docs := writeTempFile(t, "hotels.jsonl", `
+{"name": "a", "geo": {"lat": 37.5, "lon": -122.25}, "reviews": [{"content": "Great pool, great view", "ratings": {"Overall": 5}}, {"content": "Pool was cold"}]}
+{"name": "b", "geo": {"lat": 51.5, "lon": 0.25}, "reviews": [{"content": "Nice pool; 2 beds", "ratings": {"Overall": 2}}]}
+{"name": "c", "reviews": []}
+`)
+	outDir := filepath.Join(filepath.Dir(docs), "vocab")
+	hc, err := parseHarvestArgs([]string{"cb_fts_bench", harvestCommand,
+		"--field", "reviews.content", "--numeric", "reviews.ratings.Overall", "--geo", "geo",
+		"--rareMaxDocs", "1", "--commonMinDf", "1", "-o", outDir, docs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := newVocabHarvester(hc)
+	if err := readDocuments(docs, h.addDoc); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.write(); err != nil {
+		t.Fatal(err)
+	}
+	if h.numDocs != 3 {
+		t.Errorf("expected 3 docs, got %d", h.numDocs)
+	}
+
+	words, err := readWordListFile(filepath.Join(outDir, "reviews.content.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if words[0] != "pool" {
+		t.Errorf("expected the most frequent term first, got %v", words)
+	}
+	freq, err := readWordListFile(filepath.Join(outDir, "reviews.content.freq.csv"))
+	if err != nil || !reflect.DeepEqual(freq, words) {
+		t.Errorf("freq.csv terms %v (%v) differ from %v", freq, err, words)
+	}
+	common, err := readWordListFile(filepath.Join(outDir, "reviews.content.common.txt"))
+	if err != nil || !reflect.DeepEqual(common, []string{"pool"}) {
+		t.Errorf("unexpected common terms %v (%v)", common, err)
+	}
+	rare, err := readWordListFile(filepath.Join(outDir, "reviews.content.rare.txt"))
+	if err != nil || len(rare) != len(words)-1 {
+		t.Errorf("unexpected rare terms %v (%v)", rare, err)
+	}
+
+	points, err := readGeoPointsFile(filepath.Join(outDir, "geo.geo.json"))
+	if err != nil || !reflect.DeepEqual(points, [][]float32{{-122.25, 37.5}, {0.25, 51.5}}) {
+		t.Errorf("unexpected geo points %v (%v)", points, err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(outDir, "numeric_ranges.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ranges map[string]numericRange
+	if err := json.Unmarshal(data, &ranges); err != nil {
+		t.Fatal(err)
+	}
+	if r := ranges["reviews.ratings.Overall"]; r.Count != 2 || r.Min != 2 || r.Max != 5 {
+		t.Errorf("unexpected numeric range %+v", r)
+	}
+}
+
+func TestHarvestReadsDirectories(t *testing.T) {
+	dir := filepath.Dir(writeTempFile(t, "one.json", `{"t": "x"}`))
+	if err := ioutil.WriteFile(filepath.Join(dir, "many.json"), []byte(`[{"t": "y"}, {"t": "z"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "skip.txt"), []byte("not json"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	if err := readDocuments(dir, func(interface{}) { n++ }); err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("expected 3 docs, got %d", n)
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	got := tokenize("It's a GREAT hotel, 10/10 - would stay again!", 2)
+	want := []string{"it", "great", "hotel", "would", "stay", "again"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestParseHarvestArgsErrors(t *testing.T) {
+	if _, err := parseHarvestArgs([]string{"x", harvestCommand, "in.jsonl"}); err != errNoHarvestFields {
+		t.Errorf("expected %v, got %v", errNoHarvestFields, err)
+	}
+	_, err := parseHarvestArgs([]string{"x", harvestCommand, "-f", "a", "--commonMinDf", "2", "in.jsonl"})
+	if err != errInvalidCommonMinDf {
+		t.Errorf("expected %v, got %v", errInvalidCommonMinDf, err)
+	}
+}
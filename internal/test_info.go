@@ -42,6 +42,13 @@ type Spec struct {
 	ClientType ClientType
 
 	Rate *uint64
+
+	// RequestOptions is the JSON object of request members merged into
+	// every generated FTS query, empty if there are none.
+	RequestOptions string
+
+	// Seed is the seed of the generated query streams.
+	Seed int64
 }
 
 // IsTimedTest tells if the test was limited by time.
@@ -86,8 +93,179 @@ type Results struct {
 
 	Errors []ErrorWithCount
 
+	// Latencies are end-to-end, from sending a request the first time
+	// to its final response, retries and backoffs included.
 	Latencies ReadonlyUint64Histogram
 	Requests  ReadonlyFloat64Histogram
+
+	// AttemptLatencies are the latencies of every attempt, the service
+	// latency without the backoffs, it may be nil.
+	AttemptLatencies ReadonlyUint64Histogram
+
+	// Recall holds the recall@k (0.0 - 1.0) of knn queries checked
+	// against ground truth, it may be nil or empty.
+	Recall ReadonlyFloat64Histogram
+
+	// Buckets holds the results per bucket (tenant), only with
+	// --bucketStats.
+	Buckets []BucketResult
+
+	// Nodes holds the results per --target node, empty with a single
+	// node.
+	Nodes []NodeResult
+
+	// Retries holds the retries of the retry policy, it may be nil.
+	Retries *RetryResult
+}
+
+// BucketResult holds the results of the requests to one bucket.
+type BucketResult struct {
+	Name string
+
+	Req1XX, Req2XX, Req3XX, Req4XX, Req5XX uint64
+	Others                                 uint64
+
+	// Retries429 is the number of HTTP 429 responses retried.
+	Retries429 uint64
+
+	// Hits and BytesRead are the sums of total_hits and bytesRead of
+	// the responses.
+	Hits, BytesRead uint64
+
+	Latencies ReadonlyUint64Histogram
+
+	// RU is the increase of meter_ru_total of the bucket during the
+	// test, -1 if it wasn't metered.
+	RU int64
+}
+
+// Requests returns the number of requests to the bucket.
+func (b BucketResult) Requests() uint64 {
+	return b.Req1XX + b.Req2XX + b.Req3XX + b.Req4XX + b.Req5XX + b.Others
+}
+
+// HitsPerRequest returns the mean total_hits per request.
+func (b BucketResult) HitsPerRequest() float64 {
+	if b.Requests() == 0 {
+		return 0
+	}
+	return float64(b.Hits) / float64(b.Requests())
+}
+
+// BytesReadPerRequest returns the mean bytesRead per request.
+func (b BucketResult) BytesReadPerRequest() float64 {
+	if b.Requests() == 0 {
+		return 0
+	}
+	return float64(b.BytesRead) / float64(b.Requests())
+}
+
+// LatenciesStats performs various statistical calculations on the
+// latencies of the bucket.
+func (b BucketResult) LatenciesStats(percentiles []float64) *LatenciesStats {
+	return Results{Latencies: b.Latencies}.LatenciesStats(percentiles)
+}
+
+// NodeResult holds the results of the requests to one target node.
+type NodeResult struct {
+	Name string
+
+	Req1XX, Req2XX, Req3XX, Req4XX, Req5XX uint64
+	Others                                 uint64
+
+	// Retries429 is the number of HTTP 429 responses retried.
+	Retries429 uint64
+
+	// Ejections is the number of times the node was left out after
+	// failing repeatedly.
+	Ejections uint64
+
+	Latencies ReadonlyUint64Histogram
+}
+
+// Requests returns the number of requests to the node.
+func (n NodeResult) Requests() uint64 {
+	return n.Req1XX + n.Req2XX + n.Req3XX + n.Req4XX + n.Req5XX + n.Others
+}
+
+// LatenciesStats performs various statistical calculations on the
+// latencies of the node.
+func (n NodeResult) LatenciesStats(percentiles []float64) *LatenciesStats {
+	return Results{Latencies: n.Latencies}.LatenciesStats(percentiles)
+}
+
+// RetryResult holds the retries of the requests.
+type RetryResult struct {
+	// ByReason is the number of retries per HTTP status ("429") or
+	// transport error class ("timeout").
+	ByReason map[string]uint64
+
+	// Exhausted is the number of requests still failing after the max
+	// attempts.
+	Exhausted uint64
+
+	// Attempts holds the number of requests by the number of times they
+	// were sent.
+	Attempts ReadonlyUint64Histogram
+
+	// Backoffs holds the delays before the retries in microseconds.
+	Backoffs ReadonlyUint64Histogram
+}
+
+// RetryCount is the number of retries for a reason.
+type RetryCount struct {
+	Reason string
+	Count  uint64
+}
+
+// AttemptsCount is the number of requests sent Attempts times.
+type AttemptsCount struct {
+	Attempts, Count uint64
+}
+
+// Total returns the number of retries.
+func (r RetryResult) Total() uint64 {
+	total := uint64(0)
+	for _, n := range r.ByReason {
+		total += n
+	}
+	return total
+}
+
+// Reasons returns the retry counts sorted by reason.
+func (r RetryResult) Reasons() []RetryCount {
+	res := make([]RetryCount, 0, len(r.ByReason))
+	for reason, n := range r.ByReason {
+		res = append(res, RetryCount{reason, n})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Reason < res[j].Reason })
+	return res
+}
+
+// AttemptsCounts returns the number of requests by attempts, fewest
+// attempts first.
+func (r RetryResult) AttemptsCounts() []AttemptsCount {
+	res := []AttemptsCount{}
+	if r.Attempts == nil {
+		return res
+	}
+	r.Attempts.VisitAll(func(attempts, n uint64) bool {
+		if n > 0 {
+			res = append(res, AttemptsCount{attempts, n})
+		}
+		return true
+	})
+	sort.Slice(res, func(i, j int) bool { return res[i].Attempts < res[j].Attempts })
+	return res
+}
+
+// BackoffsStats performs various statistical calculations on the delays
+// before the retries.
+func (r RetryResult) BackoffsStats(percentiles []float64) *LatenciesStats {
+	if r.Backoffs == nil {
+		return nil
+	}
+	return Results{Latencies: r.Backoffs}.LatenciesStats(percentiles)
 }
 
 // ReadonlyUint64Histogram is a readonly histogram with uint64 keys
@@ -188,6 +366,15 @@ func (r Results) LatenciesStats(percentiles []float64) *LatenciesStats {
 	}
 }
 
+// AttemptLatenciesStats performs various statistical calculations on
+// the latencies of the attempts.
+func (r Results) AttemptLatenciesStats(percentiles []float64) *LatenciesStats {
+	if r.AttemptLatencies == nil {
+		return nil
+	}
+	return Results{Latencies: r.AttemptLatencies}.LatenciesStats(percentiles)
+}
+
 // RequestsStats contains statistical information about requests.
 type RequestsStats struct {
 	// These are in requests per second.
@@ -278,6 +465,78 @@ func (r Results) RequestsStats(percentiles []float64) *RequestsStats {
 	}
 }
 
+// RecallStats contains statistical information about the recall of
+// knn queries.
+type RecallStats struct {
+	// These are fractions in [0, 1]
+	Mean float64
+	Min  float64
+	Max  float64
+
+	Count uint64
+
+	// This is  map[0.0 <= p <= 1.0 (percentile)]recall
+	Percentiles map[float64]float64
+}
+
+// RecallStats performs various statistical calculations on recall,
+// nil if no query was checked against ground truth.
+func (r Results) RecallStats(percentiles []float64) *RecallStats {
+	h := r.Recall
+	if h == nil || h.Count() == 0 {
+		return nil
+	}
+	sum := float64(0)
+	count := uint64(0)
+	min, max := math.Inf(1), math.Inf(-1)
+	pairs := make([]struct {
+		k float64
+		v uint64
+	}, 0, h.Count())
+
+	h.VisitAll(func(f float64, c uint64) bool {
+		min = math.Min(min, f)
+		max = math.Max(max, f)
+		sum += f * float64(c)
+		count += c
+		pairs = append(pairs, struct {
+			k float64
+			v uint64
+		}{f, c})
+		return true
+	})
+	if count < 1 {
+		return nil
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].k < pairs[j].k
+	})
+	percentilesMap := map[float64]float64{}
+	for _, pc := range percentiles {
+		if pc < 0 || pc > 1 {
+			continue
+		}
+		rank := uint64(pc*float64(count) + 0.5)
+		total := uint64(0)
+		for _, p := range pairs {
+			total += p.v
+			if total >= rank {
+				percentilesMap[pc] = p.k
+				break
+			}
+		}
+	}
+	return &RecallStats{
+		Mean:  sum / float64(count),
+		Min:   min,
+		Max:   max,
+		Count: count,
+
+		Percentiles: percentilesMap,
+	}
+}
+
 // ErrorWithCount contains error description alongside with number of
 // times this error occurred.
 type ErrorWithCount struct {
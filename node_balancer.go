@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+
+	"cb_fts_bench/internal"
+)
+
+// With --target the requests are spread over several search nodes, the
+// scheme, path and query of the URL stay the same and only its host:port
+// is replaced by the node the balancer picks. A node failing (transport
+// error or 5xx) --ejectAfter times in a row is left out for --ejectFor.
+
+const (
+	balanceRoundRobin       = "round-robin"
+	balanceRandom           = "random"
+	balanceLeastOutstanding = "least-outstanding"
+	defaultEjectAfter       = 5
+	defaultEjectFor         = 10 * time.Second
+)
+
+var balanceModes = []string{balanceRoundRobin, balanceRandom, balanceLeastOutstanding}
+
+type targetNode struct {
+	addr string
+
+	// outstanding is the number of requests in flight
+	outstanding int64
+	// fails is the number of failures in a row
+	fails uint32
+	// ejectedUntil is the UnixNano the node is left out until
+	ejectedUntil int64
+	ejections    uint64
+
+	stats *bucketStats
+}
+
+type nodeBalancer struct {
+	nodes []*targetNode
+	mode  string
+	rr    uint64
+
+	ejectAfter uint32
+	ejectFor   time.Duration
+
+	// mu serializes the ejection messages
+	mu sync.Mutex
+}
+
+// loadTargetsFile reads host[:port] lines, blank lines and # comments are
+// skipped.
+func loadTargetsFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var targets []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		targets = append(targets, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("targets %s: no hosts", path)
+	}
+	return targets, nil
+}
+
+// splitTargets splits the comma separated hosts of the --target flags.
+func splitTargets(flags []string) []string {
+	var targets []string
+	for _, f := range flags {
+		for _, t := range strings.Split(f, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				targets = append(targets, t)
+			}
+		}
+	}
+	return targets
+}
+
+// newNodeBalancer makes a balancer over targets, a target without a port
+// gets the one of rawURL.
+func newNodeBalancer(targets []string, rawURL, mode string, ejectAfter int, ejectFor time.Duration) (*nodeBalancer, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	nb := &nodeBalancer{mode: mode, ejectAfter: uint32(ejectAfter), ejectFor: ejectFor}
+	seen := map[string]bool{}
+	for _, t := range targets {
+		addr := t
+		if _, _, err := net.SplitHostPort(t); err != nil {
+			addr = net.JoinHostPort(strings.Trim(t, "[]"), port)
+		}
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil || host == "" || strings.Contains(addr, "/") {
+			return nil, fmt.Errorf("%v: %q", errInvalidTarget, t)
+		}
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		nb.nodes = append(nb.nodes, &targetNode{addr: addr, stats: &bucketStats{latencies: uhist.Default()}})
+	}
+	if len(nb.nodes) == 0 {
+		return nil, errInvalidTarget
+	}
+	return nb, nil
+}
+
+func (n *targetNode) ejected(now int64) bool {
+	return now < atomic.LoadInt64(&n.ejectedUntil)
+}
+
+// pick returns the node of the next request, the ejected nodes only get
+// requests if all of them are.
+func (nb *nodeBalancer) pick(r randGen) *targetNode {
+	now := time.Now().UnixNano()
+	nodes := nb.nodes
+	healthy := make([]*targetNode, 0, len(nodes))
+	for _, n := range nodes {
+		if !n.ejected(now) {
+			healthy = append(healthy, n)
+		}
+	}
+	if len(healthy) > 0 {
+		nodes = healthy
+	}
+	switch nb.mode {
+	case balanceRandom:
+		return nodes[r.Intn(len(nodes))]
+	case balanceLeastOutstanding:
+		// ties go round-robin so that idle nodes share the load
+		start := int(atomic.AddUint64(&nb.rr, 1) % uint64(len(nodes)))
+		best := nodes[start]
+		for i := 1; i < len(nodes); i++ {
+			n := nodes[(start+i)%len(nodes)]
+			if atomic.LoadInt64(&n.outstanding) < atomic.LoadInt64(&best.outstanding) {
+				best = n
+			}
+		}
+		return best
+	default:
+		return nodes[(atomic.AddUint64(&nb.rr, 1)-1)%uint64(len(nodes))]
+	}
+}
+
+// acquire counts a request sent to n.
+func (nb *nodeBalancer) acquire(n *targetNode) {
+	atomic.AddInt64(&n.outstanding, 1)
+}
+
+// release counts the response of n, code is -1 for a transport error,
+// and ejects n after ejectAfter failures in a row.
+func (nb *nodeBalancer) release(n *targetNode, code int) {
+	atomic.AddInt64(&n.outstanding, -1)
+	if code >= 0 && code < 500 {
+		atomic.StoreUint32(&n.fails, 0)
+		return
+	}
+	if nb.ejectAfter == 0 || atomic.AddUint32(&n.fails, 1) < nb.ejectAfter {
+		return
+	}
+	atomic.StoreUint32(&n.fails, 0)
+	atomic.StoreInt64(&n.ejectedUntil, time.Now().Add(nb.ejectFor).UnixNano())
+	atomic.AddUint64(&n.ejections, 1)
+	nb.mu.Lock()
+	fmt.Printf("node %s ejected for %v after %d failures in a row\n", n.addr, nb.ejectFor, nb.ejectAfter)
+	nb.mu.Unlock()
+}
+
+func (nb *nodeBalancer) describe() string {
+	addrs := make([]string, len(nb.nodes))
+	for i, n := range nb.nodes {
+		addrs[i] = n.addr
+	}
+	eject := "never ejected"
+	if nb.ejectAfter > 0 {
+		eject = fmt.Sprintf("ejected for %v after %d failures in a row", nb.ejectFor, nb.ejectAfter)
+	}
+	return fmt.Sprintf("%d target node(s) %s, %s balancing, %s", len(nb.nodes), strings.Join(addrs, " "), nb.mode, eject)
+}
+
+// results returns the per node results sorted by address.
+func (nb *nodeBalancer) results() []internal.NodeResult {
+	res := make([]internal.NodeResult, 0, len(nb.nodes))
+	for _, n := range nb.nodes {
+		ns := n.stats
+		res = append(res, internal.NodeResult{
+			Name:       n.addr,
+			Req1XX:     atomic.LoadUint64(&ns.req1xx),
+			Req2XX:     atomic.LoadUint64(&ns.req2xx),
+			Req3XX:     atomic.LoadUint64(&ns.req3xx),
+			Req4XX:     atomic.LoadUint64(&ns.req4xx),
+			Req5XX:     atomic.LoadUint64(&ns.req5xx),
+			Others:     atomic.LoadUint64(&ns.others),
+			Retries429: atomic.LoadUint64(&ns.retryReq429),
+			Ejections:  atomic.LoadUint64(&n.ejections),
+			Latencies:  ns.latencies,
+		})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
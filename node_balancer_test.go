@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewNodeBalancer(t *testing.T) {
+	nb, err := newNodeBalancer(
+		[]string{"node1", "node2:9000", "node1:8094", "[::1]", "10.0.0.3"},
+		"http://localhost:8094/api/index/ix/query", balanceRoundRobin, 3, time.Second,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"node1:8094", "node2:9000", "[::1]:8094", "10.0.0.3:8094"}
+	if len(nb.nodes) != len(expected) {
+		t.Fatalf("expected %v, got %d nodes", expected, len(nb.nodes))
+	}
+	for i, n := range nb.nodes {
+		if n.addr != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], n.addr)
+		}
+	}
+
+	nb, err = newNodeBalancer([]string{"node1"}, "https://localhost/query", balanceRandom, 0, 0)
+	if err != nil || nb.nodes[0].addr != "node1:443" {
+		t.Errorf("expected node1:443, got %v %v", nb, err)
+	}
+	for _, bad := range [][]string{{}, {":8094"}, {"node1/api"}} {
+		if _, err := newNodeBalancer(bad, "http://localhost:8094", balanceRoundRobin, 0, 0); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+}
+
+func TestLoadTargetsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nodes.txt")
+	if err := os.WriteFile(path, []byte("# search nodes\nnode1:8094\n\n  node2  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	targets, err := loadTargetsFile(path)
+	if err != nil || strings.Join(targets, " ") != "node1:8094 node2" {
+		t.Errorf("unexpected targets %q %v", targets, err)
+	}
+	if err := os.WriteFile(path, []byte("# none\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadTargetsFile(path); err == nil {
+		t.Error("expected an error for a file without hosts")
+	}
+	if got := splitTargets([]string{"a, b", "c,", ""}); strings.Join(got, " ") != "a b c" {
+		t.Errorf("unexpected split %q", got)
+	}
+}
+
+func TestNodeBalancerPick(t *testing.T) {
+	nb, _ := newNodeBalancer([]string{"a", "b", "c"}, "http://localhost:8094", balanceRoundRobin, 2, time.Minute)
+	r := rand.New(rand.NewSource(1))
+	counts := map[string]int{}
+	for i := 0; i < 30; i++ {
+		counts[nb.pick(r).addr]++
+	}
+	for _, n := range nb.nodes {
+		if counts[n.addr] != 10 {
+			t.Errorf("round-robin: expected 10 requests to each node, got %v", counts)
+		}
+	}
+
+	// b fails twice in a row and is left out
+	b := nb.nodes[1]
+	nb.acquire(b)
+	nb.release(b, 503)
+	nb.acquire(b)
+	nb.release(b, -1)
+	if b.ejections != 1 || b.outstanding != 0 {
+		t.Fatalf("expected b ejected once, got %d ejections, %d outstanding", b.ejections, b.outstanding)
+	}
+	for i := 0; i < 10; i++ {
+		if n := nb.pick(r); n == b {
+			t.Fatal("expected no requests to the ejected node")
+		}
+	}
+	// a success in between resets the failures
+	a := nb.nodes[0]
+	nb.release(a, 500)
+	nb.release(a, 200)
+	nb.release(a, 500)
+	if a.ejections != 0 {
+		t.Error("expected a not ejected")
+	}
+	// with all nodes ejected they all get requests again
+	for _, n := range nb.nodes {
+		n.ejectedUntil = time.Now().Add(time.Minute).UnixNano()
+	}
+	if n := nb.pick(r); n == nil {
+		t.Error("expected a node with all nodes ejected")
+	}
+
+	nb, _ = newNodeBalancer([]string{"a", "b", "c"}, "http://localhost:8094", balanceLeastOutstanding, 0, 0)
+	nb.nodes[0].outstanding, nb.nodes[1].outstanding, nb.nodes[2].outstanding = 4, 1, 3
+	for i := 0; i < 5; i++ {
+		if n := nb.pick(r); n != nb.nodes[1] {
+			t.Errorf("least-outstanding: expected b, got %s", n.addr)
+		}
+	}
+
+	nb, _ = newNodeBalancer([]string{"a", "b", "c"}, "http://localhost:8094", balanceRandom, 0, 0)
+	counts = map[string]int{}
+	for i := 0; i < 300; i++ {
+		counts[nb.pick(r).addr]++
+	}
+	if len(counts) != 3 {
+		t.Errorf("random: expected requests to all nodes, got %v", counts)
+	}
+}
+
+func TestBombardierTargets(t *testing.T) {
+	testAllClients(t, testBombardierTargets)
+}
+
+func testBombardierTargets(clientType clientTyp, t *testing.T) {
+	handler := func(code int) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(code)
+		}))
+	}
+	ok1, ok2, failing := handler(http.StatusOK), handler(http.StatusOK), handler(http.StatusServiceUnavailable)
+	defer ok1.Close()
+	defer ok2.Close()
+	defer failing.Close()
+	addr := func(s *httptest.Server) string { return strings.TrimPrefix(s.URL, "http://") }
+
+	numReqs := uint64(60)
+	conf := config{
+		numConns:    2,
+		numReqs:     &numReqs,
+		url:         "http://localhost:1/api/index/ix/query",
+		headers:     new(headersList),
+		timeout:     defaultTimeout,
+		method:      "GET",
+		clientType:  clientType,
+		format:      knownFormat("json"),
+		printResult: true,
+		targets:     []string{addr(ok1), addr(ok2), addr(failing)},
+		balance:     balanceRoundRobin,
+		ejectAfter:  2,
+		ejectFor:    time.Minute,
+	}
+	b, err := newBombardier(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.disableOutput()
+	b.bombard()
+	out := new(bytes.Buffer)
+	b.redirectOutputTo(out)
+	b.printStats()
+
+	var info struct {
+		Result struct {
+			Nodes []struct {
+				Name      string
+				Requests  uint64
+				Req2xx    uint64
+				Req5xx    uint64
+				Ejections uint64
+				Latency   *struct{ Mean float64 }
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("%v in %s", err, out)
+	}
+	if len(info.Result.Nodes) != 3 {
+		t.Fatalf("expected 3 nodes, got %s", out)
+	}
+	total := uint64(0)
+	for _, n := range info.Result.Nodes {
+		total += n.Requests
+		if n.Latency == nil {
+			t.Errorf("%s: expected latencies in %s", n.Name, out)
+		}
+		if n.Name == addr(failing) {
+			if n.Ejections != 1 || n.Req5xx != n.Requests || n.Requests > 2+conf.numConns {
+				t.Errorf("expected the failing node ejected early, got %s", out)
+			}
+		} else if n.Req2xx != n.Requests || n.Requests < 20 {
+			t.Errorf("expected the healthy nodes to take the load, got %s", out)
+		}
+	}
+	if total != numReqs {
+		t.Errorf("expected %d requests over the nodes, got %s", numReqs, out)
+	}
+}
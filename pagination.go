@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"cb_fts_bench/internal"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+	"github.com/tidwall/gjson"
+)
+
+// Deep pagination: with --pages N each logical search walks N pages of
+// the generated query, the overall latency is recorded as usual and
+// each page gets its own latency, bytesRead and hits stats.
+
+const (
+	pageModeFrom         = "from"
+	pageModeSearchAfter  = "search_after"
+	pageModeSearchBefore = "search_before"
+
+	defaultPageSort = `["-_score", "_id"]`
+	defaultPageSize = 10
+)
+
+var pageModes = []string{pageModeFrom, pageModeSearchAfter, pageModeSearchBefore}
+
+type pageStats struct {
+	latencies *uhist.Histogram
+	count     uint64
+	bytesRead uint64
+	hits      uint64
+	// the responses by status class, others are the rest and the errors
+	req2xx, req4xx, req5xx, others uint64
+}
+
+func newPageStats(pages int) []*pageStats {
+	ps := make([]*pageStats, pages)
+	for i := range ps {
+		ps[i] = &pageStats{latencies: uhist.Default()}
+	}
+	return ps
+}
+
+// record counts a page response with code (-1 for an error), the hits
+// and bytesRead of 200 ones.
+func (ps *pageStats) record(code int, usTaken uint64, body []byte) {
+	ps.latencies.Increment(usTaken)
+	atomic.AddUint64(&ps.count, 1)
+	var counter *uint64
+	switch code / 100 {
+	case 2:
+		counter = &ps.req2xx
+	case 4:
+		counter = &ps.req4xx
+	case 5:
+		counter = &ps.req5xx
+	default:
+		counter = &ps.others
+	}
+	atomic.AddUint64(counter, 1)
+	if code == 200 {
+		atomic.AddUint64(&ps.bytesRead, uint64(gjson.GetBytes(body, "bytesRead").Int()))
+		atomic.AddUint64(&ps.hits, uint64(gjson.GetBytes(body, "hits.#").Int()))
+	}
+}
+
+// printPageStats prints the per page stats of a --pages run.
+func printPageStats(conf config, pages []*pageStats) {
+	fmt.Printf("  Pages (--pageMode %s, each search walks up to %d pages)\n", conf.pageMode, conf.pages)
+	for i, ps := range pages {
+		if ps.count == 0 {
+			fmt.Printf("    page %3d: no responses\n", i+1)
+			continue
+		}
+		ls := internal.Results{Latencies: ps.latencies}.LatenciesStats([]float64{0.5, 0.99})
+		fmt.Printf("    page %3d: %9d resps, 2xx %9d, 4xx %7d, 5xx %7d, others %7d, latency avg %10s p50 %10s p99 %10s, bytesRead/resp %12.3f, hits/resp %8.3f\n",
+			i+1, ps.count, ps.req2xx, ps.req4xx, ps.req5xx, ps.others,
+			formatTimeUs(ls.Mean), formatTimeUs(float64(ls.Percentiles[0.5])), formatTimeUs(float64(ls.Percentiles[0.99])),
+			float64(ps.bytesRead)/float64(ps.count), float64(ps.hits)/float64(ps.count))
+	}
+}
+
+// pageSize is the "size" of a request body, FTS defaults to 10.
+func pageSize(body string) int {
+	if s := gjson.Get(body, "size"); s.Exists() && s.Int() > 0 {
+		return int(s.Int())
+	}
+	return defaultPageSize
+}
+
+// pageSortExtra returns the sort search_after and search_before depend
+// on, nothing if the body has its own sort.
+func pageSortExtra(conf config, body string) string {
+	if conf.pageMode == pageModeFrom || gjson.Get(body, "sort").Exists() {
+		return ""
+	}
+	return ", \"sort\": " + conf.pageSort
+}
+
+// firstPageExtra returns the members selecting the first page, as
+// search_before walks back to the start it begins at the last page.
+func firstPageExtra(conf config, body string) string {
+	if conf.pageMode != pageModeSearchBefore {
+		return ""
+	}
+	return fmt.Sprintf(", \"from\": %d", (conf.pages-1)*pageSize(body))
+}
+
+// nextPageExtra returns the members selecting page (0 based) given the
+// response for the page before, false if there are no more pages.
+func nextPageExtra(conf config, page, size int, prev []byte) (string, bool) {
+	hits := gjson.GetBytes(prev, "hits")
+	n := len(hits.Array())
+	switch conf.pageMode {
+	case pageModeSearchAfter:
+		if n < size {
+			return "", false
+		}
+		keys := gjson.GetBytes(prev, fmt.Sprintf("hits.%d.sort", n-1))
+		if !keys.IsArray() {
+			return "", false
+		}
+		return ", \"search_after\": " + keys.Raw, true
+	case pageModeSearchBefore:
+		if n == 0 {
+			return "", false
+		}
+		keys := gjson.GetBytes(prev, "hits.0.sort")
+		if !keys.IsArray() {
+			return "", false
+		}
+		return ", \"search_before\": " + keys.Raw, true
+	}
+	if n < size {
+		return "", false
+	}
+	return fmt.Sprintf(", \"from\": %d", page*size), true
+}
+
+// crawlPages fetches the pages after the first one with x, the same way
+// as the first one (--target node, retries), pageBody of first returns
+// the request body for the members selecting a page. It stops early at
+// the end of the results or on any error.
+func (b *bombardier) crawlPages(conf config, rb *requestBuilder, x exchanger, first *preparedRequest, firstBody []byte, firstUs uint64, bs *bucketStats) {
+	b.pages[0].record(200, firstUs, firstBody)
+	size := pageSize(first.body)
+
+	p := *first
+	prev, release := firstBody, func() {}
+	defer func() { release() }()
+	for page := 1; page < conf.pages; page++ {
+		extra, more := nextPageExtra(conf, page, size, prev)
+		release()
+		release = func() {}
+		if !more {
+			return
+		}
+		p.body = first.pageBody(extra)
+		if conf.trace {
+			str, _ := formatJSON([]byte(p.body))
+			fmt.Printf("PAGE %d REQ\n%s\n", page+1, str)
+		}
+		resp, usTaken, err, perr := b.exchangeRetrying(rb, x, conf, &p, bs)
+		if perr != nil {
+			return
+		}
+		release = resp.release
+		if err != nil {
+			b.pages[page].record(-1, usTaken, nil)
+			return
+		}
+		b.pages[page].record(resp.code, usTaken, resp.body)
+		if resp.code != 200 {
+			return
+		}
+		prev = resp.body
+	}
+}
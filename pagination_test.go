@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNextPageExtra(t *testing.T) {
+	full := []byte(`{"hits": [{"id": "a", "sort": ["2", "a"]}, {"id": "b", "sort": ["1", "b"]}]}`)
+	short := []byte(`{"hits": [{"id": "a", "sort": ["2", "a"]}]}`)
+	expectations := []struct {
+		mode  string
+		prev  []byte
+		extra string
+		more  bool
+	}{
+		{pageModeFrom, full, `, "from": 6`, true},
+		{pageModeFrom, short, "", false},
+		{pageModeSearchAfter, full, `, "search_after": ["1", "b"]`, true},
+		{pageModeSearchAfter, short, "", false},
+		{pageModeSearchBefore, short, `, "search_before": ["2", "a"]`, true},
+		{pageModeSearchBefore, []byte(`{"hits": []}`), "", false},
+	}
+	for _, e := range expectations {
+		extra, more := nextPageExtra(config{pageMode: e.mode}, 3, 2, e.prev)
+		if extra != e.extra || more != e.more {
+			t.Errorf("%s: expected %q %v, got %q %v", e.mode, e.extra, e.more, extra, more)
+		}
+	}
+
+	conf := config{pageMode: pageModeSearchBefore, pages: 4, pageSort: defaultPageSort}
+	if got := firstPageExtra(conf, `{"query": {}, "size": 25}`); got != `, "from": 75` {
+		t.Errorf("unexpected first page %q", got)
+	}
+	if got := pageSortExtra(conf, `{"query": {}, "sort": ["_id"]}`); got != "" {
+		t.Errorf("expected the query's own sort to be kept, got %q", got)
+	}
+	if got := pageSortExtra(conf, `{"query": {}}`); got != `, "sort": `+defaultPageSort {
+		t.Errorf("unexpected sort %q", got)
+	}
+	if got := pageSortExtra(conf, `{"query": {"match": "sort"}}`); got != `, "sort": `+defaultPageSort {
+		t.Errorf("expected the sort added for a query matching \"sort\", got %q", got)
+	}
+}
+
+// pagedServer serves 25 hits sorted by id, 10 per page, for from,
+// search_after and search_before requests.
+func pagedServer(t *testing.T) *httptest.Server {
+	var ids []string
+	for i := 0; i < 25; i++ {
+		ids = append(ids, fmt.Sprintf("doc_%02d", i))
+	}
+	return httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			var req struct {
+				From         int      `json:"from"`
+				Size         int      `json:"size"`
+				SearchAfter  []string `json:"search_after"`
+				SearchBefore []string `json:"search_before"`
+			}
+			if err := json.Unmarshal(body, &req); err != nil {
+				t.Errorf("%v in %s", err, body)
+				return
+			}
+			start, end := req.From, req.From+req.Size
+			if req.SearchAfter != nil {
+				for i, id := range ids {
+					if id == req.SearchAfter[0] {
+						start, end = i+1, i+1+req.Size
+					}
+				}
+			}
+			if req.SearchBefore != nil {
+				for i, id := range ids {
+					if id == req.SearchBefore[0] {
+						start, end = i-req.Size, i
+					}
+				}
+			}
+			if start < 0 {
+				start = 0
+			}
+			if end > len(ids) {
+				end = len(ids)
+			}
+			if start > end {
+				start = end
+			}
+			var hits []string
+			for _, id := range ids[start:end] {
+				hits = append(hits, fmt.Sprintf(`{"id": "%s", "sort": ["%s"]}`, id, id))
+			}
+			fmt.Fprintf(rw, `{"status": {"total": 1, "successful": 1}, "total_hits": 25, "bytesRead": 100, "hits": [%s]}`,
+				strings.Join(hits, ", "))
+		}),
+	)
+}
+
+func TestBombardierWalksPages(t *testing.T) {
+	testAllClients(t, testBombardierWalksPages)
+}
+
+func testBombardierWalksPages(clientType clientTyp, t *testing.T) {
+	s := pagedServer(t)
+	defer s.Close()
+
+	expectations := []struct {
+		mode string
+		hits []uint64
+	}{
+		{pageModeFrom, []uint64{10, 10, 5, 0}},
+		{pageModeSearchAfter, []uint64{10, 10, 5, 0}},
+		// starts at from 30 which is past the end
+		{pageModeSearchBefore, []uint64{0, 0, 0, 0}},
+	}
+	for _, e := range expectations {
+		numReqs := uint64(4)
+		conf := config{
+			numConns:   1,
+			numReqs:    &numReqs,
+			url:        s.URL,
+			headers:    new(headersList),
+			timeout:    defaultTimeout,
+			method:     "POST",
+			body:       `{` + fts_query_pat + `, "size": 10}`,
+			clientType: clientType,
+			format:     knownFormat("plain-text"),
+			pages:      4,
+			pageMode:   e.mode,
+			pageSort:   `["_id"]`,
+		}
+		setBuiltinFtsData(&conf)
+		b, err := newBombardier(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.disableOutput()
+		b.bombard()
+		for i, ps := range b.pages {
+			if ps.hits != e.hits[i]*numReqs {
+				t.Errorf("%s: page %d: expected %d hits, got %d", e.mode, i+1, e.hits[i]*numReqs, ps.hits)
+			}
+			if ps.hits > 0 && (ps.count != numReqs || ps.bytesRead != 100*numReqs) {
+				t.Errorf("%s: page %d: unexpected stats %d resps, %d bytesRead", e.mode, i+1, ps.count, ps.bytesRead)
+			}
+		}
+	}
+
+	// 3 pages backwards from the last one, doc_20 to doc_24, then
+	// doc_10 to doc_19 and doc_00 to doc_09
+	numReqs := uint64(2)
+	conf := config{
+		numConns: 1, numReqs: &numReqs, url: s.URL, headers: new(headersList), timeout: defaultTimeout,
+		method: "POST", body: `{` + fts_query_pat + `, "size": 10}`, clientType: clientType, format: knownFormat("plain-text"),
+		pages: 3, pageMode: pageModeSearchBefore, pageSort: `["_id"]`,
+	}
+	setBuiltinFtsData(&conf)
+	b, err := newBombardier(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.disableOutput()
+	b.bombard()
+	for i, want := range []uint64{5, 10, 10} {
+		if b.pages[i].hits != want*numReqs {
+			t.Errorf("search_before: page %d: expected %d hits, got %d", i+1, want*numReqs, b.pages[i].hits)
+		}
+	}
+}
+
+func TestBombardierPagesRetryAndEject(t *testing.T) {
+	testAllClients(t, testBombardierPagesRetryAndEject)
+}
+
+func testBombardierPagesRetryAndEject(clientType clientTyp, t *testing.T) {
+	paged := pagedServer(t)
+	defer paged.Close()
+	// the pages after the first one get a 429 on every other attempt or
+	// always the pageCode
+	var pageCode, n int64
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		code := atomic.LoadInt64(&pageCode)
+		if strings.Contains(string(body), "search_after") && (atomic.AddInt64(&n, 1)%2 == 1 || code != http.StatusTooManyRequests) {
+			rw.WriteHeader(int(code))
+			return
+		}
+		resp, err := http.Post(paged.URL, "application/json", strings.NewReader(string(body)))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer resp.Body.Close()
+		out, _ := ioutil.ReadAll(resp.Body)
+		rw.Write(out)
+	}))
+	defer s.Close()
+
+	run := func(code int64) *bombardier {
+		atomic.StoreInt64(&pageCode, code)
+		atomic.StoreInt64(&n, 0)
+		retry, err := newRetryPolicy("429", retryPolicy{minBackoff: time.Millisecond, maxBackoff: time.Millisecond, multiplier: 1, maxAttempts: 3})
+		if err != nil {
+			t.Fatal(err)
+		}
+		numReqs := uint64(3)
+		conf := config{
+			numConns: 1, numReqs: &numReqs, url: s.URL, headers: new(headersList), timeout: defaultTimeout,
+			method: "POST", body: `{` + fts_query_pat + `, "size": 10}`, clientType: clientType, format: knownFormat("plain-text"),
+			pages: 3, pageMode: pageModeSearchAfter, pageSort: `["_id"]`, retry: retry,
+			targets: []string{strings.TrimPrefix(s.URL, "http://")}, balance: balanceLeastOutstanding, ejectAfter: 1, ejectFor: time.Minute,
+		}
+		setBuiltinFtsData(&conf)
+		b, err := newBombardier(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.disableOutput()
+		b.bombard()
+		if node := b.nodes.nodes[0]; node.outstanding != 0 {
+			t.Errorf("%d: expected no outstanding requests, got %d", code, node.outstanding)
+		}
+		return b
+	}
+
+	// a 429 on a page is retried
+	b := run(http.StatusTooManyRequests)
+	for i, want := range []uint64{10, 10, 5} {
+		if ps := b.pages[i]; ps.req2xx != 3 || ps.hits != want*3 {
+			t.Errorf("429: page %d: expected 3 2xx and %d hits, got %d and %d", i+1, want*3, ps.req2xx, ps.hits)
+		}
+	}
+	if b.retryReq429 != 6 {
+		t.Errorf("expected 6 retries, got %d", b.retryReq429)
+	}
+
+	// a 500 on a page ends the walk, is counted and ejects the node
+	b = run(http.StatusInternalServerError)
+	if ps := b.pages[1]; ps.count != 3 || ps.req5xx != 3 || ps.hits != 0 {
+		t.Errorf("500: expected 3 5xx on page 2, got %d resps, %d 5xx", ps.count, ps.req5xx)
+	}
+	if b.pages[2].count != 0 {
+		t.Errorf("500: expected no page 3, got %d resps", b.pages[2].count)
+	}
+	if b.nodes.nodes[0].ejections == 0 {
+		t.Error("500: expected the node ejected")
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// Phrase and term pattern (prefix, wildcard, regexp) queries. Pattern
+// queries are not analyzed so all terms are lower cased, the knobs
+// below make them expand to more terms of the index and get slower.
+
+func jsonString(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
+
+// randomWordMinLen picks a lower cased random word of at least minLen
+// chars, checkWords makes sure there is one.
+func randomWordMinLen(r randGen, words []string, minLen int) string {
+	for {
+		w := strings.ToLower(words[r.Intn(len(words))])
+		if utf8.RuneCountInString(w) >= minLen {
+			return w
+		}
+	}
+}
+
+func checkWordMinLen(conf config, words string, minLen int) error {
+	if minLen < 1 {
+		return errors.New("minLen must be > 0")
+	}
+	for _, w := range conf.wordList(words) {
+		if utf8.RuneCountInString(w) >= minLen {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s has no word with at least %d chars", words, minLen)
+}
+
+// phraseQueryGen emits a "match_phrase" (or with exact an exact "phrase"
+// of terms) of minTerms to maxTerms consecutive words, taken from the
+// order of the words list or, if set, from a random line of the corpus
+// word list, e.g. review sentences loaded via --wordList corpus=path.
+type phraseQueryGen struct {
+	field              string
+	words              string
+	corpus             string
+	minTerms, maxTerms int
+	exact              bool
+	miss               float64
+}
+
+func (g phraseQueryGen) ngram(conf config, n int) []string {
+	if g.corpus != "" {
+		lines := conf.wordList(g.corpus)
+		for try := 0; try < 100; try++ {
+			tokens := tokenize(lines[conf.rnd().Intn(len(lines))], 1)
+			if len(tokens) >= n {
+				start := conf.rnd().Intn(len(tokens) - n + 1)
+				return tokens[start : start+n]
+			}
+		}
+	}
+	words := conf.wordList(g.words)
+	if n > len(words) {
+		n = len(words)
+	}
+	start := conf.rnd().Intn(len(words) - n + 1)
+	gram := make([]string, n)
+	for i, w := range words[start : start+n] {
+		gram[i] = strings.ToLower(w)
+	}
+	return gram
+}
+
+func (g phraseQueryGen) generate(conf config) string {
+	gram := g.ngram(conf, randIntFromRange(conf.rnd(), g.minTerms, g.maxTerms))
+	if g.exact {
+		terms := make([]string, len(gram))
+		for i, t := range gram {
+			terms[i] = jsonString(t)
+		}
+		return fmt.Sprintf("\"query\": { \"terms\": [%s], \"field\": \"%s\" }", strings.Join(terms, ", "), g.field)
+	}
+	return fmt.Sprintf("\"query\": { \"match_phrase\": %s, \"field\": \"%s\" }", jsonString(strings.Join(gram, " ")), g.field)
+}
+
+func (g phraseQueryGen) describe() string {
+	kind := "match_phrase"
+	if g.exact {
+		kind = "exact phrase (terms)"
+	}
+	source := fmt.Sprintf("consecutive words of %s in list order", g.words)
+	if g.corpus != "" {
+		source = fmt.Sprintf("consecutive words of a random line of %s", g.corpus)
+	}
+	return fmt.Sprintf("take %d to %d %s as a %s on %s", g.minTerms, g.maxTerms, source, kind, g.field)
+}
+
+func (g phraseQueryGen) missRate() float64 { return g.miss }
+
+func (g phraseQueryGen) withParams(conf config, p *queryParams) (queryGenerator, error) {
+	g.field = p.string("field", g.field)
+	g.words = p.words(conf, "words", g.words)
+	g.corpus = p.words(conf, "corpus", g.corpus)
+	g.minTerms = p.int("minTerms", g.minTerms)
+	g.maxTerms = p.int("maxTerms", g.maxTerms)
+	g.exact = p.bool("exact", g.exact)
+	if g.minTerms < 1 || g.maxTerms < g.minTerms {
+		return nil, errors.New("need 0 < minTerms <= maxTerms")
+	}
+	return g, nil
+}
+
+// prefixQueryGen emits a "prefix" query of the first minLen to maxLen
+// chars of a random word, the shorter the prefix the more terms match.
+type prefixQueryGen struct {
+	field          string
+	words          string
+	minLen, maxLen int
+	miss           float64
+}
+
+func (g prefixQueryGen) generate(conf config) string {
+	w := []rune(randomWordMinLen(conf.rnd(), conf.wordList(g.words), g.minLen))
+	n := randIntFromRange(conf.rnd(), g.minLen, g.maxLen)
+	if n > len(w) {
+		n = len(w)
+	}
+	return fmt.Sprintf("\"query\": { \"prefix\": %s, \"field\": \"%s\" }", jsonString(string(w[:n])), g.field)
+}
+
+func (g prefixQueryGen) describe() string {
+	return fmt.Sprintf("select one random word from %s and use its first %d to %d chars as a prefix on %s",
+		g.words, g.minLen, g.maxLen, g.field)
+}
+
+func (g prefixQueryGen) missRate() float64 { return g.miss }
+
+func (g prefixQueryGen) withParams(conf config, p *queryParams) (queryGenerator, error) {
+	g.field = p.string("field", g.field)
+	g.words = p.words(conf, "words", g.words)
+	g.minLen = p.int("minLen", g.minLen)
+	g.maxLen = p.int("maxLen", g.maxLen)
+	if g.maxLen < g.minLen {
+		return nil, errors.New("need minLen <= maxLen")
+	}
+	return g, g.checkWords(conf)
+}
+
+func (g prefixQueryGen) checkWords(conf config) error {
+	return checkWordMinLen(conf, g.words, g.minLen)
+}
+
+// wildcardQueryGen emits a "wildcard" query made from a random word by
+// replacing wildcards chars with '?' or a run of chars with '*',
+// leading puts a '*' in front which forces a scan of the dictionary.
+type wildcardQueryGen struct {
+	field     string
+	words     string
+	minLen    int
+	wildcards int
+	leading   bool
+	miss      float64
+}
+
+func (g wildcardQueryGen) generate(conf config) string {
+	w := []rune(randomWordMinLen(conf.rnd(), conf.wordList(g.words), g.minLen))
+	for i := 0; i < g.wildcards && len(w) > 1; i++ {
+		// keep the first char literal unless leading is set
+		pos := randIntFromRange(conf.rnd(), 1, len(w)-1)
+		if conf.rnd().Intn(2) == 0 {
+			w[pos] = '?'
+		} else {
+			end := randIntFromRange(conf.rnd(), pos, len(w)-1)
+			w = append(append(w[:pos:pos], '*'), w[end+1:]...)
+		}
+	}
+	if g.leading {
+		w[0] = '*'
+	}
+	pattern := string(w)
+	for strings.Contains(pattern, "**") {
+		pattern = strings.ReplaceAll(pattern, "**", "*")
+	}
+	return fmt.Sprintf("\"query\": { \"wildcard\": %s, \"field\": \"%s\" }", jsonString(pattern), g.field)
+}
+
+func (g wildcardQueryGen) describe() string {
+	s := fmt.Sprintf("select one random word from %s with length at least %d chars and replace %d random\n"+
+		"chars by '?' or runs of chars by '*' as a wildcard on %s", g.words, g.minLen, g.wildcards, g.field)
+	if g.leading {
+		s += "\nthe first char is replaced by a leading '*'"
+	}
+	return s
+}
+
+func (g wildcardQueryGen) missRate() float64 { return g.miss }
+
+func (g wildcardQueryGen) withParams(conf config, p *queryParams) (queryGenerator, error) {
+	g.field = p.string("field", g.field)
+	g.words = p.words(conf, "words", g.words)
+	g.minLen = p.int("minLen", g.minLen)
+	g.wildcards = p.int("wildcards", g.wildcards)
+	g.leading = p.bool("leading", g.leading)
+	if g.wildcards < 0 {
+		return nil, errors.New("wildcards must be >= 0")
+	}
+	return g, g.checkWords(conf)
+}
+
+func (g wildcardQueryGen) checkWords(conf config) error {
+	return checkWordMinLen(conf, g.words, g.minLen)
+}
+
+// regexpQueryGen emits a "regexp" query, an alternation of 1 +
+// alternations random words where wildcards chars of each are replaced
+// by '.' or '.*'.
+type regexpQueryGen struct {
+	field        string
+	words        string
+	minLen       int
+	wildcards    int
+	alternations int
+	miss         float64
+}
+
+func (g regexpQueryGen) generate(conf config) string {
+	words := conf.wordList(g.words)
+	branches := make([]string, 1+g.alternations)
+	for b := range branches {
+		w := []rune(randomWordMinLen(conf.rnd(), words, g.minLen))
+		wild := map[int]string{}
+		for i := 0; i < g.wildcards && len(w) > 1; i++ {
+			if conf.rnd().Intn(2) == 0 {
+				wild[randIntFromRange(conf.rnd(), 1, len(w)-1)] = "."
+			} else {
+				wild[randIntFromRange(conf.rnd(), 1, len(w)-1)] = ".*"
+			}
+		}
+		var sb strings.Builder
+		for i, r := range w {
+			if re, ok := wild[i]; ok {
+				sb.WriteString(re)
+			} else {
+				sb.WriteString(regexp.QuoteMeta(string(r)))
+			}
+		}
+		branches[b] = sb.String()
+	}
+	pattern := branches[0]
+	if len(branches) > 1 {
+		pattern = "(" + strings.Join(branches, "|") + ")"
+	}
+	return fmt.Sprintf("\"query\": { \"regexp\": %s, \"field\": \"%s\" }", jsonString(pattern), g.field)
+}
+
+func (g regexpQueryGen) describe() string {
+	return fmt.Sprintf("select %d random word(s) from %s with length at least %d chars, replace %d random chars\n"+
+		"of each by '.' or '.*' and OR them as a regexp on %s", 1+g.alternations, g.words, g.minLen, g.wildcards, g.field)
+}
+
+func (g regexpQueryGen) missRate() float64 { return g.miss }
+
+func (g regexpQueryGen) withParams(conf config, p *queryParams) (queryGenerator, error) {
+	g.field = p.string("field", g.field)
+	g.words = p.words(conf, "words", g.words)
+	g.minLen = p.int("minLen", g.minLen)
+	g.wildcards = p.int("wildcards", g.wildcards)
+	g.alternations = p.int("alternations", g.alternations)
+	if g.wildcards < 0 || g.alternations < 0 {
+		return nil, errors.New("wildcards and alternations must be >= 0")
+	}
+	return g, g.checkWords(conf)
+}
+
+func (g regexpQueryGen) checkWords(conf config) error {
+	return checkWordMinLen(conf, g.words, g.minLen)
+}
+
+func init() {
+	const field = "reviews.content"
+
+	registerQueryGenerator(50, "match-phrase-2", phraseQueryGen{
+		field: field, words: "sampleReviewWords", minTerms: 2, maxTerms: 2, miss: missRateUnknown,
+	})
+	registerQueryGenerator(51, "match-phrase-2-4", phraseQueryGen{
+		field: field, words: "sampleReviewWords", minTerms: 2, maxTerms: 4, miss: missRateUnknown,
+	})
+	registerQueryGenerator(52, "phrase-2", phraseQueryGen{
+		field: field, words: "sampleReviewWords", minTerms: 2, maxTerms: 2, exact: true, miss: missRateUnknown,
+	})
+
+	registerQueryGenerator(53, "prefix-3", prefixQueryGen{
+		field: field, words: "commonReviewWords", minLen: 3, maxLen: 3, miss: missRateUnknown,
+	})
+	registerQueryGenerator(54, "prefix-1-2", prefixQueryGen{
+		field: field, words: "sampleReviewWords", minLen: 1, maxLen: 2, miss: missRateUnknown,
+	})
+
+	registerQueryGenerator(55, "wildcard-1", wildcardQueryGen{
+		field: field, words: "commonReviewWords", minLen: 5, wildcards: 1, miss: missRateUnknown,
+	})
+	registerQueryGenerator(56, "wildcard-2-leading", wildcardQueryGen{
+		field: field, words: "commonReviewWords", minLen: 5, wildcards: 2, leading: true, miss: missRateUnknown,
+	})
+
+	registerQueryGenerator(57, "regexp-1", regexpQueryGen{
+		field: field, words: "commonReviewWords", minLen: 5, wildcards: 1, miss: missRateUnknown,
+	})
+	registerQueryGenerator(58, "regexp-3-alt", regexpQueryGen{
+		field: field, words: "commonReviewWords", minLen: 5, wildcards: 2, alternations: 2, miss: missRateUnknown,
+	})
+}
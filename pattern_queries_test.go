@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func parsePatternQuery(t *testing.T, q string) map[string]interface{} {
+	var v struct {
+		Query map[string]interface{} `json:"query"`
+	}
+	if err := json.Unmarshal([]byte("{"+q+"}"), &v); err != nil {
+		t.Fatalf("%v in %s", err, q)
+	}
+	return v.Query
+}
+
+func TestPatternQueryGenerators(t *testing.T) {
+	var conf config
+	setBuiltinFtsData(&conf)
+	for i := 0; i < 200; i++ {
+		q := parsePatternQuery(t, queryGeneratorsByName["prefix-1-2"].gen.generate(conf))
+		if n := utf8.RuneCountInString(q["prefix"].(string)); n < 1 || n > 2 {
+			t.Errorf("unexpected prefix %v", q)
+		}
+
+		q = parsePatternQuery(t, queryGeneratorsByName["wildcard-2-leading"].gen.generate(conf))
+		w := q["wildcard"].(string)
+		if !strings.HasPrefix(w, "*") || strings.Contains(w, "**") {
+			t.Errorf("unexpected wildcard %q", w)
+		}
+
+		q = parsePatternQuery(t, queryGeneratorsByName["regexp-3-alt"].gen.generate(conf))
+		re := q["regexp"].(string)
+		if _, err := regexp.Compile(re); err != nil || strings.Count(re, "|") != 2 {
+			t.Errorf("unexpected regexp %q: %v", re, err)
+		}
+
+		q = parsePatternQuery(t, queryGeneratorsByName["phrase-2"].gen.generate(conf))
+		if terms := q["terms"].([]interface{}); len(terms) != 2 {
+			t.Errorf("unexpected phrase %v", q)
+		}
+	}
+}
+
+func TestPatternQueriesCheckWords(t *testing.T) {
+	var conf config
+	setBuiltinFtsData(&conf)
+	// 2 chars in 4 bytes, too short for prefix-3
+	conf.setWordList("commonReviewWords", []string{"éé", "ab"})
+	m, err := queryMixFromConfig(config{dynFtsLimit: 53})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.checkWords(conf); err == nil || !strings.Contains(err.Error(), "prefix-3") {
+		t.Errorf("expected an error for prefix-3, got %v", err)
+	}
+	conf.setWordList("commonReviewWords", []string{"ééé"})
+	if err := m.checkWords(conf); err != nil {
+		t.Fatal(err)
+	}
+	q := parsePatternQuery(t, m.generate(conf))
+	if q["prefix"] != "ééé" {
+		t.Errorf("unexpected prefix %v", q)
+	}
+}
+
+func TestPhraseQueryFromCorpus(t *testing.T) {
+	var conf config
+	setBuiltinFtsData(&conf)
+	conf.setWordList("reviews", []string{"The pool was nice, the staff was Friendly."})
+	gen := queryGeneratorsByName["match-phrase-2-4"].gen.(configurableQueryGenerator)
+	p := newQueryParams(map[string]interface{}{"corpus": "reviews", "minTerms": 3, "maxTerms": 3})
+	g, err := gen.withParams(conf, p)
+	if err == nil {
+		err = p.check()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	const sentence = "the pool was nice the staff was friendly"
+	for i := 0; i < 50; i++ {
+		phrase := parsePatternQuery(t, g.generate(conf))["match_phrase"].(string)
+		if len(strings.Fields(phrase)) != 3 || !strings.Contains(sentence, phrase) {
+			t.Errorf("%q is not a 3 word phrase of the corpus", phrase)
+		}
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"minTerms": 3, "maxTerms": 2},
+		{"corpus": "nope"},
+	} {
+		p := newQueryParams(bad)
+		_, err := gen.withParams(conf, p)
+		if err == nil {
+			err = p.check()
+		}
+		if err == nil {
+			t.Errorf("expected an error for %v", bad)
+		}
+	}
+	prefix := queryGeneratorsByName["prefix-3"].gen.(configurableQueryGenerator)
+	if _, err := prefix.withParams(conf, newQueryParams(map[string]interface{}{"minLen": 99, "maxLen": 99})); err == nil {
+		t.Error("expected an error for a minLen no word has")
+	}
+}
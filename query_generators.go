@@ -0,0 +1,385 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+func replaceAtIndex(in string, r rune, i int) string {
+	out := []rune(in)
+	out[i] = r
+	return string(out)
+}
+
+func randIntFromRange(r randGen, min int, max int) int {
+	return r.Intn(max-min+1) + min
+}
+
+func buildBasicRandomQueryMatch(r randGen, numterms int, words []string, wordsLen int) string {
+	var num int
+	repl := ""
+
+	for i := 1; i <= numterms; i++ {
+		num = randIntFromRange(r, 0, wordsLen-1)
+		if i == 1 {
+			repl = words[num]
+		} else {
+			repl = repl + words[num]
+		}
+	}
+
+	// picks up only 'hotel' (not 'landmark')
+	// repl = "\"query\": {  \"match\": \"balcony\",  \"field\": \"reviews.content\" }"
+
+	repl = "\"query\": {  \"match\": " + jsonString(repl) + ",  \"field\": \"reviews.content\" }"
+
+	return repl
+}
+
+func buildBasicRandomQueryNumWords(r randGen, numterms int, words []string, wordsLen int) string {
+	var num int
+	repl := ""
+
+	for i := 1; i <= numterms; i++ {
+		num = randIntFromRange(r, 0, wordsLen-1)
+		if i == 1 {
+			repl = "+" + words[num]
+		} else {
+			repl = repl + " +" + words[num]
+		}
+	}
+
+	// picks up both 'hotel' and 'landmark'
+	repl = "\"query\": { \"query\": " + jsonString(repl) + " }"
+
+	return repl
+}
+
+func buildBasicRandomQueryTwoNumWords(r randGen, numterms int, words []string, wordsLen int, numterms2 int, words2 []string, words2Len int) string {
+	var num int
+	repl := ""
+
+	for i := 1; i <= numterms; i++ {
+		num = randIntFromRange(r, 0, wordsLen-1)
+		if i == 1 {
+			repl = "+" + words[num]
+		} else {
+			repl = repl + " +" + words[num]
+		}
+	}
+
+	for i := 1; i <= numterms2; i++ {
+		num = randIntFromRange(r, 0, words2Len-1)
+		repl = repl + " +" + words2[num]
+	}
+
+	repl = "\"query\": { \"query\": " + jsonString(repl) + " }"
+
+	return repl
+}
+
+func buildFuzzyRandomQuery(r randGen, fuzziness int, termminlen int, words []string, wordsLen int) string {
+	var num int
+	var word1 string
+	repl := ""
+
+	// lengths and positions are in runes so loaded non-ASCII words stay intact
+	for {
+		num = randIntFromRange(r, 0, wordsLen-1)
+		word1 = words[num]
+		if utf8.RuneCountInString(word1) >= termminlen {
+			break
+		}
+	}
+
+	mychar := rune(randIntFromRange(r, 97, 122))
+	// fmt.Printf("A mychar %s\n",mychar)
+	mypos := randIntFromRange(r, 0, utf8.RuneCountInString(word1)-1)
+	// fmt.Printf("A mychar %s mypos %d word1[%d] <%s> len(word1)=%d\n",mychar,mypos,mypos,word1, len(word1));
+
+	word1 = replaceAtIndex(word1, mychar, mypos)
+
+	// fmt.Printf("B mychar %s mypos %d word1[%d] <%s>\n",mychar,mypos,mypos,word1);
+
+	repl = fmt.Sprintf("\"query\": { \"term\": %s, \"fuzziness\": %d }", jsonString(word1), fuzziness)
+	// fmt.Println(repl);
+
+	//aaa := "\"query\": { \"term\": \"Breakfasz\", \"fuzziness\": 2 }"
+	//fmt.Println("OK %s",aaa);
+	//fmt.Println("?? %s",repl);
+
+	// repl = "\"query\": { \"term\": \"Breakfasz\", \"fuzziness\": 2 }"
+	return repl
+}
+
+func buildBasicRandomRandomTermsQuery(conf config) string {
+	var num int
+	var word1 string
+	var word1a string
+	var word2 string
+	var word3 string
+	var word4 string
+	var repl string
+
+	// num = (rand.Intn(conf.commonReviewWordsLen - 1) + 1)
+	num = randIntFromRange(conf.rnd(), 0, conf.commonReviewWordsLen-1)
+	word1 = "+" + conf.commonReviewWords[num]
+	// num = (rand.Intn(conf.commonReviewWordsLen - 1) + 1)
+	num = randIntFromRange(conf.rnd(), 0, conf.commonReviewWordsLen-1)
+	word1a = "+" + conf.commonReviewWords[num]
+	repl = word1 + " " + word1a
+
+	if randIntFromRange(conf.rnd(), 1, 10) < 6 {
+		// num = (rand.Intn(conf.commonReviewWordsLen - 1) + 1)
+		num = randIntFromRange(conf.rnd(), 0, conf.commonReviewWordsLen-1)
+		word2 = "+" + conf.commonReviewWords[num]
+		repl = repl + " " + word2
+	}
+
+	if randIntFromRange(conf.rnd(), 1, 10) < 6 {
+		// num = (rand.Intn(conf.commonEnglishWordsLen - 1) + 1)
+		num = randIntFromRange(conf.rnd(), 0, conf.commonEnglishWordsLen-1)
+		word3 = "+" + conf.commonEnglishWords[num]
+		repl = repl + " " + word3
+	}
+
+	if randIntFromRange(conf.rnd(), 1, 10) < 6 {
+		// num = (rand.Intn(conf.commonVerbWordsLen - 1) + 1)
+		num = randIntFromRange(conf.rnd(), 0, conf.commonVerbWordsLen-1)
+		word4 = "+" + conf.commonVerbWords[num]
+		repl = repl + " " + word4
+	}
+	repl = "\"query\": { \"query\": " + jsonString(repl) + " }"
+
+	return repl
+}
+
+func buildPseudoGeoRandomQuery(scale float32, conf config) string {
+	repl := ""
+
+	num := randIntFromRange(conf.rnd(), 0, conf.hotelLocationLatLonsLen-1)
+	center := conf.hotelLocationLatLons[num]
+	lat := center[1]
+	lon := center[0]
+
+	delta_lat := float32(randIntFromRange(conf.rnd(), 0, 1000))/float32(10000) - 0.05*scale
+	delta_lon := float32(randIntFromRange(conf.rnd(), 0, 1000))/float32(10000) - 0.05
+
+	new_lat := lat + delta_lat
+	new_lon := lon + delta_lon
+
+	// fmt.Println(lat,lon);
+	// fmt.Println(new_lat,new_lon);
+
+	lat_min := new_lat - float32(randIntFromRange(conf.rnd(), 1, 15))/30.0*scale
+	lat_max := new_lat + float32(randIntFromRange(conf.rnd(), 1, 15))/30.0*scale
+
+	lon_min := new_lon - float32(randIntFromRange(conf.rnd(), 1, 15))/30.0*scale
+	lon_max := new_lon + float32(randIntFromRange(conf.rnd(), 1, 15))/30.0*scale
+
+	lat_min_str := fmt.Sprintf("%f", lat_min)
+	lat_max_str := fmt.Sprintf("%f", lat_max)
+	lon_min_str := fmt.Sprintf("%f", lon_min)
+	lon_max_str := fmt.Sprintf("%f", lon_max)
+
+	// aaa := "\"query\": {\"conjuncts\":[ {  \"min\": -94, \"max\": -93,  \"inclusive_min\": false,  \"inclusive_max\": false,  \"field\": \"geo.lon\" },{  \"min\": 45, \"max\": 46,  \"inclusive_min\": false,  \"inclusive_max\": false,  \"field\": \"geo.lat\" }]} "
+
+	// repl = aaa
+
+	repl = "\"query\": {\"conjuncts\":[ {  \"min\": " +
+		lon_min_str + ", \"max\": " +
+		lon_max_str + ",  \"inclusive_min\": false,  \"inclusive_max\": false,  \"field\": \"geo.lon\" },{  \"min\": " +
+		lat_min_str + ", \"max\": " +
+		lat_max_str + ",  \"inclusive_min\": false,  \"inclusive_max\": false,  \"field\": \"geo.lat\" }]} "
+
+	return repl
+}
+
+// ========= built-in FTS query generators =========
+
+// matchQueryGen emits a "match" query on reviews.content.
+type matchQueryGen struct {
+	numTerms int
+	words    string
+	miss     float64
+}
+
+func (g matchQueryGen) generate(conf config) string {
+	words := conf.wordList(g.words)
+	return buildBasicRandomQueryMatch(conf.rnd(), g.numTerms, words, len(words))
+}
+
+func (g matchQueryGen) describe() string {
+	return fmt.Sprintf("select %d random word(s) from %s as a match on reviews.content", g.numTerms, g.words)
+}
+
+func (g matchQueryGen) missRate() float64 { return g.miss }
+
+func (g matchQueryGen) withParams(conf config, p *queryParams) (queryGenerator, error) {
+	g.numTerms = p.count("terms", g.numTerms)
+	g.words = p.words(conf, "words", g.words)
+	return g, nil
+}
+
+// termsQueryGen emits a query_string of required "+term" words taken
+// from up to two word lists.
+type termsQueryGen struct {
+	numTerms int
+	words    string
+	// poolLen if > 0 limits the picks from words to its first poolLen
+	// entries, tests 37-40 were always measured this way.
+	poolLen int
+
+	numTerms2 int
+	words2    string
+
+	miss float64
+}
+
+func (g termsQueryGen) generate(conf config) string {
+	words := conf.wordList(g.words)
+	wordsLen := len(words)
+	if g.poolLen > 0 && g.poolLen < wordsLen {
+		wordsLen = g.poolLen
+	}
+	if g.numTerms2 == 0 {
+		return buildBasicRandomQueryNumWords(conf.rnd(), g.numTerms, words, wordsLen)
+	}
+	words2 := conf.wordList(g.words2)
+	return buildBasicRandomQueryTwoNumWords(conf.rnd(), g.numTerms, words, wordsLen, g.numTerms2, words2, len(words2))
+}
+
+func (g termsQueryGen) describe() string {
+	s := fmt.Sprintf("select %d random word(s) from %s", g.numTerms, g.words)
+	if g.poolLen > 0 {
+		s += fmt.Sprintf(" (first %d only)", g.poolLen)
+	}
+	if g.numTerms2 > 0 {
+		s += fmt.Sprintf(" AND select %d random word(s) from %s", g.numTerms2, g.words2)
+	}
+	return s
+}
+
+func (g termsQueryGen) missRate() float64 { return g.miss }
+
+func (g termsQueryGen) withParams(conf config, p *queryParams) (queryGenerator, error) {
+	g.numTerms = p.count("terms", g.numTerms)
+	g.words = p.words(conf, "words", g.words)
+	g.poolLen = p.count("poolLen", g.poolLen)
+	g.numTerms2 = p.count("terms2", g.numTerms2)
+	g.words2 = p.words(conf, "words2", g.words2)
+	if g.numTerms2 > 0 && g.words2 == "" {
+		return nil, errors.New("terms2 requires words2")
+	}
+	return g, nil
+}
+
+// fuzzyQueryGen emits a fuzzy "term" query for a randomly misspelled word.
+type fuzzyQueryGen struct {
+	fuzziness  int
+	termMinLen int
+	words      string
+	miss       float64
+}
+
+func (g fuzzyQueryGen) generate(conf config) string {
+	words := conf.wordList(g.words)
+	return buildFuzzyRandomQuery(conf.rnd(), g.fuzziness, g.termMinLen, words, len(words))
+}
+
+func (g fuzzyQueryGen) describe() string {
+	return fmt.Sprintf("select one random word from %s with length at least %d chars and apply fuzziness of %d\n"+
+		"AND then randomly replace one char [a-z] in any position of the term to search for", g.words, g.termMinLen, g.fuzziness)
+}
+
+func (g fuzzyQueryGen) missRate() float64 { return g.miss }
+
+func (g fuzzyQueryGen) withParams(conf config, p *queryParams) (queryGenerator, error) {
+	g.fuzziness = p.int("fuzziness", g.fuzziness)
+	g.termMinLen = p.int("termMinLen", g.termMinLen)
+	g.words = p.words(conf, "words", g.words)
+	if g.termMinLen < 1 {
+		return nil, errors.New("termMinLen must be > 0")
+	}
+	if err := g.checkWords(conf); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g fuzzyQueryGen) checkWords(conf config) error {
+	for _, w := range conf.wordList(g.words) {
+		if utf8.RuneCountInString(w) >= g.termMinLen {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s has no word with at least %d chars", g.words, g.termMinLen)
+}
+
+// pseudoGeoQueryGen emits 2 numeric range conjuncts on geo.lon and geo.lat.
+type pseudoGeoQueryGen struct {
+	scale float32
+	miss  float64
+}
+
+func (g pseudoGeoQueryGen) generate(conf config) string {
+	return buildPseudoGeoRandomQuery(g.scale, conf)
+}
+
+func (g pseudoGeoQueryGen) describe() string {
+	return "Take a random hotel location lat/lon and apply some random offsets to get a new lat/lon point then build a\n" +
+		"bounding box by adjusting the centroid by random amounts - this is a pseudo geo search - via conjuncts"
+}
+
+func (g pseudoGeoQueryGen) missRate() float64 { return g.miss }
+
+func (g pseudoGeoQueryGen) withParams(conf config, p *queryParams) (queryGenerator, error) {
+	g.scale = float32(p.float("scale", float64(g.scale)))
+	return g, nil
+}
+
+// randomTermsQueryGen emits 2 to 5 "+term" words from the common lists.
+type randomTermsQueryGen struct {
+	miss float64
+}
+
+func (g randomTermsQueryGen) generate(conf config) string {
+	return buildBasicRandomRandomTermsQuery(conf)
+}
+
+func (g randomTermsQueryGen) describe() string {
+	return "select two random word from commonReviewWords\n" +
+		"50% probability add another random word from commonReviewWords\n" +
+		"50% probability add another random word from commonEnglishWords\n" +
+		"50% probability add another random word from commonVerbWords"
+}
+
+func (g randomTermsQueryGen) missRate() float64 { return g.miss }
+
+func init() {
+	registerQueryGenerator(1, "match-sample-1", matchQueryGen{numTerms: 1, words: "sampleReviewWords", miss: 0.0})
+
+	registerQueryGenerator(31, "terms-sample-1", termsQueryGen{numTerms: 1, words: "sampleReviewWords", miss: 0.0})
+	registerQueryGenerator(32, "terms-sample-2", termsQueryGen{numTerms: 2, words: "sampleReviewWords", miss: 90.4})
+
+	registerQueryGenerator(33, "terms-common-1", termsQueryGen{numTerms: 1, words: "commonReviewWords", miss: 0.0})
+	registerQueryGenerator(34, "terms-common-2", termsQueryGen{numTerms: 2, words: "commonReviewWords", miss: 1.3})
+	registerQueryGenerator(35, "terms-common-3", termsQueryGen{numTerms: 3, words: "commonReviewWords", miss: 10.6})
+	registerQueryGenerator(36, "terms-common-4", termsQueryGen{numTerms: 4, words: "commonReviewWords", miss: 30.4})
+
+	for i, miss := range []float64{51.0, 77.0, 88.4, 94.7} {
+		registerQueryGenerator(uint64(37+i), fmt.Sprintf("terms-sample-1-common-%d", i+1), termsQueryGen{
+			numTerms: 1, words: "sampleReviewWords", poolLen: 50,
+			numTerms2: i + 1, words2: "commonReviewWords",
+			miss: miss,
+		})
+	}
+
+	registerQueryGenerator(41, "fuzzy-1", fuzzyQueryGen{fuzziness: 1, termMinLen: 5, words: "commonReviewWords", miss: 74.1})
+	registerQueryGenerator(42, "fuzzy-2", fuzzyQueryGen{fuzziness: 2, termMinLen: 5, words: "commonReviewWords", miss: 0.0})
+
+	registerQueryGenerator(43, "pseudo-geo", pseudoGeoQueryGen{scale: 0.25, miss: 14.0})
+
+	registerQueryGenerator(99, "random-terms", randomTermsQueryGen{miss: 9.9})
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// queryMixFile is the layout of a --queryMix file, e.g. in YAML
+//
+//	queries:
+//	  - name: random-terms
+//	    weight: 80
+//	  - name: fuzzy-1
+//	    weight: 10
+//	    params: {fuzziness: 2, words: sampleReviewWords}
+//	  - name: pseudo-geo
+//	    weight: 10
+//	    params: {scale: 0.5}
+//
+// the same structure is accepted as JSON.
+type queryMixFile struct {
+	Queries []queryMixFileEntry `json:"queries" yaml:"queries"`
+}
+
+type queryMixFileEntry struct {
+	Name   string                 `json:"name" yaml:"name"`
+	Weight int                    `json:"weight" yaml:"weight"`
+	Params map[string]interface{} `json:"params" yaml:"params"`
+}
+
+// configurableQueryGenerator is implemented by generators that accept
+// per-entry parameters from a --queryMix file.
+type configurableQueryGenerator interface {
+	withParams(conf config, p *queryParams) (queryGenerator, error)
+}
+
+// wordsCheckingQueryGenerator is implemented by generators that pick
+// words of a minimum length, checkWords tells whether their word list has
+// one as they would otherwise look for it forever.
+type wordsCheckingQueryGenerator interface {
+	checkWords(conf config) error
+}
+
+// queryParams gives typed access to the parameters of a query mix
+// entry and remembers which ones were used so typos can be reported.
+type queryParams struct {
+	m    map[string]interface{}
+	used map[string]bool
+	err  error
+}
+
+func newQueryParams(m map[string]interface{}) *queryParams {
+	return &queryParams{m: m, used: map[string]bool{}}
+}
+
+func (p *queryParams) setErr(key string, v interface{}, want string) {
+	if p.err == nil {
+		p.err = fmt.Errorf("parameter %q: expected %s, got %v", key, want, v)
+	}
+}
+
+func (p *queryParams) float(key string, def float64) float64 {
+	v, ok := p.m[key]
+	if !ok {
+		return def
+	}
+	p.used[key] = true
+	switch n := v.(type) {
+	case int:
+		return float64(n)
+	case float64:
+		return n
+	}
+	p.setErr(key, v, "a number")
+	return def
+}
+
+func (p *queryParams) int(key string, def int) int {
+	f := p.float(key, float64(def))
+	if f != float64(int(f)) {
+		p.setErr(key, f, "an integer")
+		return def
+	}
+	return int(f)
+}
+
+// count is int for a number of terms or words, a given value must be > 0.
+func (p *queryParams) count(key string, def int) int {
+	n := p.int(key, def)
+	if _, ok := p.m[key]; ok && n < 1 {
+		p.setErr(key, n, "an integer > 0")
+		return def
+	}
+	return n
+}
+
+func (p *queryParams) bool(key string, def bool) bool {
+	v, ok := p.m[key]
+	if !ok {
+		return def
+	}
+	p.used[key] = true
+	b, ok := v.(bool)
+	if !ok {
+		p.setErr(key, v, "true or false")
+		return def
+	}
+	return b
+}
+
+func (p *queryParams) string(key string, def string) string {
+	v, ok := p.m[key]
+	if !ok {
+		return def
+	}
+	p.used[key] = true
+	s, ok := v.(string)
+	if !ok || s == "" {
+		p.setErr(key, v, "a non-empty string")
+		return def
+	}
+	return s
+}
+
+// strings returns a list of strings, each one must be in allowed.
+func (p *queryParams) strings(key string, def []string, allowed ...string) []string {
+	v, ok := p.m[key]
+	if !ok {
+		return def
+	}
+	p.used[key] = true
+	list, ok := v.([]interface{})
+	if !ok || len(list) == 0 {
+		p.setErr(key, v, "a non-empty list of "+strings.Join(allowed, ", "))
+		return def
+	}
+	res := make([]string, 0, len(list))
+	for _, e := range list {
+		s, isStr := e.(string)
+		if !isStr || !containsString(allowed, s) {
+			p.setErr(key, v, "a non-empty list of "+strings.Join(allowed, ", "))
+			return def
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+func containsString(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
+
+// words returns the name of a word list, it must be known to conf.
+func (p *queryParams) words(conf config, key, def string) string {
+	v, ok := p.m[key]
+	if !ok {
+		return def
+	}
+	p.used[key] = true
+	name, isStr := v.(string)
+	if !isStr || conf.wordList(name) == nil {
+		p.setErr(key, v, "the name of a word list")
+		return def
+	}
+	return name
+}
+
+// check reports the first bad value or any parameter that wasn't used.
+func (p *queryParams) check() error {
+	if p.err != nil {
+		return p.err
+	}
+	var unknown []string
+	for key := range p.m {
+		if !p.used[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown parameter(s) %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// readQueryMixFile parses a --queryMix file, files ending in .json are
+// read as JSON, anything else as YAML (a superset of JSON).
+func readQueryMixFile(path string) (*queryMixFile, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	mf := new(queryMixFile)
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, mf)
+	} else {
+		err = yaml.Unmarshal(data, mf)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query mix %s: %v", path, err)
+	}
+	return mf, nil
+}
+
+// loadQueryMix builds the query mix described by the file at path.
+func loadQueryMix(conf config, path string) (*queryMix, error) {
+	mf, err := readQueryMixFile(path)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]weightedQueryGenerator, 0, len(mf.Queries))
+	for i, e := range mf.Queries {
+		rg, ok := queryGeneratorsByName[e.Name]
+		if !ok {
+			return nil, fmt.Errorf(
+				"query mix %s: entry %d: unknown FTS query generator %q", path, i+1, e.Name)
+		}
+		gen := rg.gen
+		if len(e.Params) > 0 {
+			cg, ok := gen.(configurableQueryGenerator)
+			if !ok {
+				return nil, fmt.Errorf(
+					"query mix %s: entry %d: %s does not take parameters", path, i+1, e.Name)
+			}
+			p := newQueryParams(e.Params)
+			gen, err = cg.withParams(conf, p)
+			if err == nil {
+				err = p.check()
+			}
+			if err != nil {
+				return nil, fmt.Errorf("query mix %s: entry %d (%s): %v", path, i+1, e.Name, err)
+			}
+		}
+		entries = append(entries, weightedQueryGenerator{name: e.Name, weight: e.Weight, gen: gen})
+	}
+	m, err := newQueryMix(entries)
+	if err != nil {
+		return nil, fmt.Errorf("query mix %s: %v", path, err)
+	}
+	return m, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTempFile(t *testing.T, name, content string) string {
+	dir, err := ioutil.TempDir("", "cbftsbench")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadQueryMixYAML(t *testing.T) {
+	var conf config
+	setBuiltinFtsData(&conf)
+	path := writeTempFile(t, "mix.yaml", `
+queries:
+  - name: random-terms
+    weight: 6
+  - name: fuzzy-1
+    weight: 3
+    params: {fuzziness: 2, termMinLen: 4, words: sampleReviewWords}
+  - name: pseudo-geo
+    weight: 1
+    params: {scale: 0.5}
+`)
+	m, err := loadQueryMix(conf, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.total != 10 || len(m.entries) != 3 {
+		t.Fatalf("unexpected mix %+v", m.entries)
+	}
+	fuzzy, ok := m.entries[1].gen.(fuzzyQueryGen)
+	if !ok || fuzzy.fuzziness != 2 || fuzzy.termMinLen != 4 || fuzzy.words != "sampleReviewWords" {
+		t.Errorf("params not applied: %+v", m.entries[1].gen)
+	}
+	geo, ok := m.entries[2].gen.(pseudoGeoQueryGen)
+	if !ok || geo.scale != 0.5 {
+		t.Errorf("params not applied: %+v", m.entries[2].gen)
+	}
+	if q := m.entries[1].gen.generate(conf); !strings.Contains(q, `"fuzziness": 2`) {
+		t.Errorf("unexpected query %s", q)
+	}
+}
+
+func TestLoadQueryMixJSON(t *testing.T) {
+	var conf config
+	setBuiltinFtsData(&conf)
+	path := writeTempFile(t, "mix.json", `{"queries": [
+		{"name": "terms-common-2", "weight": 1, "params": {"terms": 3}}
+	]}`)
+	m, err := loadQueryMix(conf, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	terms := m.entries[0].gen.(termsQueryGen)
+	if terms.numTerms != 3 {
+		t.Errorf("expected 3 terms, got %d", terms.numTerms)
+	}
+}
+
+func TestLoadQueryMixErrors(t *testing.T) {
+	var conf config
+	setBuiltinFtsData(&conf)
+	expectations := []struct {
+		content string
+		errPart string
+	}{
+		{`queries: [{name: nope, weight: 1}]`, "unknown FTS query generator"},
+		{`queries: [{name: fuzzy-1, weight: 0}]`, "weight > 0"},
+		{`queries: [{name: fuzzy-1, weight: 1, params: {fuzzyness: 2}}]`, "unknown parameter(s) fuzzyness"},
+		{`queries: [{name: fuzzy-1, weight: 1, params: {words: noSuchList}}]`, "name of a word list"},
+		{`queries: [{name: match-sample-1, weight: 1, params: {terms: 1.5}}]`, "an integer"},
+		{`queries: [{name: match-sample-1, weight: 1, params: {terms: 0}}]`, "an integer > 0"},
+		{`queries: [{name: terms-sample-1, weight: 1, params: {terms: -1}}]`, "an integer > 0"},
+		{`queries: [{name: terms-sample-1, weight: 1, params: {poolLen: 0}}]`, "an integer > 0"},
+		{`queries: [{name: terms-sample-1, weight: 1, params: {terms2: 0, words2: commonReviewWords}}]`, "an integer > 0"},
+		{`queries: [{name: random-terms, weight: 1, params: {terms: 1}}]`, "does not take parameters"},
+		{`queries: []`, errEmptyQueryMix.Error()},
+	}
+	for _, e := range expectations {
+		path := writeTempFile(t, "mix.yaml", e.content)
+		_, err := loadQueryMix(conf, path)
+		if err == nil || !strings.Contains(err.Error(), e.errPart) {
+			t.Errorf("%s: expected error containing %q, got %v", e.content, e.errPart, err)
+		}
+	}
+}
+
+func TestQueryMixAndLimitAreExclusive(t *testing.T) {
+	_, err := queryMixFromConfig(config{dynFtsLimit: 41, queryMixPath: "mix.yaml"})
+	if err != errQueryMixAndLimit {
+		t.Errorf("expected %v, got %v", errQueryMixAndLimit, err)
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// queryGenerator produces the FTS query fragment that is substituted
+// for __FTS_QUERY__ in the request body.
+type queryGenerator interface {
+	// generate returns a new (random) query fragment.
+	generate(conf config) string
+	// describe returns the details shown by --ftsTestHelp.
+	describe() string
+	// missRate is the expected percentage of queries without hits
+	// against the travel-sample test setup, missRateUnknown if it
+	// was never measured.
+	missRate() float64
+}
+
+const missRateUnknown = -1.0
+
+// registeredQueryGenerator is a query generator selectable via -L by
+// its id or from a query mix by its name.
+type registeredQueryGenerator struct {
+	id   uint64
+	name string
+	gen  queryGenerator
+}
+
+var (
+	queryGeneratorsByID   = map[uint64]*registeredQueryGenerator{}
+	queryGeneratorsByName = map[string]*registeredQueryGenerator{}
+)
+
+// registerQueryGenerator makes gen available under both id and name,
+// it is meant to be called from init() of the file defining gen.
+func registerQueryGenerator(id uint64, name string, gen queryGenerator) {
+	if _, dup := queryGeneratorsByID[id]; dup {
+		panic(fmt.Sprintf("query generator id %d registered twice", id))
+	}
+	if _, dup := queryGeneratorsByName[name]; dup {
+		panic(fmt.Sprintf("query generator %q registered twice", name))
+	}
+	rg := &registeredQueryGenerator{id: id, name: name, gen: gen}
+	queryGeneratorsByID[id] = rg
+	queryGeneratorsByName[name] = rg
+}
+
+func registeredQueryGenerators() []*registeredQueryGenerator {
+	res := make([]*registeredQueryGenerator, 0, len(queryGeneratorsByID))
+	for _, rg := range queryGeneratorsByID {
+		res = append(res, rg)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].id < res[j].id
+	})
+	return res
+}
+
+// weightedQueryGenerator is a single entry of a query mix.
+type weightedQueryGenerator struct {
+	name   string
+	weight int
+	gen    queryGenerator
+}
+
+// queryMix randomly selects one of its generators for each request
+// with a probability proportional to the generator's weight.
+type queryMix struct {
+	entries []weightedQueryGenerator
+	total   int
+}
+
+// defaultQueryMixSpec is used when no test is selected via -L, it sends
+// 80% random query terms, 10% simple fuzzy and 10% 2-"conjuncts"
+// min/max queries.
+var defaultQueryMixSpec = []weightedQueryGenerator{
+	{name: "random-terms", weight: 8},
+	{name: "fuzzy-1", weight: 1},
+	{name: "pseudo-geo", weight: 1},
+}
+
+func newQueryMix(entries []weightedQueryGenerator) (*queryMix, error) {
+	m := new(queryMix)
+	for _, e := range entries {
+		if e.gen == nil {
+			rg, ok := queryGeneratorsByName[e.name]
+			if !ok {
+				return nil, fmt.Errorf("unknown FTS query generator %q", e.name)
+			}
+			e.gen = rg.gen
+		}
+		if e.weight < 1 {
+			return nil, fmt.Errorf(
+				"FTS query generator %q must have a weight > 0", e.name)
+		}
+		m.entries = append(m.entries, e)
+		m.total += e.weight
+	}
+	if len(m.entries) == 0 {
+		return nil, errEmptyQueryMix
+	}
+	return m, nil
+}
+
+// queryMixFromConfig builds the mix selected by -L or --queryMix or, if
+// neither was given, the default mix. With --groundTruth the default is
+// the knn test.
+func queryMixFromConfig(conf config) (*queryMix, error) {
+	if conf.queryMixPath != "" {
+		if conf.dynFtsLimit > 0 {
+			return nil, errQueryMixAndLimit
+		}
+		return loadQueryMix(conf, conf.queryMixPath)
+	}
+	if conf.dynFtsLimit > 0 {
+		rg, ok := queryGeneratorsByID[conf.dynFtsLimit]
+		if !ok {
+			return nil, fmt.Errorf(
+				"unknown FTS query test %d, see --ftsTestHelp", conf.dynFtsLimit)
+		}
+		return newQueryMix([]weightedQueryGenerator{
+			{name: rg.name, weight: 1, gen: rg.gen},
+		})
+	}
+	if conf.groundTruth != nil {
+		return newQueryMix([]weightedQueryGenerator{{name: "knn", weight: 1}})
+	}
+	return newQueryMix(defaultQueryMixSpec)
+}
+
+func (m *queryMix) pick(r randGen) queryGenerator {
+	if len(m.entries) == 1 {
+		return m.entries[0].gen
+	}
+	n := r.Intn(m.total)
+	for _, e := range m.entries {
+		if n < e.weight {
+			return e.gen
+		}
+		n -= e.weight
+	}
+	return m.entries[len(m.entries)-1].gen
+}
+
+func (m *queryMix) generate(conf config) string {
+	return m.pick(conf.rnd()).generate(conf)
+}
+
+// generateProbe is generate plus, for knn queries checked against the
+// --groundTruth file, the probe identifying the query vector.
+func (m *queryMix) generateProbe(conf config) (string, *recallProbe) {
+	gen := m.pick(conf.rnd())
+	if rg, ok := gen.(recallQueryGenerator); ok {
+		return rg.generateProbe(conf)
+	}
+	return gen.generate(conf), nil
+}
+
+// checkWords checks the word lists of conf, e.g. loaded via --wordList,
+// against the generators of the mix that need words of a minimum length.
+func (m *queryMix) checkWords(conf config) error {
+	for _, e := range m.entries {
+		if wc, ok := e.gen.(wordsCheckingQueryGenerator); ok {
+			if err := wc.checkWords(conf); err != nil {
+				return fmt.Errorf("FTS query generator %q: %v", e.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// missRate is the weighted average of the generators' miss rates.
+func (m *queryMix) missRate() float64 {
+	sum := 0.0
+	for _, e := range m.entries {
+		mr := e.gen.missRate()
+		if mr < 0 {
+			return missRateUnknown
+		}
+		sum += float64(e.weight) * mr
+	}
+	return sum / float64(m.total)
+}
+
+func formatMissRate(mr float64) string {
+	if mr < 0 {
+		return " n/a misses"
+	}
+	return fmt.Sprintf("%4.1f%% misses", mr)
+}
+
+func (m *queryMix) describe() string {
+	var sb strings.Builder
+	for _, e := range m.entries {
+		fmt.Fprintf(&sb, "\t// %.0f%% or %d out of %d queries use %s\n",
+			float64(e.weight)/float64(m.total)*100, e.weight, m.total, e.name)
+	}
+	return sb.String()
+}
+
+// ftsTestHelp renders the --ftsTestHelp text from the registered
+// generators, an example query is generated from the built-in data.
+func ftsTestHelp() string {
+	var conf config
+	setBuiltinFtsData(&conf)
+
+	var sb strings.Builder
+	sb.WriteString(TEST_HELP)
+	sb.WriteString("\n    The following tests are supported via -L #\n\n")
+	for _, rg := range registeredQueryGenerators() {
+		fmt.Fprintf(&sb, "\n    %d: %s // %s\n\n", rg.id, rg.name, formatMissRate(rg.gen.missRate()))
+		sb.WriteString(indentLines(rg.gen.describe(), "\t"))
+		fmt.Fprintf(&sb, "\n\texample:\n\t\t%s\n", rg.gen.generate(conf))
+	}
+	if m, err := newQueryMix(defaultQueryMixSpec); err == nil {
+		fmt.Fprintf(&sb, "\n    OTHER: // %s\n\n", formatMissRate(m.missRate()))
+		sb.WriteString("\tIf no test is selected we randomly apply the following\n\n")
+		sb.WriteString(m.describe())
+	}
+	return sb.String()
+}
+
+func indentLines(s, indent string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, l := range lines {
+		lines[i] = indent + l
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestBuiltinQueryGeneratorsGenerate(t *testing.T) {
+	var conf config
+	setBuiltinFtsData(&conf)
+	for _, rg := range registeredQueryGenerators() {
+		q := rg.gen.generate(conf)
+		if !strings.HasPrefix(q, "\"query\":") {
+			t.Errorf("generator %d (%s) produced %q", rg.id, rg.name, q)
+		}
+	}
+}
+
+func TestQueryMixFromConfig(t *testing.T) {
+	m, err := queryMixFromConfig(config{dynFtsLimit: 41})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.entries) != 1 || m.entries[0].name != "fuzzy-1" {
+		t.Errorf("expected only fuzzy-1, got %v", m.entries)
+	}
+	if _, err := queryMixFromConfig(config{dynFtsLimit: 12345}); err == nil {
+		t.Error("expected an error for an unknown test")
+	}
+	m, err = queryMixFromConfig(config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.total != 10 || len(m.entries) != 3 {
+		t.Errorf("unexpected default mix %v", m.entries)
+	}
+}
+
+func TestQueryMixCheckWords(t *testing.T) {
+	var conf config
+	setBuiltinFtsData(&conf)
+	m, err := queryMixFromConfig(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.checkWords(conf); err != nil {
+		t.Errorf("expected the built-in words to do, got %v", err)
+	}
+	// as if loaded via --wordList commonReviewWords=path
+	conf.setWordList("commonReviewWords", []string{"ab", "cd"})
+	if err := m.checkWords(conf); err == nil || !strings.Contains(err.Error(), "fuzzy-1") {
+		t.Errorf("expected an error for fuzzy-1, got %v", err)
+	}
+	m, err = queryMixFromConfig(config{dynFtsLimit: 33})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.checkWords(conf); err != nil {
+		t.Errorf("expected no check for terms-common-1, got %v", err)
+	}
+}
+
+func TestBombardierChecksWords(t *testing.T) {
+	conf := config{
+		numConns: 1,
+		url:      "http://localhost:8094/api/index/ix/query",
+		headers:  new(headersList),
+		timeout:  defaultTimeout,
+		method:   "POST",
+		body:     "{" + fts_query_pat + "}",
+		format:   knownFormat("json"),
+	}
+	setBuiltinFtsData(&conf)
+	conf.setWordList("commonReviewWords", []string{"ab", "cd"})
+	if _, err := newBombardier(conf); err == nil || !strings.Contains(err.Error(), "fuzzy-1") {
+		t.Errorf("expected an error for fuzzy-1, got %v", err)
+	}
+	// no FTS queries to send, no words needed
+	conf.body = "{}"
+	if _, err := newBombardier(conf); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestFuzzyQueryKeepsRunes(t *testing.T) {
+	words := []string{"ab", "éèàü"}
+	for i := 0; i < 100; i++ {
+		q := buildFuzzyRandomQuery(globalRand{}, 1, 4, words, len(words))
+		term := strings.SplitN(q, `"`, 7)[5]
+		if !utf8.ValidString(term) || utf8.RuneCountInString(term) != 4 {
+			t.Fatalf("expected a 4 rune term, got %q", q)
+		}
+	}
+}
+
+func TestQueryMixPickHonorsWeights(t *testing.T) {
+	m, err := newQueryMix([]weightedQueryGenerator{
+		{name: "fuzzy-1", weight: 3},
+		{name: "pseudo-geo", weight: 1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fuzzy := queryGeneratorsByName["fuzzy-1"].gen
+	n, hits := 10000, 0
+	for i := 0; i < n; i++ {
+		if m.pick(globalRand{}) == fuzzy {
+			hits++
+		}
+	}
+	if ratio := float64(hits) / float64(n); ratio < 0.7 || ratio > 0.8 {
+		t.Errorf("expected ~75%% fuzzy-1 picks, got %.3f", ratio)
+	}
+}
+
+func TestNewQueryMixErrors(t *testing.T) {
+	if _, err := newQueryMix(nil); err != errEmptyQueryMix {
+		t.Errorf("expected %v, got %v", errEmptyQueryMix, err)
+	}
+	if _, err := newQueryMix([]weightedQueryGenerator{{name: "nope", weight: 1}}); err == nil {
+		t.Error("expected an error for an unknown generator")
+	}
+	if _, err := newQueryMix([]weightedQueryGenerator{{name: "fuzzy-1"}}); err == nil {
+		t.Error("expected an error for a zero weight")
+	}
+}
+
+func TestFtsTestHelpListsGenerators(t *testing.T) {
+	help := ftsTestHelp()
+	for _, rg := range registeredQueryGenerators() {
+		if !strings.Contains(help, rg.name) {
+			t.Errorf("help is missing %s", rg.name)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// recallProbe identifies the query vector sent in a knn request so the
+// hits can be checked against its ground truth neighbors.
+type recallProbe struct {
+	vector int
+	k      int
+}
+
+// recallQueryGenerator is implemented by generators whose queries can
+// be checked against the --groundTruth file, generateProbe returns a nil
+// probe for queries that can't.
+type recallQueryGenerator interface {
+	generateProbe(conf config) (string, *recallProbe)
+}
+
+// recordRecall adds the recall@k of a knn response to b.recall.
+func (b *bombardier) recordRecall(conf config, probe *recallProbe, result *CbFtsRespShort) {
+	ids := make([]string, len(result.Hits))
+	for i, hit := range result.Hits {
+		ids[i] = hit.Id
+	}
+	b.recall.Increment(recallAt(probe.k, ids, conf.groundTruth[probe.vector]))
+}
+
+// recallAt is the fraction of the first k ground truth neighbors found
+// in the first k hits.
+func recallAt(k int, hitIDs, truth []string) float64 {
+	if len(truth) < k {
+		k = len(truth)
+	}
+	if k == 0 {
+		return 0
+	}
+	want := make(map[string]bool, k)
+	for _, id := range truth[:k] {
+		want[id] = true
+	}
+	found := 0
+	for i, id := range hitIDs {
+		if i == k {
+			break
+		}
+		if want[id] {
+			found++
+			delete(want, id)
+		}
+	}
+	return float64(found) / float64(k)
+}
+
+// readGroundTruthFile reads the neighbor ids of each query vector from
+// an .ivecs file (the int32 count then int32 ids layout of the ANN
+// benchmark datasets), a .json file (an array of arrays) or a .jsonl
+// file (one array, or an object with a "neighbors" member, per line).
+// Numeric ids are turned into FTS document ids via idFormat, e.g.
+// "doc_%d", string ids are used as they are.
+func readGroundTruthFile(path, idFormat string) ([][]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var truth [][]string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ivecs":
+		truth, err = decodeIvecs(bufio.NewReader(f), idFormat)
+	case ".json":
+		var raw [][]json.RawMessage
+		if err = json.NewDecoder(f).Decode(&raw); err == nil {
+			for i, ids := range raw {
+				row, rerr := groundTruthIDs(ids, idFormat)
+				if rerr != nil {
+					return nil, fmt.Errorf("%s: query %d: %v", path, i+1, rerr)
+				}
+				truth = append(truth, row)
+			}
+		}
+	case ".jsonl", ".ndjson":
+		truth, err = decodeGroundTruthJSONL(f, idFormat)
+	default:
+		err = fmt.Errorf("unsupported file type, use .ivecs, .json or .jsonl")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(truth) == 0 {
+		return nil, fmt.Errorf("%s: no neighbors found", path)
+	}
+	return truth, nil
+}
+
+func decodeIvecs(r io.Reader, idFormat string) ([][]string, error) {
+	var truth [][]string
+	for {
+		var n int32
+		if err := binary.Read(r, binary.LittleEndian, &n); err == io.EOF {
+			return truth, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if n < 0 || n > 1<<20 {
+			return nil, fmt.Errorf("query %d: bad neighbor count %d", len(truth)+1, n)
+		}
+		ids := make([]int32, n)
+		if err := binary.Read(r, binary.LittleEndian, ids); err != nil {
+			return nil, fmt.Errorf("query %d: %v", len(truth)+1, err)
+		}
+		row := make([]string, n)
+		for i, id := range ids {
+			row[i] = fmt.Sprintf(idFormat, id)
+		}
+		truth = append(truth, row)
+	}
+}
+
+func decodeGroundTruthJSONL(r io.Reader, idFormat string) ([][]string, error) {
+	var truth [][]string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var ids []json.RawMessage
+		if line[0] == '{' {
+			var obj struct {
+				Neighbors []json.RawMessage `json:"neighbors"`
+			}
+			if err := json.Unmarshal(line, &obj); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNo, err)
+			}
+			ids = obj.Neighbors
+		} else if err := json.Unmarshal(line, &ids); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		row, err := groundTruthIDs(ids, idFormat)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		truth = append(truth, row)
+	}
+	return truth, scanner.Err()
+}
+
+func groundTruthIDs(ids []json.RawMessage, idFormat string) ([]string, error) {
+	row := make([]string, 0, len(ids))
+	for _, raw := range ids {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			row = append(row, s)
+			continue
+		}
+		var n int64
+		if err := json.Unmarshal(raw, &n); err != nil {
+			return nil, fmt.Errorf("expected a string or integer id, got %s", raw)
+		}
+		row = append(row, fmt.Sprintf(idFormat, n))
+	}
+	return row, nil
+}
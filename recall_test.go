@@ -0,0 +1,145 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRecallAt(t *testing.T) {
+	truth := []string{"a", "b", "c", "d"}
+	expectations := []struct {
+		k    int
+		hits []string
+		out  float64
+	}{
+		{2, []string{"b", "a"}, 1},
+		{2, []string{"a", "x", "b"}, 0.5},
+		{4, []string{"a", "a", "c"}, 0.5},
+		{10, []string{"d", "c", "b", "a"}, 1},
+		{3, nil, 0},
+	}
+	for _, e := range expectations {
+		if got := recallAt(e.k, e.hits, truth); got != e.out {
+			t.Errorf("recall@%d of %v: expected %v, got %v", e.k, e.hits, e.out, got)
+		}
+	}
+}
+
+func TestReadGroundTruthFile(t *testing.T) {
+	want := [][]string{{"doc_3", "doc_1"}, {"doc_2", "doc_0"}}
+	var ivecs bytes.Buffer
+	for _, ids := range [][]int32{{3, 1}, {2, 0}} {
+		binary.Write(&ivecs, binary.LittleEndian, int32(len(ids)))
+		binary.Write(&ivecs, binary.LittleEndian, ids)
+	}
+	expectations := []struct {
+		name, content string
+	}{
+		{"gt.ivecs", ivecs.String()},
+		{"gt.json", `[[3, 1], ["doc_2", 0]]`},
+		{"gt.jsonl", "[3, 1]\n{\"neighbors\": [\"doc_2\", \"doc_0\"]}\n"},
+	}
+	for _, e := range expectations {
+		truth, err := readGroundTruthFile(writeTempFile(t, e.name, e.content), "doc_%d")
+		if err != nil {
+			t.Error(e.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(truth, want) {
+			t.Errorf("%s: expected %v, got %v", e.name, want, truth)
+		}
+	}
+	if _, err := readGroundTruthFile(writeTempFile(t, "gt.jsonl", "[1.5]\n"), "%d"); err == nil {
+		t.Error("expected an error for a fractional id")
+	}
+}
+
+func TestGroundTruthNeedsVectors(t *testing.T) {
+	var conf config
+	err := loadFtsDataFiles(&conf, ftsDataFiles{
+		groundTruth: writeTempFile(t, "gt.json", `[[1]]`), groundTruthIDFormat: "%d",
+	})
+	if err != errGroundTruthWithoutVectors {
+		t.Errorf("expected errGroundTruthWithoutVectors, got %v", err)
+	}
+}
+
+// TestBombardierReportsRecall runs knn queries against a server that
+// answers the vector [v] with the hits "v" and "x", so against the
+// ground truth [v, v+1] every query has a recall@2 of 0.5.
+func TestBombardierReportsRecall(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			var req knnRequest
+			if err := json.Unmarshal(body, &req); err != nil || len(req.Knn) != 1 {
+				t.Errorf("bad knn request %s", body)
+				return
+			}
+			fmt.Fprintf(rw, `{"status": {"total": 1, "successful": 1}, "total_hits": 2, "hits": [{"id": "%v"}, {"id": "x"}]}`,
+				req.Knn[0].Vector[0])
+		}),
+	)
+	defer s.Close()
+
+	numReqs := uint64(20)
+	conf := config{
+		numConns:       defaultNumberOfConns,
+		numReqs:        &numReqs,
+		url:            s.URL,
+		headers:        new(headersList),
+		timeout:        defaultTimeout,
+		method:         "POST",
+		body:           "{" + fts_query_pat + "}",
+		printLatencies: true,
+		clientType:     fhttp,
+		vectors:        [][]float32{{1}, {2}, {3}},
+		groundTruth:    [][]string{{"1", "2"}, {"2", "3"}, {"3", "4"}},
+	}
+	setBuiltinFtsData(&conf)
+	mix := writeTempFile(t, "mix.json", `{"queries": [{"name": "knn", "weight": 1, "params": {"k": 2, "numCandidates": 10}}]}`)
+	conf.queryMixPath = mix
+
+	for _, f := range []format{knownFormat("plain-text"), knownFormat("json")} {
+		conf.format = f
+		b, err := newBombardier(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := new(bytes.Buffer)
+		b.redirectOutputTo(out)
+		b.bombard()
+		if b.recall.Get(0.5) != numReqs {
+			t.Errorf("expected %d queries with recall 0.5, got %d", numReqs, b.recall.Get(0.5))
+		}
+		b.printStats()
+		if f == knownFormat("json") {
+			var res struct {
+				Result struct {
+					Recall struct {
+						Count uint64
+						Mean  float64
+					}
+				}
+			}
+			// skip the progress bar
+			js := out.String()[strings.Index(out.String(), "{\"spec\""):]
+			if err := json.Unmarshal([]byte(js), &res); err != nil {
+				t.Fatalf("%v in %s", err, out)
+			}
+			if res.Result.Recall.Count != numReqs || res.Result.Recall.Mean != 0.5 {
+				t.Errorf("unexpected recall in %s", out)
+			}
+		} else if !strings.Contains(out.String(), "Recall@k over 20 knn queries") {
+			t.Errorf("no recall in %s", out)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Replay sends the requests of a captured JSONL workload, e.g. a query
+// log of an application or the output of --dry-run, instead of building
+// them from -b. The requests go to the target URL's host, the records
+// only give the path and query.
+
+const (
+	replayTimingASAP     = "asap"
+	replayTimingOriginal = "original"
+
+	maxReplayLineSize = 64 * 1024 * 1024
+)
+
+var replayTimings = []string{replayTimingASAP, replayTimingOriginal}
+
+// replayRecord is a line of a --replay file, timestamp is in seconds
+// relative to any fixed point, only its differences are used.
+type replayRecord struct {
+	URL       string            `json:"url"`
+	Method    string            `json:"method"`
+	Headers   map[string]string `json:"headers"`
+	Body      string            `json:"body"`
+	Timestamp *float64          `json:"timestamp"`
+
+	requestURI string
+	path       string
+	rawQuery   string
+	offset     time.Duration
+}
+
+type replayWorkload struct {
+	path    string
+	records []replayRecord
+	// with preserveTiming record i is sent offset after the start, when
+	// the records are sent again it is offset + period later
+	preserveTiming bool
+	period         time.Duration
+
+	n     uint64
+	once  sync.Once
+	start time.Time
+}
+
+func loadReplayFile(path string, timing string) (*replayWorkload, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	w := &replayWorkload{path: path, preserveTiming: timing == replayTimingOriginal}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), maxReplayLineSize)
+	for line := 1; sc.Scan(); line++ {
+		if len(strings.TrimSpace(sc.Text())) == 0 {
+			continue
+		}
+		var rec replayRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("replay %s:%d: %v", path, line, err)
+		}
+		u, err := url.Parse(rec.URL)
+		if err != nil || rec.URL == "" {
+			return nil, fmt.Errorf("replay %s:%d: invalid url %q", path, line, rec.URL)
+		}
+		rec.requestURI, rec.path, rec.rawQuery = u.RequestURI(), u.Path, u.RawQuery
+		if rec.Method == "" {
+			rec.Method = "GET"
+			if rec.Body != "" {
+				rec.Method = "POST"
+			}
+		}
+		if w.preserveTiming && rec.Timestamp == nil {
+			return nil, fmt.Errorf("replay %s:%d: %v", path, line, errReplayNoTimestamp)
+		}
+		w.records = append(w.records, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("replay %s: %v", path, err)
+	}
+	if len(w.records) == 0 {
+		return nil, fmt.Errorf("replay %s: %v", path, errReplayEmpty)
+	}
+
+	if w.preserveTiming {
+		// logs aren't always in order, the records are sent by time
+		sort.SliceStable(w.records, func(i, j int) bool {
+			return *w.records[i].Timestamp < *w.records[j].Timestamp
+		})
+		first := *w.records[0].Timestamp
+		var last time.Duration
+		for i := range w.records {
+			rec := &w.records[i]
+			rec.offset = time.Duration((*rec.Timestamp - first) * float64(time.Second))
+			last = rec.offset
+		}
+		// keep the average rate when the records are sent again
+		w.period = last
+		if n := len(w.records); n > 1 {
+			w.period = last * time.Duration(n) / time.Duration(n-1)
+		}
+	}
+	return w, nil
+}
+
+// next returns the record to send next and when to send it, the zero
+// time if it can go out right away.
+func (w *replayWorkload) next() (*replayRecord, time.Time) {
+	i := atomic.AddUint64(&w.n, 1) - 1
+	n := uint64(len(w.records))
+	rec := &w.records[i%n]
+	if !w.preserveTiming {
+		return rec, time.Time{}
+	}
+	w.once.Do(func() { w.start = time.Now() })
+	return rec, w.start.Add(time.Duration(i/n)*w.period + rec.offset)
+}
+
+func (w *replayWorkload) describe() string {
+	timing := "as fast as possible"
+	if w.preserveTiming {
+		timing = "preserving the original timing"
+	}
+	return fmt.Sprintf("Replaying %d request(s) from %s %s", len(w.records), w.path, timing)
+}
+
+// setReplayHeaders sets the headers of rec on top of the -H ones, the
+// Host and Content-Length of the capture don't apply to the target.
+func setReplayHeaders(rec *replayRecord, set func(key, value string)) {
+	for k, v := range rec.Headers {
+		if !strings.EqualFold(k, "Host") && !strings.EqualFold(k, "Content-Length") {
+			set(k, v)
+		}
+	}
+}
+
+// waitUntil sleeps until due, a zero due does not wait.
+func waitUntil(due time.Time) {
+	if !due.IsZero() {
+		if d := time.Until(due); d > 0 {
+			time.Sleep(d)
+		}
+	}
+}
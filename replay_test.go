@@ -0,0 +1,147 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testReplayJSONL = `{"url": "http://app-host:8094/api/index/a/query", "method": "POST", "headers": {"X-Tenant": "t1", "Host": "app-host"}, "body": "{\"query\": {\"match\": \"one\"}}", "timestamp": 100.0}
+
+{"url": "/api/index/b/query?x=1", "body": "{\"query\": {\"match\": \"two\"}}", "timestamp": 100.1}
+{"url": "/api/index/c", "timestamp": 100.2}
+`
+
+func TestLoadReplayFile(t *testing.T) {
+	w, err := loadReplayFile(writeTempFile(t, "replay.jsonl", testReplayJSONL), replayTimingOriginal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(w.records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(w.records))
+	}
+	expectations := []struct {
+		method, requestURI string
+		offset             time.Duration
+	}{
+		{"POST", "/api/index/a/query", 0},
+		{"POST", "/api/index/b/query?x=1", 100 * time.Millisecond},
+		{"GET", "/api/index/c", 200 * time.Millisecond},
+	}
+	for i, e := range expectations {
+		rec := w.records[i]
+		if rec.Method != e.method || rec.requestURI != e.requestURI || (rec.offset-e.offset).Abs() > time.Millisecond {
+			t.Errorf("record %d: expected %v, got %s %s %v", i, e, rec.Method, rec.requestURI, rec.offset)
+		}
+	}
+	if (w.period - 300*time.Millisecond).Abs() > time.Millisecond {
+		t.Errorf("expected a period of 300ms, got %v", w.period)
+	}
+
+	// the fourth request is the first record again one period later
+	var dues []time.Time
+	for i := 0; i < 4; i++ {
+		_, due := w.next()
+		dues = append(dues, due)
+	}
+	if d := dues[3].Sub(dues[0]); (d - 300*time.Millisecond).Abs() > time.Millisecond {
+		t.Errorf("expected the records again after 300ms, got %v", d)
+	}
+
+	// out of order records are sent by time
+	unordered := `{"url": "/b", "timestamp": 100.2}` + "\n" + `{"url": "/a", "timestamp": 100.0}` + "\n"
+	w, err = loadReplayFile(writeTempFile(t, "unordered.jsonl", unordered), replayTimingOriginal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a, b := w.records[0], w.records[1]; a.path != "/a" || a.offset != 0 || b.path != "/b" || (b.offset-200*time.Millisecond).Abs() > time.Millisecond {
+		t.Errorf("expected /a at 0 and /b at 200ms, got %s %v and %s %v", a.path, a.offset, b.path, b.offset)
+	}
+
+	bad := []struct {
+		content, timing string
+	}{
+		{"", replayTimingASAP},
+		{"not json\n", replayTimingASAP},
+		{`{"body": "{}"}` + "\n", replayTimingASAP},
+		{`{"url": "/a"}` + "\n", replayTimingOriginal},
+	}
+	for _, b := range bad {
+		if _, err := loadReplayFile(writeTempFile(t, "bad.jsonl", b.content), b.timing); err == nil {
+			t.Errorf("expected an error for %q", b.content)
+		}
+	}
+}
+
+func TestBombardierReplays(t *testing.T) {
+	testAllClients(t, testBombardierReplays)
+}
+
+func testBombardierReplays(clientType clientTyp, t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen = map[string]int{}
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			if r.Host == "app-host" || (r.URL.Path == "/api/index/a/query" && r.Header.Get("X-Tenant") != "t1") {
+				t.Errorf("unexpected host %s or headers %v", r.Host, r.Header)
+			}
+			mu.Lock()
+			seen[r.Method+" "+r.URL.RequestURI()+" "+string(body)]++
+			mu.Unlock()
+			rw.Write([]byte(`{"status": {"total": 1, "successful": 1}, "total_hits": 0, "hits": []}`))
+		}),
+	)
+	defer s.Close()
+
+	for _, timing := range replayTimings {
+		seen = map[string]int{}
+		w, err := loadReplayFile(writeTempFile(t, "replay.jsonl", testReplayJSONL), timing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		numReqs := uint64(6)
+		b, err := newBombardier(config{
+			numConns:   2,
+			numReqs:    &numReqs,
+			url:        s.URL,
+			headers:    new(headersList),
+			timeout:    defaultTimeout,
+			method:     "GET",
+			clientType: clientType,
+			format:     knownFormat("plain-text"),
+			replay:     w,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.disableOutput()
+		begin := time.Now()
+		b.bombard()
+		took := time.Since(begin)
+
+		expected := []string{
+			`POST /api/index/a/query {"query": {"match": "one"}}`,
+			`POST /api/index/b/query?x=1 {"query": {"match": "two"}}`,
+			`GET /api/index/c `,
+		}
+		for _, e := range expected {
+			if seen[e] != 2 {
+				t.Errorf("%s: expected %q twice, got %v", timing, e, seen)
+			}
+		}
+		// the last request is due 500ms after the first
+		if timing == replayTimingOriginal && took < 500*time.Millisecond {
+			t.Errorf("expected the original timing to take at least 500ms, took %v", took)
+		}
+		if !strings.Contains(w.describe(), "3 request(s)") {
+			t.Errorf("unexpected description %q", w.describe())
+		}
+	}
+}
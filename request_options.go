@@ -0,0 +1,278 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"gopkg.in/yaml.v3"
+)
+
+// requestOptions are top level members of an FTS search request (score,
+// explain, highlight, fields, includeLocations, sort, ctl, ...) merged
+// into every generated request, they replace the members of the -b body
+// except ctl which is merged member by member.
+type requestOptions struct {
+	members map[string]json.RawMessage
+	// names are the members in the order they are merged
+	names []string
+	// ctl are the members of an object ctl merged into the body's ctl
+	ctl *requestOptions
+}
+
+func newRequestOptionMembers(members map[string]json.RawMessage) *requestOptions {
+	o := &requestOptions{members: members}
+	for name := range members {
+		o.names = append(o.names, name)
+	}
+	sort.Strings(o.names)
+	return o
+}
+
+// requestOptionFlags are the request option shortcuts of the command
+// line, set ones win over the --requestOptions file.
+type requestOptionFlags struct {
+	path             string
+	score            string
+	explain          bool
+	highlight        string
+	highlightFields  string
+	fields           string
+	includeLocations bool
+	sort             string
+	ctlTimeout       time.Duration
+}
+
+var highlightStyles = []string{"html", "ansi"}
+
+// loadRequestOptionsFile reads a JSON object or, unless the file ends in
+// .json, a YAML mapping of request members, e.g.
+//
+//	score: none
+//	highlight: {style: html, fields: [reviews.content]}
+//	ctl: {timeout: 5000}
+func loadRequestOptionsFile(path string) (map[string]json.RawMessage, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var opts map[string]interface{}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(data, &opts)
+	} else {
+		err = yaml.Unmarshal(data, &opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("request options %s: %v", path, err)
+	}
+	members := make(map[string]json.RawMessage, len(opts))
+	for name, v := range opts {
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("request options %s: %s: %v", path, name, err)
+		}
+		members[name] = raw
+	}
+	return members, nil
+}
+
+// newRequestOptions builds the overlay of the file and the flags, it is
+// nil if no option is set.
+func newRequestOptions(f requestOptionFlags) (*requestOptions, error) {
+	members := map[string]json.RawMessage{}
+	if f.path != "" {
+		var err error
+		if members, err = loadRequestOptionsFile(f.path); err != nil {
+			return nil, err
+		}
+	}
+	set := func(name string, v interface{}) {
+		members[name], _ = json.Marshal(v)
+	}
+	switch f.score {
+	case "":
+	case "none":
+		set("score", f.score)
+	default:
+		return nil, errInvalidScore
+	}
+	if f.explain {
+		set("explain", true)
+	}
+	if f.highlight != "" || f.highlightFields != "" {
+		if f.highlight != "" && !containsString(highlightStyles, f.highlight) {
+			return nil, errInvalidHighlightStyle
+		}
+		hl := map[string]interface{}{}
+		if f.highlight != "" {
+			hl["style"] = f.highlight
+		}
+		if f.highlightFields != "" {
+			hl["fields"] = splitList(f.highlightFields)
+		}
+		set("highlight", hl)
+	}
+	if f.fields != "" {
+		set("fields", splitList(f.fields))
+	}
+	if f.includeLocations {
+		set("includeLocations", true)
+	}
+	if f.sort != "" {
+		if !json.Valid([]byte(f.sort)) || !strings.HasPrefix(strings.TrimSpace(f.sort), "[") {
+			return nil, errInvalidSort
+		}
+		members["sort"] = json.RawMessage(f.sort)
+	}
+	if f.ctlTimeout < 0 {
+		return nil, errNegativeCtlTimeout
+	}
+	if f.ctlTimeout > 0 {
+		ctl, err := mergeJSONObjects(members["ctl"], json.RawMessage(
+			fmt.Sprintf("{\"timeout\": %d}", f.ctlTimeout.Milliseconds())))
+		if err != nil {
+			return nil, fmt.Errorf("request options: ctl: %v", err)
+		}
+		members["ctl"] = ctl
+	}
+	if len(members) == 0 {
+		return nil, nil
+	}
+	o := newRequestOptionMembers(members)
+	var ctl map[string]json.RawMessage
+	if json.Unmarshal(members["ctl"], &ctl) == nil && ctl != nil {
+		o.ctl = newRequestOptionMembers(ctl)
+	}
+	return o, nil
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+// mergeJSONObjects returns base with the members of overlay set, either
+// may be empty.
+func mergeJSONObjects(base, overlay json.RawMessage) (json.RawMessage, error) {
+	merged := map[string]json.RawMessage{}
+	if len(base) > 0 {
+		if err := json.Unmarshal(base, &merged); err != nil {
+			return nil, err
+		}
+	}
+	if len(overlay) > 0 {
+		var o map[string]json.RawMessage
+		if err := json.Unmarshal(overlay, &o); err != nil {
+			return nil, err
+		}
+		for k, v := range o {
+			merged[k] = v
+		}
+	}
+	return marshalNoEscape(merged)
+}
+
+func marshalNoEscape(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// apply returns body with the options merged into its text, members of
+// body are replaced in place (ctl member by member) and the others are
+// added at the end. This keeps the order of body and saves decoding it
+// for every request, newBombardier makes sure it is a JSON object.
+func (o *requestOptions) apply(body string) string {
+	if o == nil {
+		return body
+	}
+	for _, name := range o.names {
+		v := string(o.members[name])
+		old := gjson.Get(body, name)
+		if !old.Exists() {
+			body = addMember(body, name, v)
+			continue
+		}
+		if name == "ctl" && o.ctl != nil && old.IsObject() {
+			v = o.ctl.apply(old.Raw)
+		}
+		body = body[:old.Index] + v + body[old.Index+len(old.Raw):]
+	}
+	return body
+}
+
+// addMember adds the member name with the JSON value v at the end of the
+// JSON object obj, text that isn't one is returned as is.
+func addMember(obj, name, v string) string {
+	end := strings.LastIndexByte(obj, '}')
+	if end < 0 {
+		return obj
+	}
+	member := jsonString(name) + ": " + v
+	if !strings.HasSuffix(strings.TrimSpace(obj[:end]), "{") {
+		member = ", " + member
+	}
+	return obj[:end] + member + obj[end:]
+}
+
+// has tells whether the options set the member name.
+func (o *requestOptions) has(name string) bool {
+	if o == nil {
+		return false
+	}
+	_, ok := o.members[name]
+	return ok
+}
+
+// sampleRequest is the body with __FTS_QUERY__ replaced by a query, to
+// look at the FTS requests before generating them.
+func sampleRequest(body string) string {
+	return strings.ReplaceAll(body, fts_query_pat, "\"query\": {}")
+}
+
+// setsMember tells whether the body with __FTS_QUERY__ or the options
+// set the top level member name of the FTS requests.
+func setsMember(body string, o *requestOptions, name string) bool {
+	return gjson.Get(sampleRequest(body), name).Exists() || o.has(name)
+}
+
+// checkBody makes sure the options can be merged into the FTS requests
+// of the body with __FTS_QUERY__.
+func (o *requestOptions) checkBody(body string) error {
+	if o == nil || !strings.Contains(body, fts_query_pat) {
+		return nil
+	}
+	request := sampleRequest(body)
+	if !gjson.Valid(request) || !gjson.Parse(request).IsObject() {
+		return errOptionsNeedJSONBody
+	}
+	return nil
+}
+
+// String is the option set as a JSON object with sorted members as
+// recorded in the results, "" if there are no options.
+func (o *requestOptions) String() string {
+	if o == nil {
+		return ""
+	}
+	data, err := marshalNoEscape(o.members)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
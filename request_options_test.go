@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewRequestOptions(t *testing.T) {
+	path := writeTempFile(t, "options.yaml", "score: none\nctl: {consistency: {level: at_plus}, timeout: 1000}\n")
+	opts, err := newRequestOptions(requestOptionFlags{
+		path:             path,
+		explain:          true,
+		highlight:        "html",
+		highlightFields:  "reviews.content, name",
+		fields:           "*",
+		includeLocations: true,
+		sort:             `["-_score", "_id"]`,
+		ctlTimeout:       5 * time.Second,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"ctl":{"consistency":{"level":"at_plus"},"timeout":5000},"explain":true,"fields":["*"],` +
+		`"highlight":{"fields":["reviews.content","name"],"style":"html"},"includeLocations":true,` +
+		`"score":"none","sort":["-_score","_id"]}`
+	if opts.String() != expected {
+		t.Errorf("expected %s, got %s", expected, opts)
+	}
+
+	if opts, err := newRequestOptions(requestOptionFlags{}); opts != nil || err != nil {
+		t.Errorf("expected no options, got %v %v", opts, err)
+	}
+	if opts.String() == "" || (*requestOptions)(nil).String() != "" {
+		t.Error("unexpected String of options")
+	}
+
+	bad := []struct {
+		flags requestOptionFlags
+		err   error
+	}{
+		{requestOptionFlags{score: "bm25"}, errInvalidScore},
+		{requestOptionFlags{highlight: "bold"}, errInvalidHighlightStyle},
+		{requestOptionFlags{sort: `{"by": "id"}`}, errInvalidSort},
+		{requestOptionFlags{ctlTimeout: -time.Second}, errNegativeCtlTimeout},
+	}
+	for _, b := range bad {
+		if _, err := newRequestOptions(b.flags); err != b.err {
+			t.Errorf("expected %v, got %v", b.err, err)
+		}
+	}
+}
+
+func TestRequestOptionsApply(t *testing.T) {
+	opts, err := newRequestOptions(requestOptionFlags{score: "none", sort: `["_id"]`, ctlTimeout: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := opts.apply(`{"query": {"match": "a&b"}, "size": 10, "sort": ["-_score"], "ctl": {"consistency": {"level": ""}}}`)
+	var req map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatalf("%v in %s", err, body)
+	}
+	if req["score"] != "none" || req["size"] != float64(10) || !strings.Contains(body, `"a&b"`) {
+		t.Errorf("unexpected body %s", body)
+	}
+	if sort := req["sort"].([]interface{}); len(sort) != 1 || sort[0] != "_id" {
+		t.Errorf("expected the sort to be replaced, got %s", body)
+	}
+	if ctl := req["ctl"].(map[string]interface{}); ctl["timeout"] != float64(1000) || ctl["consistency"] == nil {
+		t.Errorf("expected the ctl members to be merged, got %s", body)
+	}
+	// merged as text, the body keeps its order
+	expected := `{"query": {"match": "a&b"}, "size": 10, "sort": ["_id"], "ctl": {"consistency": {"level": ""}, "timeout": 1000}, "score": "none"}`
+	if body != expected {
+		t.Errorf("expected %s, got %s", expected, body)
+	}
+	if got := opts.apply(`{}`); got != `{"ctl": {"timeout":1000}, "score": "none", "sort": ["_id"]}` {
+		t.Errorf("unexpected members added to an empty body: %s", got)
+	}
+
+	if got := opts.apply("not json"); got != "not json" {
+		t.Errorf("expected a non JSON body to be kept, got %s", got)
+	}
+	if got := (*requestOptions)(nil).apply("{}"); got != "{}" {
+		t.Errorf("expected nil options to keep the body, got %s", got)
+	}
+}
+
+func TestRequestOptionsCheckBody(t *testing.T) {
+	opts, err := newRequestOptions(requestOptionFlags{score: "none"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for body, valid := range map[string]bool{
+		`{` + fts_query_pat + `, "size": 10}`: true,
+		`no FTS query`:                        true,
+		`[{` + fts_query_pat + `}]`:           false,
+		fts_query_pat:                         false,
+	} {
+		if err := opts.checkBody(body); (err == nil) != valid {
+			t.Errorf("%s: unexpected %v", body, err)
+		}
+	}
+	if err := (*requestOptions)(nil).checkBody(fts_query_pat); err != nil {
+		t.Errorf("expected no check without options, got %v", err)
+	}
+}
+
+func TestSetsMember(t *testing.T) {
+	body := `{` + fts_query_pat + `, "size": 10, "sort": ["-_score"]}`
+	if !setsMember(body, nil, "sort") || setsMember(body, nil, "facets") {
+		t.Errorf("expected only the sort of %s", body)
+	}
+	opts, err := newRequestOptions(requestOptionFlags{sort: `["_id"]`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !setsMember(`{`+fts_query_pat+`}`, opts, "sort") {
+		t.Error("expected the sort of the options")
+	}
+}
+
+func TestBombardierAppliesRequestOptions(t *testing.T) {
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			var req struct {
+				Query   json.RawMessage `json:"query"`
+				Score   string          `json:"score"`
+				Explain bool            `json:"explain"`
+			}
+			if err := json.Unmarshal(body, &req); err != nil || req.Query == nil || req.Score != "none" || !req.Explain {
+				t.Errorf("expected a query with the request options, got %s", body)
+			}
+			rw.Write([]byte(`{"status": {"total": 1, "successful": 1}, "total_hits": 0, "hits": []}`))
+		}),
+	)
+	defer s.Close()
+
+	numReqs := uint64(10)
+	conf := config{
+		numConns:   defaultNumberOfConns,
+		numReqs:    &numReqs,
+		url:        s.URL,
+		headers:    new(headersList),
+		timeout:    defaultTimeout,
+		method:     "POST",
+		body:       "{" + fts_query_pat + ", \"size\": 5}",
+		clientType: fhttp,
+		format:     knownFormat("json"),
+	}
+	setBuiltinFtsData(&conf)
+	opts, err := newRequestOptions(requestOptionFlags{score: "none", explain: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.requestOptions = opts
+	b, err := newBombardier(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := new(bytes.Buffer)
+	b.redirectOutputTo(out)
+	b.bombard()
+	b.printStats()
+
+	var res struct {
+		Spec struct {
+			RequestOptions map[string]interface{}
+		}
+	}
+	// skip the progress bar
+	js := out.String()[strings.Index(out.String(), "{\"spec\""):]
+	if err := json.Unmarshal([]byte(js), &res); err != nil {
+		t.Fatalf("%v in %s", err, out)
+	}
+	if res.Spec.RequestOptions["score"] != "none" || res.Spec.RequestOptions["explain"] != true {
+		t.Errorf("expected the request options in %s", out)
+	}
+}
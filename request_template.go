@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"text/template"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// With --template the body and the path and query of the URL are Go
+// text/templates evaluated for every request, e.g.
+//
+//	{"query": {"term": {{ RandWord "commonReviewWords" | JSON }}, "field": "reviews.content"},
+//	 "size": {{ RandInt 10 50 }}, "ctl": {"client_context_id": "{{ Seq "req" }}-{{ UUIDV4 }}"}}
+//
+// the random functions draw from the query stream of the connection
+// so templated requests are reproducible with --seed.
+
+// uuidTemplateFuncs are shared by the output and the request templates.
+var uuidTemplateFuncs = template.FuncMap{
+	"UUIDV1": uuid.NewV1,
+	"UUIDV2": uuid.NewV2,
+	"UUIDV3": uuid.NewV3,
+	"UUIDV4": uuid.NewV4,
+	"UUIDV5": uuid.NewV5,
+}
+
+// requestTemplates holds the parsed templates, a connection evaluates
+// its own copy bound to its random stream via forWorker.
+type requestTemplates struct {
+	body, uri *template.Template
+
+	mu   sync.Mutex
+	seqs map[string]*uint64
+}
+
+// requestURIOf returns the path and query of rawURL with the template
+// actions unescaped, tryParseURL escapes the braces and spaces of the
+// path.
+func requestURIOf(rawURL string) string {
+	rest := rawURL
+	if i := strings.Index(rest, "://"); i >= 0 {
+		rest = rest[i+3:]
+	}
+	i := strings.IndexAny(rest, "/?")
+	if i < 0 {
+		return "/"
+	}
+	path, query := rest[i:], ""
+	if j := strings.IndexByte(path, '?'); j >= 0 {
+		path, query = path[:j], path[j:]
+	}
+	if p, err := url.PathUnescape(path); err == nil {
+		path = p
+	}
+	if path == "" {
+		path = "/"
+	}
+	return path + query
+}
+
+// newRequestTemplates parses body and the request URI of rawURL, the
+// ones without actions are left as they are.
+func newRequestTemplates(conf config, mix *queryMix, body, rawURL string) (*requestTemplates, error) {
+	t := &requestTemplates{seqs: map[string]*uint64{}}
+	funcs := t.funcs(conf, mix)
+	if strings.Contains(body, "{{") {
+		tmpl, err := template.New("body").Funcs(funcs).Parse(body)
+		if err != nil {
+			return nil, fmt.Errorf("body template: %v", err)
+		}
+		t.body = tmpl
+	}
+	if uri := requestURIOf(rawURL); strings.Contains(uri, "{{") {
+		tmpl, err := template.New("url").Funcs(funcs).Parse(uri)
+		if err != nil {
+			return nil, fmt.Errorf("url template: %v", err)
+		}
+		t.uri = tmpl
+	}
+	return t, nil
+}
+
+// seq returns the next number, starting at 1, of the named sequence,
+// the sequences are shared by all connections.
+func (t *requestTemplates) seq(name string) uint64 {
+	t.mu.Lock()
+	n, ok := t.seqs[name]
+	if !ok {
+		n = new(uint64)
+		t.seqs[name] = n
+	}
+	t.mu.Unlock()
+	return atomic.AddUint64(n, 1)
+}
+
+func (t *requestTemplates) funcs(conf config, mix *queryMix) template.FuncMap {
+	r := conf.rnd()
+	funcs := template.FuncMap{
+		"RandInt": func(min, max int) int {
+			return randIntFromRange(r, min, max)
+		},
+		"RandFloat": func(min, max float64) float64 {
+			return min + r.Float64()*(max-min)
+		},
+		"RandWord": func(list string) (string, error) {
+			words := conf.wordList(list)
+			if len(words) == 0 {
+				return "", fmt.Errorf("no word list %s", list)
+			}
+			return words[r.Intn(len(words))], nil
+		},
+		"Seq": func(name string) int {
+			return int(t.seq(name))
+		},
+		"Now": time.Now,
+		"Timestamp": func(layout string) string {
+			return time.Now().Format(layout)
+		},
+		"UnixMillis": func() int64 {
+			return time.Now().UnixNano() / int64(time.Millisecond)
+		},
+		"CSVRow": func(name string) (map[string]string, error) {
+			table, ok := conf.dataTables[name]
+			if !ok {
+				return nil, fmt.Errorf("no --csv table %s", name)
+			}
+			return table.row(r.Intn(len(table.rows))), nil
+		},
+		"CSVRowAt": func(name string, i int) (map[string]string, error) {
+			table, ok := conf.dataTables[name]
+			if !ok {
+				return nil, fmt.Errorf("no --csv table %s", name)
+			}
+			return table.row(i), nil
+		},
+		"FTSQuery": func() string {
+			return mix.generate(conf)
+		},
+		"JSON": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}
+	for name, f := range uuidTemplateFuncs {
+		funcs[name] = f
+	}
+	return funcs
+}
+
+// workerTemplates are the templates of one connection.
+type workerTemplates struct {
+	body, uri *template.Template
+}
+
+// forWorker binds a copy of the templates to the random stream of conf.
+func (t *requestTemplates) forWorker(conf config, mix *queryMix) *workerTemplates {
+	funcs := t.funcs(conf, mix)
+	wt := new(workerTemplates)
+	if t.body != nil {
+		wt.body = template.Must(t.body.Clone()).Funcs(funcs)
+	}
+	if t.uri != nil {
+		wt.uri = template.Must(t.uri.Clone()).Funcs(funcs)
+	}
+	return wt
+}
+
+func executeTemplate(t *template.Template) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, nil); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRequestURIOf(t *testing.T) {
+	expectations := []struct {
+		in, out string
+	}{
+		{"http://localhost:8094", "/"},
+		{"http://localhost:8094?a=1", "/?a=1"},
+		{"http://localhost:8094/api/index/%7B%7B%20Seq%20%22i%22%20%7D%7D/query", `/api/index/{{ Seq "i" }}/query`},
+		{"http://localhost:8094/api/{{x}}?q={{ RandInt 1 2 }}", "/api/{{x}}?q={{ RandInt 1 2 }}"},
+	}
+	for _, e := range expectations {
+		if out := requestURIOf(e.in); out != e.out {
+			t.Errorf("%s: expected %q, got %q", e.in, e.out, out)
+		}
+	}
+}
+
+func templateConf(t *testing.T, numReqs *uint64, body, rawURL string) config {
+	conf := dryRunConf(numReqs)
+	conf.url = rawURL
+	conf.body = body
+	conf.urlSeqs = nil
+	conf.template = true
+	table, err := readCSVTable(writeTempFile(t, "users.csv", "id,name\n7,alice\n8,bob\n9,carol\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf.dataTables = map[string]*dataTable{"users": table}
+	return conf
+}
+
+func TestTemplateDryRun(t *testing.T) {
+	numReqs := uint64(30)
+	body := `{"size": {{ RandInt 10 20 }}, "seq": {{ Seq "req" }}, "user": {{ (CSVRow "users").name | JSON }},` +
+		` "term": {{ RandWord "commonReviewWords" | JSON }}, "f": {{ RandFloat 0 1 }}, "id": "{{ UUIDV4 }}"}`
+	rawURL := `http://localhost:8094/api/index/u{{ (CSVRowAt "users" (Seq "u")).id }}/query?t={{ UnixMillis }}`
+	run := func() string {
+		b, err := newBombardier(templateConf(t, &numReqs, body, rawURL))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := new(bytes.Buffer)
+		if err := b.dryRun(out, numReqs); err != nil {
+			t.Fatal(err)
+		}
+		return out.String()
+	}
+
+	seqs := map[int]bool{}
+	sizes := map[int]bool{}
+	uris := map[string]bool{}
+	sc := bufio.NewScanner(strings.NewReader(run()))
+	for sc.Scan() {
+		var dr dryRunRequest
+		if err := json.Unmarshal(sc.Bytes(), &dr); err != nil {
+			t.Fatalf("%v in %s", err, sc.Text())
+		}
+		var b struct {
+			Size, Seq int
+			User      string
+			F         float64
+		}
+		if err := json.Unmarshal([]byte(dr.Body), &b); err != nil {
+			t.Fatalf("%v in body %s", err, dr.Body)
+		}
+		if b.Size < 10 || b.Size > 20 || b.F < 0 || b.F >= 1 {
+			t.Errorf("out of range size or float in %s", dr.Body)
+		}
+		if b.User != "alice" && b.User != "bob" && b.User != "carol" {
+			t.Errorf("unexpected user in %s", dr.Body)
+		}
+		seqs[b.Seq] = true
+		sizes[b.Size] = true
+		uris[dr.URL[:strings.Index(dr.URL, "?")]] = true
+	}
+	for i := 1; i <= int(numReqs); i++ {
+		if !seqs[i] {
+			t.Errorf("expected sequence number %d, got %v", i, seqs)
+		}
+	}
+	if len(sizes) < 2 {
+		t.Errorf("expected random sizes, got %v", sizes)
+	}
+	for _, id := range []string{"7", "8", "9"} {
+		if u := "http://localhost:8094/api/index/u" + id + "/query"; !uris[u] {
+			t.Errorf("expected %s, got %v", u, uris)
+		}
+	}
+
+	// the random parts are reproducible with the seed
+	fixed := `{"size": {{ RandInt 10 1000 }}, "term": {{ RandWord "commonReviewWords" | JSON }}}`
+	body, rawURL = fixed, "http://localhost:8094/api/index/u{{ RandInt 1 1000 }}/query"
+	if first := run(); run() != first {
+		t.Error("expected the same templated requests for the same seed")
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	numReqs := uint64(1)
+	bad := []struct {
+		body, url string
+	}{
+		{`{"size": {{ RandInt 1 }`, "http://localhost:8094/"},
+		{`{}`, "http://localhost:8094/{{ Nope }}"},
+	}
+	for _, c := range bad {
+		if _, err := newBombardier(templateConf(t, &numReqs, c.body, c.url)); err == nil {
+			t.Errorf("expected a parse error for %q %q", c.body, c.url)
+		}
+	}
+
+	conf := templateConf(t, &numReqs, `{{ (CSVRow "missing").id }}`, "http://localhost:8094/")
+	b, err := newBombardier(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.dryRun(new(bytes.Buffer), numReqs); err == nil {
+		t.Error("expected an error for a missing table")
+	}
+
+	conf = templateConf(t, &numReqs, "{}", "http://localhost:8094/")
+	conf.stream = true
+	if _, err := newBombardier(conf); err != errTemplateNeedsBody {
+		t.Errorf("expected %v, got %v", errTemplateNeedsBody, err)
+	}
+}
+
+func TestBombardierSendsTemplates(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		seen = map[string]bool{}
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			mu.Lock()
+			seen[r.URL.Path+" "+string(body)] = true
+			mu.Unlock()
+			rw.Write([]byte(`{"status": {"total": 1, "successful": 1}, "total_hits": 0, "hits": []}`))
+		}),
+	)
+	defer s.Close()
+
+	numReqs := uint64(10)
+	conf := templateConf(t, &numReqs, `{"n": {{ Seq "n" }}}`, s.URL+`/index/{{ Seq "p" }}`)
+	b, err := newBombardier(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.disableOutput()
+	b.bombard()
+
+	for i := 1; i <= int(numReqs); i++ {
+		found := false
+		for k := range seen {
+			if strings.HasSuffix(k, ` {"n": `+strconv.Itoa(i)+`}`) {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected body %d to be sent, got %v", i, seen)
+		}
+	}
+	if len(seen) != int(numReqs) {
+		t.Errorf("expected %d distinct requests, got %v", numReqs, seen)
+	}
+}
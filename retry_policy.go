@@ -0,0 +1,337 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	uhist "github.com/codesenberg/concurrent/uint64/histogram"
+	"github.com/jon-strabala/fasthttp"
+
+	"cb_fts_bench/internal"
+)
+
+// The retry policy decides which requests are sent again and how long to
+// back off before: --retryOn lists the HTTP statuses and the transport
+// error classes to retry, retry n waits --minBackoff *
+// --backoffMultiplier^(n-1) capped at --maxBackoff (a 0 multiplier is the
+// ladder used before the retry flags: x4 for retries 2 and 3, x2 up to
+// retry 7 and x1.2 after), with full jitter a random delay up to that and
+// with decorrelated jitter a random delay between --minBackoff and
+// --backoffMultiplier times the previous one. A longer Retry-After of the
+// response is waited instead (still capped at --maxBackoff). A request is
+// sent at most --maxAttempts times.
+
+const (
+	retryOnTimeout    = "timeout"
+	retryOnConnection = "connection"
+	retryOnError      = "error"
+
+	jitterNone         = "none"
+	jitterFull         = "full"
+	jitterDecorrelated = "decorrelated"
+
+	defaultRetryOn      = "429"
+	defaultMinBackoffUs = 500
+	defaultMaxBackoff   = time.Second
+	defaultMaxAttempts  = 19
+)
+
+var (
+	jitterModes = []string{jitterNone, jitterFull, jitterDecorrelated}
+	// errorClasses are the --retryOn transport error classes, error is
+	// any transport error
+	errorClasses = []string{retryOnTimeout, retryOnConnection, retryOnError}
+)
+
+type retryPolicy struct {
+	statuses     map[int]bool
+	errorClasses map[string]bool
+
+	minBackoff, maxBackoff time.Duration
+	multiplier             float64
+	jitter                 string
+	maxAttempts            int
+	ignoreRetryAfter       bool
+}
+
+// minBackoffOf returns the --minBackoff delay of ms, 0 is 0.5 ms.
+func minBackoffOf(ms int) time.Duration {
+	if ms == 0 {
+		return defaultMinBackoffUs * time.Microsecond
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// newRetryPolicy checks the backoff settings of p and adds the statuses
+// and error classes of the comma separated retryOn, "" retries nothing. A
+// maxBackoff below minBackoff is raised to it so that a large --minBackoff
+// alone still works.
+func newRetryPolicy(retryOn string, p retryPolicy) (*retryPolicy, error) {
+	if p.minBackoff < 0 || p.maxBackoff < 0 || (p.multiplier != 0 && p.multiplier < 1) {
+		return nil, errInvalidBackoff
+	}
+	if p.maxBackoff < p.minBackoff {
+		p.maxBackoff = p.minBackoff
+	}
+	if p.maxAttempts < 1 {
+		return nil, errInvalidMaxAttempts
+	}
+	if p.jitter == "" {
+		p.jitter = jitterNone
+	}
+	p.statuses, p.errorClasses = map[int]bool{}, map[string]bool{}
+	for _, s := range strings.Split(retryOn, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		if containsString(errorClasses, s) {
+			p.errorClasses[s] = true
+			continue
+		}
+		code, err := strconv.Atoi(s)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("%v: %q", errInvalidRetryOn, s)
+		}
+		p.statuses[code] = true
+	}
+	return &p, nil
+}
+
+// defaultRetryPolicy retries HTTP 429 the way it is done without any
+// retry flags, with the legacy ladder from minBackoffMs up to 1s.
+func defaultRetryPolicy(minBackoffMs int) *retryPolicy {
+	p, _ := newRetryPolicy(defaultRetryOn, retryPolicy{
+		minBackoff:  minBackoffOf(minBackoffMs),
+		maxBackoff:  defaultMaxBackoff,
+		maxAttempts: defaultMaxAttempts,
+	})
+	return p
+}
+
+// growth returns how much the backoff grows from retry n-1 to retry n.
+func (rp *retryPolicy) growth(n int) float64 {
+	if rp.multiplier != 0 {
+		return rp.multiplier
+	}
+	switch {
+	case n < 2:
+		return 1
+	case n < 4:
+		return 4
+	case n < 8:
+		return 2
+	}
+	return 1.2
+}
+
+// reasons returns what the policy retries, the statuses in order and then
+// the error classes.
+func (rp *retryPolicy) reasons() []string {
+	codes := make([]int, 0, len(rp.statuses))
+	for code := range rp.statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	reasons := make([]string, 0, len(codes)+len(errorClasses))
+	for _, code := range codes {
+		reasons = append(reasons, strconv.Itoa(code))
+	}
+	for _, class := range errorClasses {
+		if rp.errorClasses[class] {
+			reasons = append(reasons, class)
+		}
+	}
+	return reasons
+}
+
+// retryReason returns why a response with code or the transport error
+// err is retried, "" if it isn't.
+func (rp *retryPolicy) retryReason(code int, err error) string {
+	if err != nil {
+		class := errorClass(err)
+		if rp.errorClasses[class] || rp.errorClasses[retryOnError] {
+			return class
+		}
+		return ""
+	}
+	if rp.statuses[code] {
+		return strconv.Itoa(code)
+	}
+	return ""
+}
+
+// errorClass returns the --retryOn class of the transport error err.
+func errorClass(err error) string {
+	var te interface{ Timeout() bool }
+	switch {
+	case errors.As(err, &te) && te.Timeout(), errors.Is(err, fasthttp.ErrDialTimeout):
+		return retryOnTimeout
+	case errors.Is(err, fasthttp.ErrConnectionClosed), errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.EPIPE):
+		return retryOnConnection
+	}
+	return retryOnError
+}
+
+// backoff returns the delay before retry n (1 for the first), prev is the
+// delay before the previous retry and retryAfter the Retry-After header
+// of the response.
+func (rp *retryPolicy) backoff(n int, prev time.Duration, retryAfter string, r randGen) time.Duration {
+	min, max := float64(rp.minBackoff), float64(rp.maxBackoff)
+	// exp is the capped exponential delay, 0 stays 0 (and not NaN)
+	exp := 0.0
+	if min > 0 {
+		exp = min
+		for i := 2; i <= n && exp < max; i++ {
+			exp *= rp.growth(i)
+		}
+		exp = math.Min(max, exp)
+	}
+	var d float64
+	switch rp.jitter {
+	case jitterDecorrelated:
+		hi := math.Min(max, math.Max(float64(prev), min)*rp.growth(n))
+		d = min + r.Float64()*(hi-min)
+	case jitterFull:
+		d = r.Float64() * exp
+	default:
+		d = exp
+	}
+	if !rp.ignoreRetryAfter {
+		if ra, ok := parseRetryAfter(retryAfter, time.Now()); ok {
+			d = math.Max(d, float64(ra))
+		}
+	}
+	return time.Duration(math.Min(d, max))
+}
+
+// parseRetryAfter returns the delay of a Retry-After header in seconds
+// or an HTTP date.
+func parseRetryAfter(v string, now time.Time) (time.Duration, bool) {
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0, false
+		}
+		return time.Duration(secs) * time.Second, true
+	}
+	t, err := http.ParseTime(v)
+	if err != nil {
+		return 0, false
+	}
+	if d := t.Sub(now); d > 0 {
+		return d, true
+	}
+	return 0, true
+}
+
+func (rp *retryPolicy) describe() string {
+	reasons := rp.reasons()
+	if len(reasons) == 0 {
+		return "No retries"
+	}
+	jitter := "no jitter"
+	if rp.jitter != jitterNone {
+		jitter = rp.jitter + " jitter"
+	}
+	retryAfter := "honoring Retry-After"
+	if rp.ignoreRetryAfter {
+		retryAfter = "ignoring Retry-After"
+	}
+	growth := "x4/x2/x1.2"
+	if rp.multiplier != 0 {
+		growth = fmt.Sprintf("x%g", rp.multiplier)
+	}
+	return fmt.Sprintf("Retrying %s up to %d attempts, backoff %v %s up to %v, %s, %s",
+		strings.Join(reasons, ","), rp.maxAttempts, rp.minBackoff, growth, rp.maxBackoff, jitter, retryAfter)
+}
+
+// retryStats counts the retries of a retry policy.
+type retryStats struct {
+	// byReason is fixed when made, only the counts change
+	byReason  map[string]*uint64
+	exhausted uint64
+	// attempts is the number of requests by the number of attempts
+	attempts *uhist.Histogram
+	// backoffs are the backoff delays in microseconds
+	backoffs *uhist.Histogram
+}
+
+func newRetryStats(rp *retryPolicy) *retryStats {
+	rs := &retryStats{
+		byReason: map[string]*uint64{},
+		attempts: uhist.Default(),
+		backoffs: uhist.Default(),
+	}
+	for _, reason := range rp.reasons() {
+		rs.byReason[reason] = new(uint64)
+	}
+	if rp.errorClasses[retryOnError] {
+		for _, class := range errorClasses {
+			rs.byReason[class] = new(uint64)
+		}
+	}
+	return rs
+}
+
+// retry counts a retry for reason after backoff, it returns the number of
+// retries for reason so far.
+func (rs *retryStats) retry(reason string, backoff time.Duration) uint64 {
+	rs.backoffs.Increment(uint64(backoff / time.Microsecond))
+	return atomic.AddUint64(rs.byReason[reason], 1)
+}
+
+// done counts a request sent attempts times, exhausted tells whether it
+// still failed after the max attempts.
+func (rs *retryStats) done(attempts int, exhausted bool) {
+	rs.attempts.Increment(uint64(attempts))
+	if exhausted {
+		atomic.AddUint64(&rs.exhausted, 1)
+	}
+}
+
+func (rs *retryStats) result() *internal.RetryResult {
+	res := &internal.RetryResult{
+		ByReason:  map[string]uint64{},
+		Exhausted: atomic.LoadUint64(&rs.exhausted),
+		Attempts:  rs.attempts,
+		Backoffs:  rs.backoffs,
+	}
+	for reason, n := range rs.byReason {
+		res.ByReason[reason] = atomic.LoadUint64(n)
+	}
+	return res
+}
+
+// countRetry counts a retry for reason after a response with code (-1 for
+// a transport error), the first retry for each reason is told about.
+func (b *bombardier) countRetry(reason string, code int, backoff time.Duration, bs *bucketStats, node *targetNode) {
+	if code == http.StatusTooManyRequests {
+		atomic.AddUint64(&b.retryReq429, 1)
+		if bs != nil {
+			atomic.AddUint64(&bs.retryReq429, 1)
+		}
+		if node != nil {
+			atomic.AddUint64(&node.stats.retryReq429, 1)
+		}
+	}
+	if b.retries.retry(reason, backoff) == 1 {
+		what := "HTTP status " + reason
+		if code < 0 {
+			what = "Transport error (" + reason + ")"
+		}
+		fmt.Printf("%s: %s, consider '-r #' rate limit -or- '-c #' to lower client threads\n", what, b.retry.describe())
+	}
+}
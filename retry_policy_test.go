@@ -0,0 +1,346 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jon-strabala/fasthttp"
+)
+
+func TestNewRetryPolicy(t *testing.T) {
+	settings := retryPolicy{minBackoff: time.Millisecond, maxBackoff: time.Second, multiplier: 2, maxAttempts: 3}
+	rp, err := newRetryPolicy("503, 429,timeout", settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(rp.reasons(), " "); got != "429 503 timeout" {
+		t.Errorf("unexpected reasons %s", got)
+	}
+	if rp.jitter != jitterNone {
+		t.Errorf("expected no jitter, got %s", rp.jitter)
+	}
+	if rp, err := newRetryPolicy("", settings); err != nil || len(rp.reasons()) != 0 || rp.describe() != "No retries" {
+		t.Errorf("expected no retries, got %v %v", rp, err)
+	}
+	for _, bad := range []string{"abc", "99", "600", "429,timeouts"} {
+		if _, err := newRetryPolicy(bad, settings); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+
+	bad := settings
+	bad.multiplier = 0.5
+	if _, err := newRetryPolicy("429", bad); err != errInvalidBackoff {
+		t.Errorf("expected %v, got %v", errInvalidBackoff, err)
+	}
+	bad = settings
+	bad.maxAttempts = 0
+	if _, err := newRetryPolicy("429", bad); err != errInvalidMaxAttempts {
+		t.Errorf("expected %v, got %v", errInvalidMaxAttempts, err)
+	}
+	raised := settings
+	raised.minBackoff = 2 * time.Second
+	if rp, err := newRetryPolicy("429", raised); err != nil || rp.maxBackoff != 2*time.Second {
+		t.Errorf("expected the max backoff raised to 2s, got %v %v", rp, err)
+	}
+
+	if rp := defaultRetryPolicy(0); rp.minBackoff != 500*time.Microsecond || !rp.statuses[429] || rp.maxAttempts != defaultMaxAttempts {
+		t.Errorf("unexpected default policy %+v", rp)
+	}
+}
+
+func TestDefaultRetryBackoff(t *testing.T) {
+	// the ladder of the 429 retries before the retry flags
+	rp := defaultRetryPolicy(0)
+	r := rand.New(rand.NewSource(1))
+	for n, expected := range []time.Duration{500, 2000, 8000, 16000, 32000, 64000, 128000, 153600, 184320} {
+		if d := rp.backoff(n+1, 0, "", r); d != expected*time.Microsecond {
+			t.Errorf("retry %d: expected %v, got %v", n+1, expected*time.Microsecond, d)
+		}
+	}
+	rp5 := defaultRetryPolicy(5)
+	if d := rp5.backoff(3, 0, "", r); d != 80*time.Millisecond {
+		t.Errorf("expected 80ms from a 5ms --minBackoff, got %v", d)
+	}
+	if d := rp5.backoff(7, 0, "", r); d != time.Second {
+		t.Errorf("expected 1.28s capped at 1s, got %v", d)
+	}
+	if s := rp.describe(); !strings.Contains(s, "backoff 500µs x4/x2/x1.2 up to 1s") {
+		t.Errorf("unexpected description %s", s)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	rp, _ := newRetryPolicy("429", retryPolicy{
+		minBackoff: time.Millisecond, maxBackoff: 5 * time.Millisecond, multiplier: 2, maxAttempts: 10,
+	})
+	r := rand.New(rand.NewSource(1))
+	for n, expected := range []time.Duration{1, 2, 4, 5, 5} {
+		if d := rp.backoff(n+1, 0, "", r); d != expected*time.Millisecond {
+			t.Errorf("retry %d: expected %v, got %v", n+1, expected*time.Millisecond, d)
+		}
+	}
+	if d := rp.backoff(1, 0, "1", r); d != 5*time.Millisecond {
+		t.Errorf("expected Retry-After capped at 5ms, got %v", d)
+	}
+	rp.maxBackoff = 10 * time.Second
+	if d := rp.backoff(1, 0, "3", r); d != 3*time.Second {
+		t.Errorf("expected Retry-After 3s, got %v", d)
+	}
+	date := time.Now().Add(2 * time.Second).UTC().Format(http.TimeFormat)
+	if d := rp.backoff(1, 0, date, r); d < time.Second || d > 2*time.Second {
+		t.Errorf("expected about 2s for Retry-After %s, got %v", date, d)
+	}
+	rp.ignoreRetryAfter = true
+	if d := rp.backoff(1, 0, "3", r); d != time.Millisecond {
+		t.Errorf("expected Retry-After ignored, got %v", d)
+	}
+
+	rp.maxBackoff, rp.jitter = 5*time.Millisecond, jitterFull
+	for i := 0; i < 100; i++ {
+		if d := rp.backoff(3, 0, "", r); d < 0 || d > 4*time.Millisecond {
+			t.Fatalf("full jitter: expected up to 4ms, got %v", d)
+		}
+	}
+	rp.jitter = jitterDecorrelated
+	prev := time.Duration(0)
+	for i := 0; i < 100; i++ {
+		d := rp.backoff(i+1, prev, "", r)
+		hi := 2 * prev
+		if hi < 2*time.Millisecond {
+			hi = 2 * time.Millisecond
+		}
+		if hi > rp.maxBackoff {
+			hi = rp.maxBackoff
+		}
+		if d < time.Millisecond || d > hi {
+			t.Fatalf("decorrelated jitter: expected 1ms up to %v, got %v", hi, d)
+		}
+		prev = d
+	}
+}
+
+func TestRetryReason(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	expectations := []struct {
+		err   error
+		class string
+	}{
+		{fasthttp.ErrTimeout, retryOnTimeout},
+		{fasthttp.ErrDialTimeout, retryOnTimeout},
+		{&url.Error{Op: "Get", URL: "http://localhost", Err: fasthttp.ErrTimeout}, retryOnTimeout},
+		{refused, retryOnConnection},
+		{fmt.Errorf("dial: %w", refused), retryOnConnection},
+		{fasthttp.ErrConnectionClosed, retryOnConnection},
+		{errors.New("no such host"), retryOnError},
+	}
+	for _, e := range expectations {
+		if class := errorClass(e.err); class != e.class {
+			t.Errorf("%v: expected %s, got %s", e.err, e.class, class)
+		}
+	}
+
+	settings := retryPolicy{minBackoff: time.Millisecond, maxBackoff: time.Second, multiplier: 2, maxAttempts: 3}
+	rp, _ := newRetryPolicy("503,timeout", settings)
+	if r := rp.retryReason(503, nil); r != "503" {
+		t.Errorf("expected 503, got %q", r)
+	}
+	if r := rp.retryReason(429, nil); r != "" {
+		t.Errorf("expected no retry of 429, got %q", r)
+	}
+	if r := rp.retryReason(-1, fasthttp.ErrTimeout); r != retryOnTimeout {
+		t.Errorf("expected a timeout retry, got %q", r)
+	}
+	if r := rp.retryReason(-1, refused); r != "" {
+		t.Errorf("expected no retry of a refused connection, got %q", r)
+	}
+	rp, _ = newRetryPolicy("error", settings)
+	if r := rp.retryReason(-1, refused); r != retryOnConnection {
+		t.Errorf("expected a connection retry, got %q", r)
+	}
+	if rs := newRetryStats(rp); len(rs.byReason) != len(errorClasses) {
+		t.Errorf("expected counters for all error classes, got %v", rs.byReason)
+	}
+}
+
+func TestBombardierRetries(t *testing.T) {
+	testAllClients(t, testBombardierRetries)
+}
+
+func testBombardierRetries(clientType clientTyp, t *testing.T) {
+	// two of every three responses are a 503 with a Retry-After
+	var n uint64
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if atomic.AddUint64(&n, 1)%3 != 0 {
+			rw.Header().Set("Retry-After", "1")
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		rw.Write([]byte(`{"status": {"total": 1, "successful": 1}, "total_hits": 0, "hits": []}`))
+	}))
+	defer s.Close()
+
+	retry, err := newRetryPolicy("503,connection", retryPolicy{
+		minBackoff:  time.Millisecond,
+		maxBackoff:  2 * time.Millisecond,
+		multiplier:  2,
+		maxAttempts: 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	numReqs := uint64(10)
+	conf := config{
+		numConns:    1,
+		numReqs:     &numReqs,
+		url:         s.URL + "/api/index/ix/query",
+		headers:     new(headersList),
+		timeout:     defaultTimeout,
+		method:      "GET",
+		clientType:  clientType,
+		format:      knownFormat("json"),
+		printResult: true,
+		retry:       retry,
+	}
+	b, err := newBombardier(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.disableOutput()
+	b.bombard()
+	out := new(bytes.Buffer)
+	b.redirectOutputTo(out)
+	b.printStats()
+
+	var info struct {
+		Result struct {
+			Req2xx  uint64
+			Req5xx  uint64
+			Retries struct {
+				Total     uint64
+				ByReason  map[string]uint64
+				Exhausted uint64
+				Attempts  map[string]uint64
+				Backoff   *struct{ Mean, Max float64 }
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("%v in %s", err, out)
+	}
+	res := info.Result
+	if res.Req2xx != numReqs || res.Req5xx != 0 {
+		t.Errorf("expected only the successful attempts counted, got %s", out)
+	}
+	rr := res.Retries
+	if rr.Total != 2*numReqs || rr.ByReason["503"] != 2*numReqs || rr.ByReason["connection"] != 0 || rr.Exhausted != 0 {
+		t.Errorf("expected 2 retries per request, got %s", out)
+	}
+	if len(rr.Attempts) != 1 || rr.Attempts["3"] != numReqs {
+		t.Errorf("expected 3 attempts per request, got %s", out)
+	}
+	// the Retry-After of 1s is capped at --maxBackoff
+	if rr.Backoff == nil || rr.Backoff.Max > 2000 {
+		t.Errorf("expected backoffs up to 2ms, got %s", out)
+	}
+
+	out.Reset()
+	b.conf.format = knownFormat("plain-text")
+	if b.template, err = b.prepareTemplate(); err != nil {
+		t.Fatal(err)
+	}
+	b.printStats()
+	if !strings.Contains(out.String(), "Retries:\n    503 - 20, connection - 0, exhausted - 0\n    attempts 3 - 10\n    backoff avg") {
+		t.Errorf("unexpected plain text %s", out)
+	}
+}
+
+func TestBombardierRetryLatencies(t *testing.T) {
+	testAllClients(t, testBombardierRetryLatencies)
+}
+
+func testBombardierRetryLatencies(clientType clientTyp, t *testing.T) {
+	// two of every three responses are a 429
+	var n uint64
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if atomic.AddUint64(&n, 1)%3 != 0 {
+			rw.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer s.Close()
+
+	retry, err := newRetryPolicy("429", retryPolicy{
+		minBackoff:  20 * time.Millisecond,
+		maxBackoff:  20 * time.Millisecond,
+		multiplier:  1,
+		maxAttempts: 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	numReqs := uint64(5)
+	conf := config{
+		numConns:       1,
+		numReqs:        &numReqs,
+		url:            s.URL,
+		headers:        new(headersList),
+		timeout:        defaultTimeout,
+		method:         "GET",
+		clientType:     clientType,
+		format:         knownFormat("json"),
+		printResult:    true,
+		printLatencies: true,
+		retry:          retry,
+	}
+	b, err := newBombardier(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.disableOutput()
+	b.bombard()
+
+	attempts := uint64(0)
+	b.attemptLatencies.VisitAll(func(_ uint64, c uint64) bool {
+		attempts += c
+		return true
+	})
+	if attempts != 3*numReqs {
+		t.Errorf("expected %d attempt latencies, got %d", 3*numReqs, attempts)
+	}
+
+	out := new(bytes.Buffer)
+	b.redirectOutputTo(out)
+	b.printStats()
+	var info struct {
+		Result struct {
+			Latency, AttemptLatency *struct {
+				Mean        float64
+				Percentiles map[string]uint64
+			}
+		}
+	}
+	if err := json.Unmarshal(out.Bytes(), &info); err != nil {
+		t.Fatalf("%v in %s", err, out)
+	}
+	e2e, attempt := info.Result.Latency, info.Result.AttemptLatency
+	if e2e == nil || attempt == nil || len(attempt.Percentiles) == 0 {
+		t.Fatalf("expected both latencies, got %s", out)
+	}
+	// the two 20ms backoffs of every request are end-to-end only
+	if e2e.Mean < attempt.Mean+40000 {
+		t.Errorf("expected end-to-end latencies 40ms+ over the attempt ones, got %s", out)
+	}
+}
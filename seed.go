@@ -0,0 +1,37 @@
+package main
+
+import (
+	"math/rand"
+)
+
+// Query streams are reproducible: every worker draws from its own
+// generator seeded from --seed (a time based seed is used and printed
+// when none is given) and the worker's index, so the n-th query of a
+// worker is the same in every run whatever the goroutine scheduling.
+
+// randGen is the part of *rand.Rand the query generators use.
+type randGen interface {
+	Intn(n int) int
+	Float64() float64
+}
+
+// globalRand draws from the shared math/rand source, it is used by
+// configs without a worker generator, e.g. for --ftsTestHelp samples.
+type globalRand struct{}
+
+func (globalRand) Intn(n int) int   { return rand.Intn(n) }
+func (globalRand) Float64() float64 { return rand.Float64() }
+
+// rnd returns the generator of the worker the config belongs to.
+func (c config) rnd() randGen {
+	if c.rng != nil {
+		return c.rng
+	}
+	return globalRand{}
+}
+
+// workerRand returns the generator of worker, the worker index is mixed
+// in so that seed+1 does not replay the stream of worker 1 of seed.
+func workerRand(seed int64, worker uint64) *rand.Rand {
+	return rand.New(rand.NewSource(seed ^ int64(worker*0x9e3779b97f4a7c15)))
+}
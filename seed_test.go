@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestWorkerRand(t *testing.T) {
+	draw := func(seed int64, worker uint64) []int {
+		r := workerRand(seed, worker)
+		res := make([]int, 10)
+		for i := range res {
+			res[i] = r.Intn(1000000)
+		}
+		return res
+	}
+	if !reflect.DeepEqual(draw(42, 3), draw(42, 3)) {
+		t.Error("expected the same stream for the same seed and worker")
+	}
+	if reflect.DeepEqual(draw(42, 0), draw(42, 1)) || reflect.DeepEqual(draw(42, 1), draw(43, 0)) {
+		t.Error("expected different streams for different workers and seeds")
+	}
+}
+
+func TestSeededQueryStreams(t *testing.T) {
+	conf := config{}
+	setBuiltinFtsData(&conf)
+	mix, err := queryMixFromConfig(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream := func(seed int64) []string {
+		conf.rng = workerRand(seed, 0)
+		res := make([]string, 100)
+		for i := range res {
+			res[i] = mix.generate(conf)
+		}
+		return res
+	}
+	if !reflect.DeepEqual(stream(7), stream(7)) {
+		t.Error("expected the same queries for the same seed")
+	}
+	if reflect.DeepEqual(stream(7), stream(8)) {
+		t.Error("expected different queries for different seeds")
+	}
+}
+
+func TestBombardierReplaysSeededRun(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies []string
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			mu.Lock()
+			bodies = append(bodies, string(body))
+			mu.Unlock()
+			rw.Write([]byte(`{"status": {"total": 1, "successful": 1}, "total_hits": 0, "hits": []}`))
+		}),
+	)
+	defer s.Close()
+
+	run := func(seed int64) ([]string, string) {
+		bodies = nil
+		numReqs := uint64(50)
+		conf := config{
+			numConns:   1,
+			numReqs:    &numReqs,
+			url:        s.URL,
+			headers:    new(headersList),
+			timeout:    defaultTimeout,
+			method:     "POST",
+			body:       "{" + fts_query_pat + "}",
+			clientType: fhttp,
+			format:     knownFormat("json"),
+			seed:       seed,
+		}
+		setBuiltinFtsData(&conf)
+		b, err := newBombardier(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := new(bytes.Buffer)
+		b.redirectOutputTo(out)
+		b.bombard()
+		b.printStats()
+		return bodies, out.String()
+	}
+	first, out := run(1234)
+	second, _ := run(1234)
+	if len(first) != 50 || !reflect.DeepEqual(first, second) {
+		t.Errorf("expected the same 50 requests in both runs, got %d and %d", len(first), len(second))
+	}
+
+	var res struct {
+		Spec struct {
+			Seed int64
+		}
+	}
+	// skip the progress bar
+	js := out[strings.Index(out, "{\"spec\""):]
+	if err := json.Unmarshal([]byte(js), &res); err != nil {
+		t.Fatalf("%v in %s", err, out)
+	}
+	if res.Spec.Seed != 1234 {
+		t.Errorf("expected the seed in %s", out)
+	}
+}
+
+func TestSeededQueriesIgnoreTargets(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		bodies []string
+		// with throttle every other request gets a 429
+		throttle bool
+		n        int
+	)
+	s := httptest.NewServer(
+		http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			mu.Lock()
+			defer mu.Unlock()
+			if n++; throttle && n%2 == 1 {
+				rw.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			bodies = append(bodies, string(body))
+			rw.Write([]byte(`{"status": {"total": 1, "successful": 1}, "total_hits": 0, "hits": []}`))
+		}),
+	)
+	defer s.Close()
+
+	run := func(targets []string) []string {
+		bodies, n = nil, 0
+		numReqs := uint64(50)
+		conf := config{
+			numConns:   1,
+			numReqs:    &numReqs,
+			url:        s.URL,
+			headers:    new(headersList),
+			timeout:    defaultTimeout,
+			method:     "POST",
+			body:       "{" + fts_query_pat + "}",
+			clientType: fhttp,
+			format:     knownFormat("plain-text"),
+			seed:       1234,
+			targets:    targets,
+			balance:    balanceRandom,
+		}
+		setBuiltinFtsData(&conf)
+		b, err := newBombardier(conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b.disableOutput()
+		b.bombard()
+		return bodies
+	}
+	addr := strings.TrimPrefix(s.URL, "http://")
+	targets := []string{addr, "localhost" + addr[strings.LastIndex(addr, ":"):]}
+	without := run(nil)
+	with := run(targets)
+	if len(without) != 50 || !reflect.DeepEqual(without, with) {
+		t.Errorf("expected the same 50 requests with and without --target, got %d and %d", len(without), len(with))
+	}
+	// retried to another node
+	throttle = true
+	if retried := run(targets); !reflect.DeepEqual(without, retried) {
+		t.Errorf("expected the same 50 requests with retries, got %d", len(retried))
+	}
+}
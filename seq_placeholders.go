@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// A [[SEQ:beg:end]] of the URL is replaced by a number of beg..end for
+// every request, options after the range change how:
+//
+//	pad=N           zero pad to N digits (default the digits of beg or end, at least 2)
+//	uniform         every number equally likely (default)
+//	rr              the numbers in turn, shared by all connections
+//	zipf[=s]        number i+1 of the range s times less likely than i, s defaults to 1
+//	weights=a,b,... relative weights, one per number
+//	name=x          placeholders of the same name take the n-th number of their
+//	                ranges together, e.g. bucket ts[[SEQ:1:4:name=t]] and index
+//	                fts[[SEQ:101:104:name=t]]
+//
+// placeholders without a name are independent, the same text twice takes
+// the same number.
+
+const (
+	seqDistUniform  = "uniform"
+	seqDistRR       = "rr"
+	seqDistZipf     = "zipf"
+	seqDistWeighted = "weights"
+)
+
+var seqPatRegexp = regexp.MustCompile(`\[\[SEQ:[^\]]*\]\]`)
+
+// seqPlaceholder is one distinct [[SEQ:...]] text.
+type seqPlaceholder struct {
+	text     string
+	beg, end int
+	pad      int
+	group    *seqGroup
+}
+
+// seqGroup is drawn once per request for all its placeholders, it holds
+// the index into their ranges.
+type seqGroup struct {
+	// index is the position of the group in urlSeqs.groups
+	index int
+	name  string
+	size  int
+	dist  string
+	// spec is the distribution as given, the placeholders of a group must
+	// not disagree on it
+	spec string
+	cdf  []float64
+	rr   uint64
+}
+
+// urlSeqs are the [[SEQ:...]] placeholders of the URL, bucket is the one
+// -C uses for the KV lookups and the metering counts buckets of.
+type urlSeqs struct {
+	placeholders []*seqPlaceholder
+	groups       []*seqGroup
+	bucket       *seqPlaceholder
+}
+
+// parseURLSeqs finds the placeholders of rawURL, the bucket one is the
+// first that also occurs in kvBucket or else the first of the URL. It
+// returns nil if there are none.
+func parseURLSeqs(rawURL, kvBucket string) (*urlSeqs, error) {
+	texts := seqPatRegexp.FindAllString(rawURL, -1)
+	if len(texts) == 0 {
+		return nil, nil
+	}
+	s := new(urlSeqs)
+	seen := map[string]bool{}
+	groups := map[string]*seqGroup{}
+	for _, text := range texts {
+		if seen[text] {
+			continue
+		}
+		seen[text] = true
+		p, name, dist, err := parseSeqPlaceholder(text)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			name = text
+		}
+		g, ok := groups[name]
+		if !ok {
+			g = &seqGroup{index: len(s.groups), name: name, size: p.end - p.beg + 1}
+			groups[name] = g
+			s.groups = append(s.groups, g)
+		}
+		if g.size != p.end-p.beg+1 {
+			return nil, fmt.Errorf("%s: %d numbers, the other %s placeholders have %d", text, p.end-p.beg+1, name, g.size)
+		}
+		if dist != "" {
+			if g.spec != "" && g.spec != dist {
+				return nil, fmt.Errorf("%s: %s, the other %s placeholders have %s", text, dist, name, g.spec)
+			}
+			if err := g.setDist(dist); err != nil {
+				return nil, fmt.Errorf("%s: %v", text, err)
+			}
+		}
+		p.group = g
+		s.placeholders = append(s.placeholders, p)
+	}
+	for _, g := range s.groups {
+		if g.dist == "" {
+			g.dist = seqDistUniform
+		}
+	}
+	s.bucket = s.placeholders[0]
+	for _, p := range s.placeholders {
+		if strings.Contains(kvBucket, p.text) {
+			s.bucket = p
+			break
+		}
+	}
+	return s, nil
+}
+
+func parseSeqPlaceholder(text string) (p *seqPlaceholder, name, dist string, err error) {
+	fields := strings.Split(text[len("[[SEQ:"):len(text)-2], ":")
+	if len(fields) < 2 {
+		return nil, "", "", fmt.Errorf("%s: %v", text, errInvalidSeqPlaceholder)
+	}
+	p = &seqPlaceholder{text: text}
+	if p.beg, err = strconv.Atoi(fields[0]); err != nil {
+		return nil, "", "", fmt.Errorf("%s: %v", text, errInvalidSeqPlaceholder)
+	}
+	if p.end, err = strconv.Atoi(fields[1]); err != nil || p.beg < 0 || p.end < p.beg {
+		return nil, "", "", fmt.Errorf("%s: %v", text, errInvalidSeqPlaceholder)
+	}
+	p.pad = len(fields[0])
+	if len(fields[1]) > p.pad {
+		p.pad = len(fields[1])
+	}
+	if p.pad < 2 {
+		p.pad = 2
+	}
+	for _, opt := range fields[2:] {
+		key, value, _ := strings.Cut(opt, "=")
+		switch key {
+		case "pad":
+			if p.pad, err = strconv.Atoi(value); err != nil || p.pad < 0 {
+				return nil, "", "", fmt.Errorf("%s: invalid %s", text, opt)
+			}
+		case "name":
+			if value == "" {
+				return nil, "", "", fmt.Errorf("%s: invalid %s", text, opt)
+			}
+			name = value
+		case seqDistUniform, seqDistRR, seqDistZipf, seqDistWeighted:
+			if dist != "" {
+				return nil, "", "", fmt.Errorf("%s: %s and %s", text, dist, opt)
+			}
+			dist = opt
+		default:
+			return nil, "", "", fmt.Errorf("%s: unknown option %s", text, opt)
+		}
+	}
+	return p, name, dist, nil
+}
+
+// setDist sets the distribution of g from an option like "zipf=1.2".
+func (g *seqGroup) setDist(spec string) error {
+	key, value, hasValue := strings.Cut(spec, "=")
+	g.dist, g.spec = key, spec
+	switch key {
+	case seqDistUniform, seqDistRR:
+		if hasValue {
+			return fmt.Errorf("%s takes no value", key)
+		}
+	case seqDistZipf:
+		s := 1.0
+		if hasValue {
+			var err error
+			if s, err = strconv.ParseFloat(value, 64); err != nil || s <= 0 {
+				return fmt.Errorf("invalid zipf exponent %q", value)
+			}
+		}
+		weights := make([]float64, g.size)
+		for i := range weights {
+			weights[i] = 1 / math.Pow(float64(i+1), s)
+		}
+		g.cdf = cumulative(weights)
+	case seqDistWeighted:
+		parts := strings.Split(value, ",")
+		if len(parts) != g.size {
+			return fmt.Errorf("%d weights for %d numbers", len(parts), g.size)
+		}
+		weights := make([]float64, g.size)
+		for i, part := range parts {
+			w, err := strconv.ParseFloat(part, 64)
+			if err != nil || w < 0 {
+				return fmt.Errorf("invalid weight %q", part)
+			}
+			weights[i] = w
+		}
+		if g.cdf = cumulative(weights); g.cdf[len(g.cdf)-1] == 0 {
+			return fmt.Errorf("all weights are 0")
+		}
+	}
+	return nil
+}
+
+func cumulative(weights []float64) []float64 {
+	cdf := make([]float64, len(weights))
+	sum := 0.0
+	for i, w := range weights {
+		sum += w
+		cdf[i] = sum
+	}
+	return cdf
+}
+
+// draw returns an index into the ranges of g.
+func (g *seqGroup) draw(r randGen) int {
+	switch g.dist {
+	case seqDistRR:
+		return int((atomic.AddUint64(&g.rr, 1) - 1) % uint64(g.size))
+	case seqDistZipf, seqDistWeighted:
+		x := r.Float64() * g.cdf[len(g.cdf)-1]
+		return sort.Search(len(g.cdf)-1, func(i int) bool { return g.cdf[i] > x })
+	}
+	return r.Intn(g.size)
+}
+
+func (p *seqPlaceholder) format(num int) string {
+	return fmt.Sprintf("%0*d", p.pad, num)
+}
+
+// seqDraw is the numbers of a request, one index per group in the
+// order of urlSeqs.groups.
+type seqDraw []int
+
+func (s *urlSeqs) draw(r randGen) seqDraw {
+	d := make(seqDraw, len(s.groups))
+	for i, g := range s.groups {
+		d[i] = g.draw(r)
+	}
+	return d
+}
+
+// num returns the number p takes in d.
+func (p *seqPlaceholder) num(d seqDraw) int {
+	return p.beg + d[p.group.index]
+}
+
+// substitute replaces the placeholders of text by their numbers of d.
+func (s *urlSeqs) substitute(text string, d seqDraw) string {
+	for _, p := range s.placeholders {
+		text = strings.ReplaceAll(text, p.text, p.format(p.num(d)))
+	}
+	return text
+}
+
+func (s *urlSeqs) String() string {
+	var parts []string
+	for _, p := range s.placeholders {
+		desc := fmt.Sprintf("%s %s..%s %s", p.text, p.format(p.beg), p.format(p.end), p.group.dist)
+		if p.group.name != p.text {
+			desc += " name " + p.group.name
+		}
+		parts = append(parts, desc)
+	}
+	return strings.Join(parts, ", ")
+}
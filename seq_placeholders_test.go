@@ -0,0 +1,95 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestParseURLSeqs(t *testing.T) {
+	s, err := parseURLSeqs("http://h:8094/api/bucket/ts[[SEQ:1:4:name=t]]/index/fts[[SEQ:101:104:name=t:rr]]"+
+		"/x[[SEQ:0:9:pad=3:zipf=1.5]]/y[[SEQ:1:3:weights=0,1,0]]/ts[[SEQ:1:4:name=t]]", "ts[[SEQ:101:104:name=t:rr]]")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.placeholders) != 4 || len(s.groups) != 3 {
+		t.Fatalf("expected 4 placeholders in 3 groups, got %v", s)
+	}
+	if s.bucket != s.placeholders[1] {
+		t.Errorf("expected the -C placeholder to be the bucket one, got %s", s.bucket.text)
+	}
+	if got := s.placeholders[2].format(7); got != "007" {
+		t.Errorf("expected pad=3, got %s", got)
+	}
+	if got := s.placeholders[0].format(3); got != "03" {
+		t.Errorf("expected the legacy padding to 2 digits, got %s", got)
+	}
+	if s, _ := parseURLSeqs("/[[SEQ:1:100]]", ""); s.placeholders[0].format(5) != "005" {
+		t.Errorf("expected padding to the digits of end, got %s", s.placeholders[0].format(5))
+	}
+
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 8; i++ {
+		d := s.draw(r)
+		uri := s.substitute("/ts[[SEQ:1:4:name=t]]/fts[[SEQ:101:104:name=t:rr]]/y[[SEQ:1:3:weights=0,1,0]]", d)
+		n := i%4 + 1
+		if expected := "/ts0" + string(rune('0'+n)) + "/fts10" + string(rune('0'+n)) + "/y02"; uri != expected {
+			t.Errorf("request %d: expected %s, got %s", i, expected, uri)
+		}
+	}
+
+	bad := []string{
+		"/[[SEQ:4:1]]",
+		"/[[SEQ:a:4]]",
+		"/[[SEQ:1]]",
+		"/[[SEQ:1:4:pad=x]]",
+		"/[[SEQ:1:4:zipf=0]]",
+		"/[[SEQ:1:4:weights=1,2]]",
+		"/[[SEQ:1:4:weights=0,0,0,0]]",
+		"/[[SEQ:1:4:rr:zipf]]",
+		"/[[SEQ:1:4:hot]]",
+		"/[[SEQ:1:4:name=a]]/[[SEQ:1:5:name=a]]",
+		"/[[SEQ:1:4:name=a:rr]]/[[SEQ:5:8:name=a:zipf]]",
+	}
+	for _, u := range bad {
+		if _, err := parseURLSeqs(u, ""); err == nil {
+			t.Errorf("expected an error for %s", u)
+		}
+	}
+	if s, err := parseURLSeqs("/no/placeholders", ""); s != nil || err != nil {
+		t.Errorf("expected nil, got %v %v", s, err)
+	}
+}
+
+func TestSeqDistributions(t *testing.T) {
+	counts := func(spec string) []int {
+		s, err := parseURLSeqs("/[[SEQ:1:10:"+spec+"]]", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := make([]int, 10)
+		r := rand.New(rand.NewSource(7))
+		for i := 0; i < 20000; i++ {
+			c[s.placeholders[0].num(s.draw(r))-1]++
+		}
+		return c
+	}
+
+	for i, c := range counts("uniform") {
+		if c < 1700 || c > 2300 {
+			t.Errorf("uniform: %d drawn %d times of 20000", i+1, c)
+		}
+	}
+	zipf := counts("zipf")
+	for i := 1; i < len(zipf); i++ {
+		if zipf[i] > zipf[i-1]+150 {
+			t.Errorf("zipf: expected decreasing counts, got %v", zipf)
+		}
+	}
+	if zipf[0] < 5*zipf[9] {
+		t.Errorf("zipf: expected 1 about 10 times as often as 10, got %v", zipf)
+	}
+	weighted := counts("weights=1,0,0,0,0,0,0,0,0,3")
+	if weighted[0]+weighted[9] != 20000 || weighted[9] < 2*weighted[0] {
+		t.Errorf("weights: unexpected counts %v", weighted)
+	}
+}
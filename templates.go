@@ -61,6 +61,23 @@ const (
 {{ else }}
 	{{- print "  There wasn't enough data to compute statistics for latencies." }}
 {{ end -}}
+{{ with .Result.AttemptLatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) }}
+	{{- printf "  %-10v %10v %10v %10v" "Attempt" (FormatTimeUs .Mean) (FormatTimeUs .Stddev) (FormatTimeUs .Max) }}
+	{{- if WithLatencies }}
+  		{{- "\n  Attempt Latency Distribution" }}
+		{{- range $pc, $lat := .Percentiles }}
+			{{- printf "\n     %2.0f%% %10s" (Multiply $pc 100) (FormatTimeUsUint64 $lat) -}}
+		{{ end -}}
+	{{ end }}
+{{ end -}}
+{{ with .Result.RecallStats (FloatsToArray 0.1 0.25 0.5 0.75 0.9) -}}
+{{ printf "  Recall@k over %d knn queries:" .Count }}
+{{ printf "    mean %.4f, min %.4f, max %.4f" .Mean .Min .Max }}
+{{- "\n  Recall Distribution" }}
+	{{- range $pc, $r := .Percentiles }}
+		{{- printf "\n     %2.0f%% %10.4f" (Multiply $pc 100) $r -}}
+	{{ end }}
+{{ end -}}
 {{ with .Result -}}
 {{ "  HTTP codes:" }}
 {{ printf "    1xx - %v, 2xx - %v, 3xx - %v, 4xx - %v, 5xx - %v" .Req1XX .Req2XX .Req3XX .Req4XX .Req5XX }}
@@ -72,6 +89,42 @@ const (
 		{{- end -}}
 	{{ end -}}
 {{ end }}
+{{- with .Result.Buckets }}
+{{ "  Buckets:" }}
+	{{- range . }}
+		{{- printf "\n    %-16v reqs %9d, 2xx %9d, 4xx %7d, 5xx %7d, others %7d, 429 retries %7d" .Name .Requests .Req2XX .Req4XX .Req5XX .Others .Retries429 }}
+		{{- with .LatenciesStats (FloatsToArray 0.5 0.99) }}
+			{{- printf "\n      latency avg %10s, p50 %10s, p99 %10s, max %10s" (FormatTimeUs .Mean) (FormatTimeUsUint64 (index .Percentiles 0.5)) (FormatTimeUsUint64 (index .Percentiles 0.99)) (FormatTimeUs .Max) }}
+		{{- end }}
+		{{- printf "\n      hits/req %12.3f, bytesRead/req %12.3f" .HitsPerRequest .BytesReadPerRequest }}
+		{{- if ge .RU 0 }}{{ printf ", RU %d" .RU }}{{ end }}
+	{{- end }}
+{{- end }}
+{{- with .Result.Nodes }}
+{{ "  Nodes:" }}
+	{{- range . }}
+		{{- printf "\n    %-21v reqs %9d, 2xx %9d, 4xx %7d, 5xx %7d, others %7d, 429 retries %7d, ejections %3d" .Name .Requests .Req2XX .Req4XX .Req5XX .Others .Retries429 .Ejections }}
+		{{- with .LatenciesStats (FloatsToArray 0.5 0.99) }}
+			{{- printf "\n      latency avg %10s, p50 %10s, p99 %10s, max %10s" (FormatTimeUs .Mean) (FormatTimeUsUint64 (index .Percentiles 0.5)) (FormatTimeUsUint64 (index .Percentiles 0.99)) (FormatTimeUs .Max) }}
+		{{- end }}
+	{{- end }}
+{{- end }}
+{{- with .Result.Retries }}{{ if .Total }}
+{{ "  Retries:" }}
+	{{- "\n   " }}
+	{{- range .Reasons }}
+		{{- printf " %v - %d," .Reason .Count }}
+	{{- end }}
+	{{- printf " exhausted - %d" .Exhausted }}
+	{{- "\n    attempts" }}
+	{{- range $i, $a := .AttemptsCounts }}
+		{{- if ne $i 0 }},{{ end }}
+		{{- printf " %d - %d" .Attempts .Count }}
+	{{- end }}
+	{{- with .BackoffsStats (FloatsToArray 0.5 0.99) }}
+		{{- printf "\n    backoff avg %10s, p50 %10s, p99 %10s, max %10s" (FormatTimeUs .Mean) (FormatTimeUsUint64 (index .Percentiles 0.5)) (FormatTimeUsUint64 (index .Percentiles 0.99)) (FormatTimeUs .Max) }}
+	{{- end }}
+{{- end }}{{ end }}
 {{ printf "  %-10v %10v/s\n" "Throughput:" (FormatBinary .Result.Throughput)}}`
 
 
@@ -124,6 +177,10 @@ const (
 {{- with .Rate -}}
 ,"rate":{{ . }}
 {{- end -}}
+{{- with .RequestOptions -}}
+,"requestOptions":{{ . }}
+{{- end -}}
+,"seed":{{ .Seed }}
 {{- end -}}
 },
 
@@ -165,6 +222,128 @@ const (
 }
 {{- end -}}
 
+{{- with .AttemptLatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+,"attemptLatency":{"mean":{{ .Mean -}}
+,"stddev":{{ .Stddev -}}
+,"max":{{ .Max -}}
+
+{{- if WithLatencies -}}
+,"percentiles":{
+{{- range $pc, $lat := .Percentiles }}
+{{- if ne $pc 0.5 -}},{{- end -}}
+{{- printf "\"%2.0f\":%d" (Multiply $pc 100) $lat -}}
+{{- end -}}
+}
+{{- end -}}
+
+}
+{{- end -}}
+
+{{- with .RecallStats (FloatsToArray 0.1 0.25 0.5 0.75 0.9) -}}
+,"recall":{"count":{{ .Count -}}
+,"mean":{{ .Mean -}}
+,"min":{{ .Min -}}
+,"max":{{ .Max -}}
+,"percentiles":{
+{{- range $pc, $r := .Percentiles }}
+{{- if ne $pc 0.1 -}},{{- end -}}
+{{- printf "\"%2.0f\":%f" (Multiply $pc 100) $r -}}
+{{- end -}}
+}}
+{{- end -}}
+
+{{- with .Buckets -}}
+,"buckets":[
+{{- range $index, $bucket := . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{"name":{{ .Name | printf "%q" -}}
+,"requests":{{ .Requests -}}
+,"req1xx":{{ .Req1XX -}}
+,"req2xx":{{ .Req2XX -}}
+,"req3xx":{{ .Req3XX -}}
+,"req4xx":{{ .Req4XX -}}
+,"req5xx":{{ .Req5XX -}}
+,"others":{{ .Others -}}
+,"retries429":{{ .Retries429 -}}
+,"hits":{{ .Hits -}}
+,"bytesRead":{{ .BytesRead -}}
+{{- with .LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+,"latency":{"mean":{{ .Mean -}}
+,"stddev":{{ .Stddev -}}
+,"max":{{ .Max -}}
+,"percentiles":{
+{{- range $pc, $lat := .Percentiles }}
+{{- if ne $pc 0.5 -}},{{- end -}}
+{{- printf "\"%2.0f\":%d" (Multiply $pc 100) $lat -}}
+{{- end -}}
+}}
+{{- end -}}
+{{- if ge .RU 0 -}}
+,"ru":{{ .RU -}}
+{{- end -}}
+}
+{{- end -}}
+]
+{{- end -}}
+
+{{- with .Nodes -}}
+,"nodes":[
+{{- range $index, $node := . -}}
+{{- if ne $index 0 -}},{{- end -}}
+{"name":{{ .Name | printf "%q" -}}
+,"requests":{{ .Requests -}}
+,"req1xx":{{ .Req1XX -}}
+,"req2xx":{{ .Req2XX -}}
+,"req3xx":{{ .Req3XX -}}
+,"req4xx":{{ .Req4XX -}}
+,"req5xx":{{ .Req5XX -}}
+,"others":{{ .Others -}}
+,"retries429":{{ .Retries429 -}}
+,"ejections":{{ .Ejections -}}
+{{- with .LatenciesStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+,"latency":{"mean":{{ .Mean -}}
+,"stddev":{{ .Stddev -}}
+,"max":{{ .Max -}}
+,"percentiles":{
+{{- range $pc, $lat := .Percentiles }}
+{{- if ne $pc 0.5 -}},{{- end -}}
+{{- printf "\"%2.0f\":%d" (Multiply $pc 100) $lat -}}
+{{- end -}}
+}}
+{{- end -}}
+}
+{{- end -}}
+]
+{{- end -}}
+
+{{- with .Retries -}}{{- if .Total -}}
+,"retries":{"total":{{ .Total -}}
+,"byReason":{
+{{- range $index, $r := .Reasons -}}
+{{- if ne $index 0 -}},{{- end -}}
+{{ .Reason | printf "%q" }}:{{ .Count }}
+{{- end -}}
+},"exhausted":{{ .Exhausted -}}
+,"attempts":{
+{{- range $index, $a := .AttemptsCounts -}}
+{{- if ne $index 0 -}},{{- end -}}
+"{{ .Attempts }}":{{ .Count }}
+{{- end -}}
+}
+{{- with .BackoffsStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
+,"backoff":{"mean":{{ .Mean -}}
+,"stddev":{{ .Stddev -}}
+,"max":{{ .Max -}}
+,"percentiles":{
+{{- range $pc, $lat := .Percentiles }}
+{{- if ne $pc 0.5 -}},{{- end -}}
+{{- printf "\"%2.0f\":%d" (Multiply $pc 100) $lat -}}
+{{- end -}}
+}}
+{{- end -}}
+}
+{{- end -}}{{- end -}}
+
 {{- with .RequestsStats (FloatsToArray 0.5 0.75 0.9 0.95 0.99) -}}
 ,"rps":{"mean":{{ .Mean -}}
 ,"stddev":{{ .Stddev -}}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// knnQueryGen emits FTS vector search requests, a "knn" clause with a
+// random query vector, optionally combined with a text query from
+// another generator (hybrid search). The index must map field as a
+// vector of the same dimension.
+type knnQueryGen struct {
+	field         string
+	dims          int
+	k             int
+	numCandidates int
+	// source is "random", "file" (the --vectors file) or empty to use
+	// the file if one was loaded
+	source string
+	// hybrid names the generator providing the text query, empty for a
+	// pure knn search
+	hybrid    string
+	hybridGen queryGenerator
+	miss      float64
+}
+
+func (g knnQueryGen) useFile(conf config) bool {
+	return g.source == "file" || (g.source == "" && len(conf.vectors) > 0)
+}
+
+func randomUnitVector(r randGen, dims int) []float32 {
+	v := make([]float32, dims)
+	sum := 0.0
+	for i := range v {
+		f := r.Float64()*2 - 1
+		v[i] = float32(f)
+		sum += f * f
+	}
+	if norm := float32(math.Sqrt(sum)); norm > 0 {
+		for i := range v {
+			v[i] /= norm
+		}
+	}
+	return v
+}
+
+func appendVectorJSON(buf []byte, v []float32) []byte {
+	buf = append(buf, '[')
+	for i, f := range v {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = strconv.AppendFloat(buf, float64(f), 'g', -1, 32)
+	}
+	return append(buf, ']')
+}
+
+func (g knnQueryGen) knnClause(v []float32) string {
+	buf := make([]byte, 0, 64+len(v)*12)
+	buf = append(buf, fmt.Sprintf("\"knn\": [{\"field\": \"%s\", \"k\": %d, \"num_candidates\": %d, \"vector\": ",
+		g.field, g.k, g.numCandidates)...)
+	buf = appendVectorJSON(buf, v)
+	return string(append(buf, "}]"...))
+}
+
+func (g knnQueryGen) generate(conf config) string {
+	q, _ := g.generateProbe(conf)
+	return q
+}
+
+// generateProbe returns a probe for pure knn searches with vectors from
+// the --vectors file when there is ground truth to check them against.
+func (g knnQueryGen) generateProbe(conf config) (string, *recallProbe) {
+	if !g.useFile(conf) {
+		return g.wrap(conf, randomUnitVector(conf.rnd(), g.dims)), nil
+	}
+	idx := conf.rnd().Intn(len(conf.vectors))
+	q := g.wrap(conf, conf.vectors[idx])
+	if g.hybridGen != nil || conf.groundTruth == nil {
+		return q, nil
+	}
+	return q, &recallProbe{vector: idx, k: g.k}
+}
+
+func (g knnQueryGen) wrap(conf config, v []float32) string {
+	knn := g.knnClause(v)
+	if g.hybridGen == nil {
+		return "\"query\": {\"match_none\": {}}, " + knn
+	}
+	return g.hybridGen.generate(conf) + ", " + knn
+}
+
+func (g knnQueryGen) describe() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Search the vector field %s for the k=%d nearest neighbors (num_candidates %d) of a query\n",
+		g.field, g.k, g.numCandidates)
+	switch {
+	case g.source == "file":
+		sb.WriteString("vector picked at random from the --vectors file")
+	case g.source == "random":
+		fmt.Fprintf(&sb, "vector made of %d random values, normalized to unit length", g.dims)
+	default:
+		fmt.Fprintf(&sb, "vector picked at random from the --vectors file, without one a random\n"+
+			"unit vector of %d dimensions", g.dims)
+	}
+	if g.hybridGen != nil {
+		fmt.Fprintf(&sb, "\nhybrid search, the text query comes from %s", g.hybrid)
+	}
+	return sb.String()
+}
+
+func (g knnQueryGen) missRate() float64 { return g.miss }
+
+func (g knnQueryGen) withParams(conf config, p *queryParams) (queryGenerator, error) {
+	g.field = p.string("field", g.field)
+	g.dims = p.int("dims", g.dims)
+	g.k = p.int("k", g.k)
+	g.numCandidates = p.int("numCandidates", g.numCandidates)
+	g.source = p.string("vectors", g.source)
+	g.hybrid = p.string("hybrid", g.hybrid)
+	if g.dims < 1 || g.k < 1 || g.numCandidates < g.k {
+		return nil, fmt.Errorf("need dims > 0, k > 0 and numCandidates >= k")
+	}
+	switch g.source {
+	case "", "random":
+	case "file":
+		if len(conf.vectors) == 0 {
+			return nil, errNoVectorsFile
+		}
+	default:
+		return nil, fmt.Errorf("vectors must be random or file")
+	}
+	if _, set := p.m["dims"]; set && g.useFile(conf) && len(conf.vectors[0]) != g.dims {
+		return nil, fmt.Errorf("dims %d does not match the %d dimensions of the --vectors file",
+			g.dims, len(conf.vectors[0]))
+	}
+	g.hybridGen = nil
+	if g.hybrid != "" {
+		rg, ok := queryGeneratorsByName[g.hybrid]
+		if !ok {
+			return nil, fmt.Errorf("unknown FTS query generator %q for hybrid", g.hybrid)
+		}
+		if _, isKnn := rg.gen.(knnQueryGen); isKnn {
+			return nil, fmt.Errorf("hybrid needs a text query generator, not %s", g.hybrid)
+		}
+		g.hybridGen = rg.gen
+	}
+	return g, nil
+}
+
+func init() {
+	knn := knnQueryGen{field: "vector", dims: 128, k: 10, numCandidates: 100, miss: missRateUnknown}
+	registerQueryGenerator(60, "knn", knn)
+
+	// query_generators.go is initialized first, random-terms exists
+	hybrid := knn
+	hybrid.hybrid = "random-terms"
+	hybrid.hybridGen = queryGeneratorsByName[hybrid.hybrid].gen
+	registerQueryGenerator(61, "knn-hybrid", hybrid)
+}
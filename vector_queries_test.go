@@ -0,0 +1,97 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+type knnRequest struct {
+	Query map[string]interface{} `json:"query"`
+	Knn   []struct {
+		Field         string    `json:"field"`
+		K             int       `json:"k"`
+		NumCandidates int       `json:"num_candidates"`
+		Vector        []float32 `json:"vector"`
+	} `json:"knn"`
+}
+
+func parseKnnRequest(t *testing.T, q string) knnRequest {
+	var r knnRequest
+	if err := json.Unmarshal([]byte("{"+q+"}"), &r); err != nil {
+		t.Fatalf("%v in %s", err, q)
+	}
+	if len(r.Knn) != 1 {
+		t.Fatalf("expected one knn clause in %s", q)
+	}
+	return r
+}
+
+func TestKnnQueryGenerators(t *testing.T) {
+	var conf config
+	setBuiltinFtsData(&conf)
+
+	r := parseKnnRequest(t, queryGeneratorsByName["knn"].gen.generate(conf))
+	if _, ok := r.Query["match_none"]; !ok {
+		t.Errorf("expected match_none for a pure knn search, got %v", r.Query)
+	}
+	knn := r.Knn[0]
+	if knn.Field != "vector" || knn.K != 10 || knn.NumCandidates != 100 || len(knn.Vector) != 128 {
+		t.Errorf("unexpected knn clause %+v", knn)
+	}
+	norm := 0.0
+	for _, f := range knn.Vector {
+		norm += float64(f) * float64(f)
+	}
+	if math.Abs(norm-1) > 1e-3 {
+		t.Errorf("expected a unit vector, norm^2 is %f", norm)
+	}
+
+	r = parseKnnRequest(t, queryGeneratorsByName["knn-hybrid"].gen.generate(conf))
+	if _, ok := r.Query["match_none"]; ok {
+		t.Errorf("expected a text query for hybrid search, got %v", r.Query)
+	}
+}
+
+func TestKnnQueryParams(t *testing.T) {
+	var conf config
+	setBuiltinFtsData(&conf)
+	conf.vectors = [][]float32{{1, 2, 3}}
+	gen := queryGeneratorsByName["knn"].gen.(configurableQueryGenerator)
+
+	p := newQueryParams(map[string]interface{}{
+		"field": "emb", "k": 3, "numCandidates": 30, "vectors": "file", "hybrid": "fuzzy-1",
+	})
+	g, err := gen.withParams(conf, p)
+	if err == nil {
+		err = p.check()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := g.generate(conf)
+	r := parseKnnRequest(t, q)
+	knn := r.Knn[0]
+	if knn.Field != "emb" || knn.K != 3 || knn.NumCandidates != 30 || len(knn.Vector) != 3 || knn.Vector[2] != 3 {
+		t.Errorf("unexpected knn clause %+v", knn)
+	}
+	if !strings.Contains(q, "\"fuzziness\"") {
+		t.Errorf("expected the fuzzy-1 text query in %s", q)
+	}
+
+	for _, bad := range []map[string]interface{}{
+		{"k": 20, "numCandidates": 10},
+		{"vectors": "somewhere"},
+		{"dims": 4, "vectors": "file"},
+		{"hybrid": "knn-hybrid"},
+		{"hybrid": "nope"},
+	} {
+		if _, err := gen.withParams(conf, newQueryParams(bad)); err == nil {
+			t.Errorf("expected an error for %v", bad)
+		}
+	}
+	if _, err := gen.withParams(config{}, newQueryParams(map[string]interface{}{"vectors": "file"})); err != errNoVectorsFile {
+		t.Errorf("expected errNoVectorsFile, got %v", err)
+	}
+}
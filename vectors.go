@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// readVectorsFile reads query vectors for the knn generators from an
+// .fvecs file (the little endian int32 dimension then float32 values
+// layout of the ANN benchmark datasets), a .json file (an array of
+// arrays) or a .jsonl file (one array, or an object with a "vector"
+// member, per line). All vectors must have the same dimension.
+func readVectorsFile(path string) ([][]float32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var vectors [][]float32
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".fvecs":
+		vectors, err = decodeFvecs(bufio.NewReader(f))
+	case ".json":
+		err = json.NewDecoder(f).Decode(&vectors)
+	case ".jsonl", ".ndjson":
+		vectors, err = decodeVectorsJSONL(f)
+	default:
+		err = fmt.Errorf("unsupported file type, use .fvecs, .json or .jsonl")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("%s: no vectors found", path)
+	}
+	dims := len(vectors[0])
+	for i, v := range vectors {
+		if len(v) == 0 || len(v) != dims {
+			return nil, fmt.Errorf("%s: vector %d has %d dimensions, expected %d", path, i+1, len(v), dims)
+		}
+	}
+	return vectors, nil
+}
+
+func decodeFvecs(r io.Reader) ([][]float32, error) {
+	var vectors [][]float32
+	for {
+		var dims int32
+		if err := binary.Read(r, binary.LittleEndian, &dims); err == io.EOF {
+			return vectors, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if dims <= 0 || dims > 1<<16 {
+			return nil, fmt.Errorf("vector %d: bad dimension %d", len(vectors)+1, dims)
+		}
+		raw := make([]uint32, dims)
+		if err := binary.Read(r, binary.LittleEndian, raw); err != nil {
+			return nil, fmt.Errorf("vector %d: %v", len(vectors)+1, err)
+		}
+		v := make([]float32, dims)
+		for i, bits := range raw {
+			v[i] = math.Float32frombits(bits)
+		}
+		vectors = append(vectors, v)
+	}
+}
+
+func decodeVectorsJSONL(r io.Reader) ([][]float32, error) {
+	var vectors [][]float32
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var v []float32
+		if strings.HasPrefix(line, "{") {
+			var obj struct {
+				Vector []float32 `json:"vector"`
+			}
+			if err := json.Unmarshal([]byte(line), &obj); err != nil {
+				return nil, fmt.Errorf("line %d: %v", lineNo, err)
+			}
+			v = obj.Vector
+		} else if err := json.Unmarshal([]byte(line), &v); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, err)
+		}
+		vectors = append(vectors, v)
+	}
+	return vectors, scanner.Err()
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestReadVectorsFile(t *testing.T) {
+	want := [][]float32{{0.5, -1, 2}, {1, 0, 0.25}}
+	var fvecs bytes.Buffer
+	for _, v := range want {
+		binary.Write(&fvecs, binary.LittleEndian, int32(len(v)))
+		binary.Write(&fvecs, binary.LittleEndian, v)
+	}
+	expectations := []struct {
+		name, content string
+	}{
+		{"q.fvecs", fvecs.String()},
+		{"q.json", `[[0.5, -1, 2], [1, 0, 0.25]]`},
+		{"q.jsonl", "[0.5, -1, 2]\n\n{\"id\": 7, \"vector\": [1, 0, 0.25]}\n"},
+	}
+	for _, e := range expectations {
+		vectors, err := readVectorsFile(writeTempFile(t, e.name, e.content))
+		if err != nil {
+			t.Error(e.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(vectors, want) {
+			t.Errorf("%s: expected %v, got %v", e.name, want, vectors)
+		}
+	}
+	for name, content := range map[string]string{
+		"mixed.json": `[[1, 2], [1, 2, 3]]`,
+		"empty.json": `[]`,
+		"q.txt":      "1 2 3\n",
+	} {
+		if _, err := readVectorsFile(writeTempFile(t, name, content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}